@@ -5,8 +5,16 @@ import (
 )
 
 type ansibleConfig struct {
-	InventoryFilePaths []string `hcl:"inventory_file_paths,optional" steampipe:"watch"`
-	PlayBookFilePaths  []string `hcl:"playbook_file_paths,optional" steampipe:"watch"`
+	CollectionsPaths      []string `hcl:"collections_paths,optional" steampipe:"watch"`
+	FactCachePaths        []string `hcl:"fact_cache_paths,optional" steampipe:"watch"`
+	InventoryFilePaths    []string `hcl:"inventory_file_paths,optional" steampipe:"watch"`
+	JUnitReportPaths      []string `hcl:"junit_report_paths,optional" steampipe:"watch"`
+	LogFilePaths          []string `hcl:"log_file_paths,optional" steampipe:"watch"`
+	MaxParseConcurrency   *int     `hcl:"max_parse_concurrency,optional"`
+	OnParseError          *string  `hcl:"on_parse_error,optional"`
+	PlayBookFilePaths     []string `hcl:"playbook_file_paths,optional" steampipe:"watch"`
+	RequirementsFilePaths []string `hcl:"requirements_file_paths,optional" steampipe:"watch"`
+	RunLogPaths           []string `hcl:"run_log_paths,optional" steampipe:"watch"`
 }
 
 func ConfigInstance() interface{} {
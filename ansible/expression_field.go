@@ -0,0 +1,216 @@
+package ansible
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scopes of the strings holding Jinja2 expressions
+const (
+	expressionScopePlay     = "play"
+	expressionScopeTask     = "task"
+	expressionScopeTemplate = "template"
+	expressionScopeVars     = "vars"
+)
+
+// ansibleBareKeywords are the keywords whose value is a Jinja2 expression
+// without {{ }} delimiters, or a list of them.
+var ansibleBareKeywords = map[string]bool{
+	"changed_when": true,
+	"failed_when":  true,
+	"until":        true,
+	"when":         true,
+}
+
+// ansibleBareModuleArgs are the module arguments whose value is a Jinja2
+// expression without {{ }} delimiters, by short module name.
+var ansibleBareModuleArgs = map[string]string{
+	"assert": "that",
+	"debug":  "var",
+}
+
+// ansibleExpressionField is a string of a play, a task or a vars file that
+// Ansible templates, e.g. "{{ app_dir }}/config" or a when conditional.
+type ansibleExpressionField struct {
+	// Bare is set for the expressions evaluated without {{ }} delimiters
+	Bare bool
+	// Field is the path to the string in the play, task or vars file, e.g.
+	// when, apt.name or vars.packages[0]
+	Field        string
+	Node         *yaml.Node
+	PlaybookName string
+	// Scope is play, task or vars
+	Scope    string
+	TaskName string
+}
+
+// analyze returns what the expressions of the field use, with lines relative
+// to the file.
+func (f ansibleExpressionField) analyze() (jinjaAnalysis, error) {
+	var analysis jinjaAnalysis
+	var err error
+	if f.Bare {
+		analysis, err = analyzeJinjaExpression(f.Node.Value)
+	} else {
+		analysis, err = analyzeJinjaTemplate(f.Node.Value)
+	}
+
+	// The content of block scalars starts on the line following the key
+	offset := f.Node.Line - 1
+	if f.Node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		offset++
+	}
+	if err != nil {
+		err = fmt.Errorf("%s: %v", f.Field, err)
+	}
+	for i := range analysis.References {
+		analysis.References[i].Line += offset
+	}
+	for _, usages := range [][]jinjaUsage{analysis.Extends, analysis.Filters, analysis.Imports, analysis.Includes, analysis.Macros, analysis.Tests} {
+		for i := range usages {
+			usages[i].Line += offset
+		}
+	}
+	for i := range analysis.Lookups {
+		analysis.Lookups[i].Line += offset
+	}
+
+	return analysis, err
+}
+
+// walkAnsibleExpressionFields calls fn for every string of a playbook, a tasks
+// file or a vars file that holds Jinja2 expressions. Unlike
+// walkAnsibleTaskNodes, the keywords of blocks are walked too.
+func walkAnsibleExpressionFields(doc *yaml.Node, kind string, fn func(field ansibleExpressionField)) {
+	root := documentRoot(doc)
+	if root == nil {
+		return
+	}
+
+	if kind == fileKindVars {
+		walkExpressionValue(root, nil, ansibleExpressionField{Scope: expressionScopeVars}, fn)
+		return
+	}
+	if root.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, item := range root.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if !isAnsiblePlayNode(item) {
+			walkExpressionTasks([]*yaml.Node{item}, "", fn)
+			continue
+		}
+
+		var playbookName string
+		if name := mappingValue(item, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			playbookName = name.Value
+		}
+		play := ansibleExpressionField{PlaybookName: playbookName, Scope: expressionScopePlay}
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			key, value := item.Content[i].Value, item.Content[i+1]
+			if isAnsibleTaskSection(key) {
+				if value.Kind == yaml.SequenceNode {
+					walkExpressionTasks(value.Content, playbookName, fn)
+				}
+				continue
+			}
+			walkExpressionValue(value, []string{key}, play, fn)
+		}
+	}
+}
+
+// walkExpressionTasks walks the keywords and module arguments of tasks,
+// recursing into blocks.
+func walkExpressionTasks(tasks []*yaml.Node, playbookName string, fn func(field ansibleExpressionField)) {
+	for _, task := range tasks {
+		if task.Kind != yaml.MappingNode {
+			continue
+		}
+
+		field := ansibleExpressionField{PlaybookName: playbookName, Scope: expressionScopeTask}
+		if name := mappingValue(task, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			field.TaskName = name.Value
+		}
+		for i := 0; i+1 < len(task.Content); i += 2 {
+			key, value := task.Content[i].Value, task.Content[i+1]
+			if isAnsibleBlockSection(key) {
+				if value.Kind == yaml.SequenceNode {
+					walkExpressionTasks(value.Content, playbookName, fn)
+				}
+				continue
+			}
+			walkExpressionValue(value, []string{key}, field, fn)
+		}
+	}
+}
+
+// walkExpressionValue walks a value, calling fn for its strings holding
+// expressions.
+func walkExpressionValue(node *yaml.Node, path []string, field ansibleExpressionField, fn func(field ansibleExpressionField)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkExpressionValue(node.Content[i+1], append(path, node.Content[i].Value), field, fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			// The items of a top-level list have no key to index
+			itemPath := []string{fmt.Sprintf("[%d]", i)}
+			if len(path) > 0 {
+				itemPath = append([]string(nil), path...)
+				itemPath[len(itemPath)-1] += fmt.Sprintf("[%d]", i)
+			}
+			walkExpressionValue(item, itemPath, field, fn)
+		}
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" {
+			return
+		}
+		field.Bare = field.Scope == expressionScopeTask && isBareExpressionPath(path)
+		if !field.Bare && !isTemplated(node.Value) {
+			return
+		}
+		field.Field = strings.Join(path, ".")
+		field.Node = node
+		fn(field)
+	}
+}
+
+// isBareExpressionPath reports whether the string at the path of a task is
+// evaluated without {{ }} delimiters, e.g. when or assert.that[0].
+func isBareExpressionPath(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	key := strings.SplitN(path[0], "[", 2)[0]
+	if ansibleBareKeywords[key] {
+		return true
+	}
+	if arg, ok := ansibleBareModuleArgs[shortModuleName(key)]; ok && len(path) > 1 {
+		return strings.SplitN(path[1], "[", 2)[0] == arg
+	}
+	return false
+}
+
+func isAnsibleTaskSection(key string) bool {
+	for _, section := range ansibleTaskSections {
+		if key == section {
+			return true
+		}
+	}
+	return false
+}
+
+func isAnsibleBlockSection(key string) bool {
+	for _, section := range ansibleBlockSections {
+		if key == section {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package ansible
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of Ansible files
+const (
+	fileKindFactCache    = "fact_cache"
+	fileKindGalaxy       = "galaxy"
+	fileKindInventory    = "inventory"
+	fileKindJUnitReport  = "junit_report"
+	fileKindLog          = "log"
+	fileKindPlaybook     = "playbook"
+	fileKindRequirements = "requirements"
+	fileKindRoleMeta     = "role_meta"
+	fileKindRunLog       = "run_log"
+	fileKindTasks        = "tasks"
+	fileKindTemplate     = "template"
+	fileKindUnknown      = "unknown"
+	fileKindVars         = "vars"
+)
+
+// ansibleOutputFileKinds are the kinds of the files written by Ansible runs,
+// by the config argument matching them. Their kind is not detected from their
+// content.
+var ansibleOutputFileKinds = map[string]string{
+	fileKindFactCache:   "fact_cache_paths",
+	fileKindJUnitReport: "junit_report_paths",
+	fileKindLog:         "log_file_paths",
+	fileKindRunLog:      "run_log_paths",
+}
+
+// Confidence levels of a file classification
+const (
+	confidenceHigh   = "high"
+	confidenceMedium = "medium"
+	confidenceLow    = "low"
+)
+
+// ansibleFileClassification is the detected kind of a file, along with how
+// it was detected.
+type ansibleFileClassification struct {
+	Confidence string
+	Kind       string
+	Reason     string
+	// ValidYAML is false if the file could not be parsed as YAML, in which
+	// case the kind is only guessed from the path
+	ValidYAML bool
+}
+
+// isPlaybookCandidate reports whether the tables listing the content of
+// playbooks should parse the file. Files that are not valid YAML are kept so
+// that their syntax errors are reported according to on_parse_error.
+func (c ansibleFileClassification) isPlaybookCandidate() bool {
+	return c.Kind == fileKindPlaybook || (c.Kind == fileKindUnknown && !c.ValidYAML)
+}
+
+// ansibleVarsDirs are the directories holding variables files, in roles or
+// next to playbooks and inventories.
+var ansibleVarsDirs = map[string]bool{
+	"defaults":   true,
+	"group_vars": true,
+	"host_vars":  true,
+	"vars":       true,
+}
+
+// ansibleTasksDirs are the role directories holding tasks files.
+var ansibleTasksDirs = map[string]bool{
+	"handlers": true,
+	"tasks":    true,
+}
+
+var iniSectionRegex = regexp.MustCompile(`(?m)^\s*\[[^\]\s]+\]\s*$`)
+
+// classifyAnsibleFile detects the kind of an Ansible file, first from well
+// known file names and role layouts, then from the shape of its content.
+func classifyAnsibleFile(path string, content []byte) ansibleFileClassification {
+	base := strings.ToLower(filepath.Base(path))
+	name := strings.TrimSuffix(strings.TrimSuffix(base, ".yml"), ".yaml")
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(path)), "/")
+	parent := dirs[len(dirs)-1]
+
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	validYAML := err == nil
+	classification := func(kind string, confidence string, reason string) ansibleFileClassification {
+		return ansibleFileClassification{Confidence: confidence, Kind: kind, Reason: reason, ValidYAML: validYAML}
+	}
+
+	// #1 - Well known file names and directories
+
+	switch {
+	case base == "galaxy.yml" || base == "galaxy.yaml":
+		return classification(fileKindGalaxy, confidenceHigh, "file is named galaxy.yml")
+	case name == "requirements":
+		return classification(fileKindRequirements, confidenceHigh, "file is named requirements.yml")
+	case parent == "meta" && name == "main":
+		return classification(fileKindRoleMeta, confidenceHigh, "file is meta/main.yml of a role")
+	case ansibleTasksDirs[parent] && isYAMLFileName(base):
+		return classification(fileKindTasks, confidenceHigh, "file is in the "+parent+" directory of a role")
+	case strings.HasSuffix(base, ".j2"):
+		return classification(fileKindTemplate, confidenceHigh, "file has the .j2 extension of Jinja2 templates")
+	case parent == "templates":
+		return classification(fileKindTemplate, confidenceHigh, "file is in the templates directory of a role")
+	case base == "hosts" || strings.HasSuffix(base, ".ini"):
+		return classification(fileKindInventory, confidenceHigh, "file is named "+base)
+	}
+	if ansibleVarsDirs[parent] && isYAMLFileName(base) {
+		return classification(fileKindVars, confidenceHigh, "file is in a "+parent+" directory")
+	}
+	// Variables of a group or host may be split in a directory, e.g.
+	// group_vars/webservers/main.yml
+	if len(dirs) > 1 && (dirs[len(dirs)-2] == "group_vars" || dirs[len(dirs)-2] == "host_vars") {
+		return classification(fileKindVars, confidenceHigh, "file is in a "+dirs[len(dirs)-2]+" directory")
+	}
+
+	// #2 - Shape of the content
+
+	var root *yaml.Node
+	if validYAML && doc.Kind != 0 {
+		root = documentRoot(&doc)
+	}
+
+	// INI inventories may also be valid YAML, e.g. a [webservers] section
+	// reads as a list
+	if iniSectionRegex.Match(content) && (root == nil || !hasMappingItems(root)) {
+		return classification(fileKindInventory, confidenceMedium, "file has INI sections")
+	}
+	if !validYAML {
+		return classification(fileKindUnknown, confidenceLow, "file is not valid YAML: "+newAnsibleParseError(err, nil).Message)
+	}
+	if root == nil {
+		return classification(fileKindUnknown, confidenceLow, "file is empty")
+	}
+
+	switch root.Kind {
+	case yaml.SequenceNode:
+		return classifyAnsibleSequence(root, classification)
+	case yaml.MappingNode:
+		return classifyAnsibleMapping(root, classification)
+	}
+
+	return classification(fileKindUnknown, confidenceLow, "file content is a scalar")
+}
+
+// classifyAnsibleSequence detects the kind of a file whose content is a list,
+// i.e. a playbook, a tasks file or a legacy requirements file.
+func classifyAnsibleSequence(root *yaml.Node, classification func(string, string, string) ansibleFileClassification) ansibleFileClassification {
+	var tasks, requirements int
+	for _, item := range root.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if isAnsiblePlayNode(item) {
+			return classification(fileKindPlaybook, confidenceHigh, "top-level list has a play with hosts or import_playbook")
+		}
+
+		var raw map[string]interface{}
+		if err := item.Decode(&raw); err != nil {
+			continue
+		}
+		module, _ := parseTaskModule(raw)
+		switch {
+		case raw["block"] != nil || resolveModuleFQCN(module) != "":
+			tasks++
+		case raw["src"] != nil:
+			requirements++
+		}
+	}
+
+	switch {
+	case tasks > 0 && tasks >= requirements:
+		return classification(fileKindTasks, confidenceMedium, "top-level list has tasks calling known modules")
+	case requirements > 0:
+		return classification(fileKindRequirements, confidenceMedium, "top-level list has roles with a src")
+	}
+	return classification(fileKindUnknown, confidenceLow, "top-level list has no plays or tasks")
+}
+
+// classifyAnsibleMapping detects the kind of a file whose content is a map,
+// i.e. a vars file, a role meta file, a requirements file or a YAML inventory.
+func classifyAnsibleMapping(root *yaml.Node, classification func(string, string, string) ansibleFileClassification) ansibleFileClassification {
+	switch {
+	case mappingValue(root, "galaxy_info") != nil:
+		return classification(fileKindRoleMeta, confidenceHigh, "file has a galaxy_info key")
+	case mappingValue(root, "namespace") != nil && mappingValue(root, "name") != nil && mappingValue(root, "version") != nil && mappingValue(root, "authors") != nil:
+		return classification(fileKindGalaxy, confidenceMedium, "file has the namespace, name, version and authors keys of a collection")
+	}
+
+	roles, collections := mappingValue(root, "roles"), mappingValue(root, "collections")
+	if (roles != nil && roles.Kind == yaml.SequenceNode) || (collections != nil && collections.Kind == yaml.SequenceNode) {
+		if len(root.Content) <= 4 {
+			return classification(fileKindRequirements, confidenceMedium, "file only has roles and collections lists")
+		}
+	}
+
+	if all := mappingValue(root, "all"); all != nil && (mappingValue(all, "hosts") != nil || mappingValue(all, "children") != nil) {
+		return classification(fileKindInventory, confidenceMedium, "file has an all group with hosts or children")
+	}
+
+	// Common YAML files found next to playbooks
+	switch {
+	case mappingValue(root, "services") != nil:
+		return classification(fileKindUnknown, confidenceMedium, "file has a services key, as in Docker Compose files")
+	case mappingValue(root, "jobs") != nil || mappingValue(root, "stages") != nil:
+		return classification(fileKindUnknown, confidenceMedium, "file has a jobs or stages key, as in CI configuration files")
+	case mappingValue(root, "apiVersion") != nil && mappingValue(root, "kind") != nil:
+		return classification(fileKindUnknown, confidenceMedium, "file has apiVersion and kind keys, as in Kubernetes manifests")
+	}
+
+	return classification(fileKindVars, confidenceLow, "top-level content is a map of variables")
+}
+
+// hasMappingItems reports whether a node is a map or a list of maps.
+func hasMappingItems(node *yaml.Node) bool {
+	if node.Kind == yaml.MappingNode {
+		return true
+	}
+	for _, item := range node.Content {
+		if item.Kind == yaml.MappingNode {
+			return true
+		}
+	}
+	return false
+}
+
+func isYAMLFileName(name string) bool {
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
@@ -0,0 +1,73 @@
+package ansible
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/relex/aini"
+)
+
+// inventorySectionRegex matches the section headers of an INI inventory, as
+// parsed by aini, e.g. [webservers] or [webservers:vars].
+var inventorySectionRegex = regexp.MustCompile(`^\[([^:\]\s]+)(?::(\w+))?\]\s*(?:\#.*)?$`)
+
+// inventoryLocations holds the location of the groups and hosts of an INI
+// inventory, which aini does not keep.
+type inventoryLocations struct {
+	Groups map[string]sourceLocation
+	Hosts  map[string]sourceLocation
+}
+
+// scanInventoryLocations finds where groups and hosts are declared. A group is
+// located at its first section, from the header to its last entry. A host is
+// located at the first line declaring it. The implicit all and ungrouped
+// groups have no location.
+func scanInventoryLocations(content []byte) inventoryLocations {
+	locations := inventoryLocations{
+		Groups: map[string]sourceLocation{},
+		Hosts:  map[string]sourceLocation{},
+	}
+
+	var group string
+	inHosts := true
+	for i, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		number := i + 1
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+
+		if matches := inventorySectionRegex.FindStringSubmatch(trimmed); matches != nil {
+			group = ""
+			if _, ok := locations.Groups[matches[1]]; !ok {
+				group = matches[1]
+				locations.Groups[group] = sourceLocation{EndLine: number, StartColumn: column, StartLine: number}
+			}
+			inHosts = matches[2] == "" || matches[2] == "hosts"
+			continue
+		}
+
+		if group != "" {
+			location := locations.Groups[group]
+			location.EndLine = number
+			locations.Groups[group] = location
+		}
+
+		if !inHosts {
+			continue
+		}
+		// Parse the line on its own to expand host ranges such as web[01:10]
+		data, err := aini.ParseString(trimmed)
+		if err != nil {
+			continue
+		}
+		for name := range data.Hosts {
+			if _, ok := locations.Hosts[name]; !ok {
+				locations.Hosts[name] = sourceLocation{EndLine: number, StartColumn: column, StartLine: number}
+			}
+		}
+	}
+
+	return locations
+}
@@ -0,0 +1,219 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleVarsFileValues are the values of a vars file, e.g. a group_vars file
+// or the defaults of a role.
+type ansibleVarsFileValues struct {
+	// Opaque is set if the variables of the file cannot be read, e.g. because
+	// it is encrypted with ansible-vault
+	Opaque bool
+	Values map[string]interface{}
+}
+
+// parseAnsibleVarsFileValues parses a vars file, written in YAML or JSON.
+// Templated values are unknown.
+func parseAnsibleVarsFileValues(content []byte) ansibleVarsFileValues {
+	var decoded interface{}
+	if err := yaml.Unmarshal(content, &decoded); err != nil {
+		return ansibleVarsFileValues{Opaque: true}
+	}
+	if decoded == nil {
+		return ansibleVarsFileValues{}
+	}
+	mapping, ok := normalizeYAMLValue(decoded).(map[string]interface{})
+	if !ok {
+		return ansibleVarsFileValues{Opaque: true}
+	}
+
+	values := make(map[string]interface{}, len(mapping))
+	for name, value := range mapping {
+		value, templated := jinjaValueFromYAML(value)
+		if templated {
+			value = jinjaUnknown{Reason: fmt.Sprintf("variable %s is templated", name)}
+		}
+		values[name] = value
+	}
+	return ansibleVarsFileValues{Values: values}
+}
+
+// ansibleHostVarsSources are the variables a play reads beside its own vars
+// and the inventory files: those of the group_vars and host_vars directories
+// next to the inventories and the playbook, and the defaults and vars of the
+// roles found from the playbook.
+type ansibleHostVarsSources struct {
+	// The group_vars and host_vars files by group or host name, next to the
+	// inventories at index 0 and next to the playbook, which take
+	// precedence, at index 1
+	groups [2]map[string]*ansibleVarsFileValues
+	hosts  [2]map[string]*ansibleVarsFileValues
+	// The roles defining each variable in their defaults or vars
+	roleDefaults map[string][]string
+	roleVars     map[string][]string
+}
+
+// loadAnsibleHostVarsSources reads the vars files of the group_vars and
+// host_vars directories next to the inventories and the playbook, and of the
+// roles found from the playbook.
+func loadAnsibleHostVarsSources(ctx context.Context, d *plugin.QueryData, metrics *parseMetrics, playbook string, inventories []string) (*ansibleHostVarsSources, error) {
+	files, err := listAnsibleImplicitVarsFiles([]string{playbook}, inventories)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := &ansibleHostVarsSources{roleDefaults: map[string][]string{}, roleVars: map[string][]string{}}
+	for i := range sources.groups {
+		sources.groups[i] = map[string]*ansibleVarsFileValues{}
+		sources.hosts[i] = map[string]*ansibleVarsFileValues{}
+	}
+
+	playbookDir := filepath.Dir(playbook)
+	for _, path := range files {
+		values, err := getAnsibleVarsFileValues(ctx, d, filePath{Metrics: metrics, Path: path})
+		if err != nil {
+			plugin.Logger(ctx).Error("loadAnsibleHostVarsSources", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+
+		switch source := ansibleVarsFileSource(path); source {
+		case variableSourceRoleDefaults, variableSourceRoleVars:
+			_, role := ansibleRoleFromPath(path)
+			names := sources.roleDefaults
+			if source == variableSourceRoleVars {
+				names = sources.roleVars
+			}
+			for name := range values.Values {
+				names[name] = append(names[name], role)
+			}
+		case variableSourceGroupVars, variableSourceHostVars:
+			dir, name := splitAnsibleGroupHostVarsPath(path)
+			precedence := 0
+			if dir == playbookDir {
+				precedence = 1
+			}
+			byName := sources.groups[precedence]
+			if source == variableSourceHostVars {
+				byName = sources.hosts[precedence]
+			}
+			merged, ok := byName[name]
+			if !ok {
+				merged = &ansibleVarsFileValues{Values: map[string]interface{}{}}
+				byName[name] = merged
+			}
+			merged.Opaque = merged.Opaque || values.Opaque
+			for key, value := range values.Values {
+				merged.Values[key] = value
+			}
+		}
+	}
+
+	return sources, nil
+}
+
+// splitAnsibleGroupHostVarsPath returns the directory holding the group_vars
+// or host_vars directory of a vars file, and the group or host the file
+// belongs to, e.g. /src and web for /src/group_vars/web/main.yml or
+// /src/group_vars/web.yml.
+func splitAnsibleGroupHostVarsPath(path string) (string, string) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == "group_vars" || parts[i] == "host_vars" {
+			name := parts[i+1]
+			if i+2 == len(parts) {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			return filepath.FromSlash(strings.Join(parts[:i], "/")), name
+		}
+	}
+	return "", ""
+}
+
+// lookup returns the value of a variable for a host from the inventory
+// files, the group_vars and host_vars files and the role defaults, following
+// the precedence of Ansible. It reports false if the variable is not defined
+// by any of them.
+func (s *ansibleHostVarsSources) lookup(host *ansibleInventoryHost, name string) (interface{}, bool) {
+	opaque := false
+
+	// host_vars files take precedence over the inventory files
+	for i := len(s.hosts) - 1; i >= 0; i-- {
+		if values, ok := s.hosts[i][host.Name]; ok {
+			if value, ok := values.Values[name]; ok {
+				return value, true
+			}
+			opaque = opaque || values.Opaque
+		}
+	}
+
+	// The group_vars files of the groups of the host, then those of the all
+	// group, the files next to the playbook taking precedence in each case.
+	// Ansible orders the other groups by depth and name, which are not known,
+	// so that different values are unknown.
+	var groups []string
+	for group := range host.Groups {
+		if group != "all" {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+
+	var groupValue interface{}
+	found := false
+	for _, group := range groups {
+		for i := len(s.groups) - 1; i >= 0; i-- {
+			values, ok := s.groups[i][group]
+			if !ok {
+				continue
+			}
+			opaque = opaque || values.Opaque
+			value, ok := values.Values[name]
+			if !ok {
+				continue
+			}
+			if !found {
+				groupValue, found = value, true
+			} else if !jinjaEqual(groupValue, value) {
+				groupValue = jinjaUnknown{Reason: fmt.Sprintf("variable %s is defined by several groups of the host", name)}
+			}
+			break
+		}
+	}
+	for i := len(s.groups) - 1; i >= 0 && !found; i-- {
+		if values, ok := s.groups[i]["all"]; ok {
+			if value, ok := values.Values[name]; ok {
+				groupValue, found = value, true
+			}
+			opaque = opaque || values.Opaque
+		}
+	}
+
+	// The inventory files mix the group and host variables, which are
+	// respectively lower and higher than the group_vars files
+	if value, ok := host.Vars[name]; ok {
+		inventory := inventoryValue(value)
+		if found && !jinjaEqual(groupValue, inventory) {
+			return jinjaUnknown{Reason: fmt.Sprintf("variable %s is defined by both an inventory and a group_vars file", name)}, true
+		}
+		return inventory, true
+	}
+	if found {
+		return groupValue, true
+	}
+
+	if opaque {
+		return jinjaUnknown{Reason: fmt.Sprintf("variable %s may be defined by an encrypted or invalid vars file", name)}, true
+	}
+	if roles, ok := s.roleDefaults[name]; ok {
+		return jinjaUnknown{Reason: fmt.Sprintf("variable %s is defined by the defaults of role %s", name, strings.Join(roles, ", "))}, true
+	}
+	return nil, false
+}
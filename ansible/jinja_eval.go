@@ -0,0 +1,936 @@
+package ansible
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The evaluator below covers the subset of Jinja2 used by most conditionals,
+// e.g. `ansible_os_family == 'Debian' and 'web' in group_names`, over static
+// values such as inventory variables: literals, comparisons, boolean logic,
+// arithmetic, attributes and subscripts, and common filters and tests. Values
+// only known when the playbook runs, e.g. facts, are unknown, and make the
+// result unknown unless the boolean logic does not depend on them.
+
+// Results of a conditional
+const (
+	conditionFalse   = "false"
+	conditionTrue    = "true"
+	conditionUnknown = "unknown"
+)
+
+// jinjaUndefined is a variable that is not defined.
+type jinjaUndefined struct {
+	Name string
+}
+
+// jinjaUnknown is a value that cannot be known statically, e.g. a fact.
+type jinjaUnknown struct {
+	Reason string
+}
+
+// jinjaEvaluator evaluates an expression, reusing the token helpers of the
+// parser.
+type jinjaEvaluator struct {
+	*jinjaParser
+	// lookup returns the value of a variable, or a jinjaUndefined or
+	// jinjaUnknown value
+	lookup func(name string) interface{}
+}
+
+// evaluateAnsibleCondition evaluates a conditional, e.g. of the when keyword,
+// and returns true, false or unknown, along with the reason for an unknown
+// result.
+func evaluateAnsibleCondition(condition string, lookup func(name string) interface{}) (string, string) {
+	expression := strings.TrimSpace(condition)
+	// Ansible accepts, with a warning, conditionals wrapped in delimiters
+	if strings.HasPrefix(expression, "{{") && strings.HasSuffix(expression, "}}") && strings.Count(expression, "{{") == 1 {
+		expression = strings.TrimSpace(expression[2 : len(expression)-2])
+	}
+
+	value, err := evaluateJinjaExpression(expression, lookup)
+	if err != nil {
+		return conditionUnknown, err.Error()
+	}
+
+	// Like Ansible, a bare variable holding a string is evaluated as a
+	// conditional itself, e.g. a flag set to "false" in an INI inventory
+	if s, ok := value.(string); ok && isIdentifier(expression) {
+		value, err = evaluateJinjaExpression(s, lookup)
+		if err != nil {
+			return conditionUnknown, err.Error()
+		}
+	}
+
+	switch v := jinjaKnown(value).(type) {
+	case jinjaUnknown:
+		return conditionUnknown, v.Reason
+	default:
+		if jinjaTruthy(v) {
+			return conditionTrue, ""
+		}
+		return conditionFalse, ""
+	}
+}
+
+// evaluateJinjaExpression evaluates a bare expression. Errors are syntax
+// errors and unsupported constructs.
+func evaluateJinjaExpression(expression string, lookup func(name string) interface{}) (interface{}, error) {
+	tokens, _, err := tokenizeJinja(expression, 0, 1, "")
+	if err != nil {
+		return nil, err
+	}
+	e := &jinjaEvaluator{jinjaParser: newJinjaParser(), lookup: lookup}
+	e.tokens = tokens
+
+	value, err := e.evalExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.expectEnd(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (e *jinjaEvaluator) evalExpression() (interface{}, error) {
+	value, err := e.evalOr()
+	if err != nil {
+		return nil, err
+	}
+	if !e.skipName("if") {
+		return value, nil
+	}
+
+	condition, err := e.evalOr()
+	if err != nil {
+		return nil, err
+	}
+	var otherwise interface{} = jinjaUndefined{Name: "else"}
+	if e.skipName("else") {
+		if otherwise, err = e.evalExpression(); err != nil {
+			return nil, err
+		}
+	}
+	switch c := jinjaKnown(condition).(type) {
+	case jinjaUnknown:
+		return c, nil
+	default:
+		if jinjaTruthy(c) {
+			return value, nil
+		}
+		return otherwise, nil
+	}
+}
+
+func (e *jinjaEvaluator) evalOr() (interface{}, error) {
+	left, err := e.evalAnd()
+	if err != nil {
+		return nil, err
+	}
+	for e.skipName("or") {
+		right, err := e.evalAnd()
+		if err != nil {
+			return nil, err
+		}
+		l, r := jinjaKnown(left), jinjaKnown(right)
+		switch {
+		case !isJinjaUnknown(l) && jinjaTruthy(l):
+			left = l
+		case !isJinjaUnknown(l):
+			left = r
+		case !isJinjaUnknown(r) && jinjaTruthy(r):
+			left = r
+		default:
+			left = l
+		}
+	}
+	return left, nil
+}
+
+func (e *jinjaEvaluator) evalAnd() (interface{}, error) {
+	left, err := e.evalNot()
+	if err != nil {
+		return nil, err
+	}
+	for e.skipName("and") {
+		right, err := e.evalNot()
+		if err != nil {
+			return nil, err
+		}
+		l, r := jinjaKnown(left), jinjaKnown(right)
+		switch {
+		case !isJinjaUnknown(l) && !jinjaTruthy(l):
+			left = l
+		case !isJinjaUnknown(l):
+			left = r
+		case !isJinjaUnknown(r) && !jinjaTruthy(r):
+			left = r
+		default:
+			left = l
+		}
+	}
+	return left, nil
+}
+
+func (e *jinjaEvaluator) evalNot() (interface{}, error) {
+	if e.skipName("not") {
+		value, err := e.evalNot()
+		if err != nil {
+			return nil, err
+		}
+		value = jinjaKnown(value)
+		if isJinjaUnknown(value) {
+			return value, nil
+		}
+		return !jinjaTruthy(value), nil
+	}
+	return e.evalCompare()
+}
+
+func (e *jinjaEvaluator) evalCompare() (interface{}, error) {
+	left, err := e.evalMath()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var operator string
+		switch {
+		case e.isOperator("==") || e.isOperator("!=") || e.isOperator("<") || e.isOperator(">") || e.isOperator("<=") || e.isOperator(">="):
+			operator = e.next().Value
+		case e.isName("in"):
+			e.next()
+			operator = "in"
+		case e.isName("not") && e.peekAt(1).Kind == jinjaTokenName && e.peekAt(1).Value == "in":
+			e.next()
+			e.next()
+			operator = "not in"
+		default:
+			return left, nil
+		}
+		right, err := e.evalMath()
+		if err != nil {
+			return nil, err
+		}
+		left = jinjaCompare(operator, left, right)
+	}
+}
+
+// evalMath evaluates the arithmetic and concatenation operators, with their
+// Jinja2 precedence.
+func (e *jinjaEvaluator) evalMath() (interface{}, error) {
+	return e.evalBinary([][]string{{"+", "-"}, {"~"}, {"*", "/", "//", "%"}, {"**"}}, 0)
+}
+
+func (e *jinjaEvaluator) evalBinary(levels [][]string, level int) (interface{}, error) {
+	if level == len(levels) {
+		return e.evalUnary()
+	}
+	left, err := e.evalBinary(levels, level+1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := e.peek()
+		if token.Kind != jinjaTokenOperator || !containsString(levels[level], token.Value) {
+			return left, nil
+		}
+		e.next()
+		right, err := e.evalBinary(levels, level+1)
+		if err != nil {
+			return nil, err
+		}
+		left, err = jinjaArithmetic(token.Value, left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (e *jinjaEvaluator) evalUnary() (interface{}, error) {
+	var value interface{}
+	var err error
+	switch {
+	case e.skipOperator("-"):
+		if value, err = e.evalUnary(); err != nil {
+			return nil, err
+		}
+		value, err = jinjaArithmetic("*", value, int64(-1))
+	case e.skipOperator("+"):
+		value, err = e.evalUnary()
+	default:
+		if value, err = e.evalPrimary(); err != nil {
+			return nil, err
+		}
+		value, err = e.evalPostfix(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.evalFilters(value)
+}
+
+func (e *jinjaEvaluator) evalPrimary() (interface{}, error) {
+	token := e.peek()
+	switch token.Kind {
+	case jinjaTokenName:
+		if jinjaKeywords[token.Value] {
+			return nil, e.unexpected("expected an expression")
+		}
+		e.next()
+		if jinjaConstants[token.Value] {
+			switch strings.ToLower(token.Value) {
+			case "true":
+				return true, nil
+			case "false":
+				return false, nil
+			}
+			return nil, nil
+		}
+		if jinjaGlobals[token.Value] && e.isOperator("(") {
+			return nil, fmt.Errorf("unsupported function %s", token.Value)
+		}
+		return e.lookup(token.Value), nil
+	case jinjaTokenNumber:
+		e.next()
+		value := strings.ReplaceAll(token.Value, "_", "")
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid number %s", token.Line, token.Value)
+		}
+		return f, nil
+	case jinjaTokenString:
+		var s strings.Builder
+		for e.peek().Kind == jinjaTokenString {
+			s.WriteString(e.next().Value)
+		}
+		return s.String(), nil
+	case jinjaTokenOperator:
+		switch token.Value {
+		case "(", "[":
+			closing := map[string]string{"(": ")", "[": "]"}[token.Value]
+			e.next()
+			var items []interface{}
+			tuple := false
+			for !e.skipOperator(closing) {
+				item, err := e.evalExpression()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+				if e.skipOperator(",") {
+					tuple = true
+				} else if !e.isOperator(closing) {
+					return nil, e.unexpected("expected " + closing)
+				}
+			}
+			// A parenthesized expression rather than a tuple
+			if token.Value == "(" && !tuple && len(items) == 1 {
+				return items[0], nil
+			}
+			return items, nil
+		case "{":
+			e.next()
+			items := map[string]interface{}{}
+			for !e.skipOperator("}") {
+				key, err := e.evalExpression()
+				if err != nil {
+					return nil, err
+				}
+				if err := e.expectOperator(":"); err != nil {
+					return nil, err
+				}
+				value, err := e.evalExpression()
+				if err != nil {
+					return nil, err
+				}
+				items[fmt.Sprint(key)] = value
+				if !e.skipOperator(",") && !e.isOperator("}") {
+					return nil, e.unexpected("expected }")
+				}
+			}
+			return items, nil
+		}
+	}
+	return nil, e.unexpected("expected an expression")
+}
+
+// evalPostfix evaluates the attributes and subscripts of a value.
+func (e *jinjaEvaluator) evalPostfix(value interface{}) (interface{}, error) {
+	for {
+		switch {
+		case e.skipOperator("."):
+			if kind := e.peek().Kind; kind != jinjaTokenName && kind != jinjaTokenNumber {
+				return nil, e.unexpected("expected an attribute")
+			}
+			token := e.next()
+			if e.isOperator("(") {
+				return nil, fmt.Errorf("unsupported method call %s", token.Value)
+			}
+			value = jinjaGetItem(value, token.Value)
+		case e.skipOperator("["):
+			key, err := e.evalExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := e.expectOperator("]"); err != nil {
+				return nil, err
+			}
+			value = jinjaGetItem(value, key)
+		case e.isOperator("("):
+			return nil, fmt.Errorf("unsupported function call")
+		default:
+			return value, nil
+		}
+	}
+}
+
+// evalFilters evaluates the filters and tests applied to a value.
+func (e *jinjaEvaluator) evalFilters(value interface{}) (interface{}, error) {
+	for {
+		switch {
+		case e.skipOperator("|"):
+			name, err := e.parseDottedName()
+			if err != nil {
+				return nil, err
+			}
+			args, err := e.evalArgs()
+			if err != nil {
+				return nil, err
+			}
+			if value, err = jinjaApplyFilter(name.Name, value, args); err != nil {
+				return nil, err
+			}
+		case e.isName("is"):
+			e.next()
+			negate := e.skipName("not")
+			name, err := e.parseDottedName()
+			if err != nil {
+				return nil, err
+			}
+			var args []interface{}
+			next := e.peek()
+			switch {
+			case e.isOperator("("):
+				if args, err = e.evalArgs(); err != nil {
+					return nil, err
+				}
+			case next.Kind == jinjaTokenString || next.Kind == jinjaTokenNumber || e.isOperator("[") || e.isOperator("{") ||
+				(next.Kind == jinjaTokenName && !jinjaKeywords[next.Value]):
+				arg, err := e.evalPrimary()
+				if err != nil {
+					return nil, err
+				}
+				if arg, err = e.evalPostfix(arg); err != nil {
+					return nil, err
+				}
+				args = []interface{}{arg}
+			}
+			result, err := jinjaApplyTest(name.Name, value, args)
+			if err != nil {
+				return nil, err
+			}
+			if b, ok := result.(bool); ok && negate {
+				result = !b
+			}
+			value = result
+		default:
+			return value, nil
+		}
+	}
+}
+
+// evalArgs evaluates the positional arguments of a filter or test call, if
+// any.
+func (e *jinjaEvaluator) evalArgs() ([]interface{}, error) {
+	if !e.skipOperator("(") {
+		return nil, nil
+	}
+	var args []interface{}
+	for !e.skipOperator(")") {
+		if e.peek().Kind == jinjaTokenName && e.peekAt(1).Kind == jinjaTokenOperator && e.peekAt(1).Value == "=" {
+			return nil, fmt.Errorf("unsupported keyword argument %s", e.peek().Value)
+		}
+		arg, err := e.evalExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !e.skipOperator(",") && !e.isOperator(")") {
+			return nil, e.unexpected("expected )")
+		}
+	}
+	return args, nil
+}
+
+//// VALUES
+
+// jinjaKnown returns the value, or an unknown value for an undefined
+// variable, which fails the task if used.
+func jinjaKnown(value interface{}) interface{} {
+	if u, ok := value.(jinjaUndefined); ok {
+		return jinjaUnknown{Reason: fmt.Sprintf("variable %s is not defined", u.Name)}
+	}
+	return value
+}
+
+func isJinjaUnknown(value interface{}) bool {
+	_, ok := value.(jinjaUnknown)
+	return ok
+}
+
+// jinjaTruthy mirrors the truth value of Python objects.
+func jinjaTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// jinjaFloat returns the value of a number.
+func jinjaFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		// Python booleans are integers
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// jinjaEqual compares two values, numbers by value.
+func jinjaEqual(a interface{}, b interface{}) bool {
+	if x, ok := jinjaFloat(a); ok {
+		if y, ok := jinjaFloat(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// jinjaCompare evaluates a comparison or membership operator.
+func jinjaCompare(operator string, left interface{}, right interface{}) interface{} {
+	left, right = jinjaKnown(left), jinjaKnown(right)
+	if isJinjaUnknown(left) {
+		return left
+	}
+	if isJinjaUnknown(right) {
+		return right
+	}
+
+	switch operator {
+	case "==":
+		return jinjaEqual(left, right)
+	case "!=":
+		return !jinjaEqual(left, right)
+	case "in", "not in":
+		var found bool
+		switch container := right.(type) {
+		case string:
+			s, ok := left.(string)
+			if !ok {
+				return jinjaUnknown{Reason: "in operator with a string requires a string"}
+			}
+			found = strings.Contains(container, s)
+		case []interface{}:
+			for _, item := range container {
+				if jinjaEqual(left, item) {
+					found = true
+					break
+				}
+			}
+		case map[string]interface{}:
+			_, found = container[fmt.Sprint(left)]
+		default:
+			return jinjaUnknown{Reason: fmt.Sprintf("in operator is not supported for %T", right)}
+		}
+		return found == (operator == "in")
+	}
+
+	// Ordering comparisons of numbers or strings
+	var cmp int
+	x, xok := jinjaFloat(left)
+	y, yok := jinjaFloat(right)
+	s, sok := left.(string)
+	t, tok := right.(string)
+	switch {
+	case xok && yok:
+		cmp = compareFloats(x, y)
+	case sok && tok:
+		cmp = strings.Compare(s, t)
+	default:
+		return jinjaUnknown{Reason: fmt.Sprintf("cannot compare %v and %v", left, right)}
+	}
+	switch operator {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func compareFloats(x float64, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// jinjaArithmetic evaluates an arithmetic or concatenation operator.
+func jinjaArithmetic(operator string, left interface{}, right interface{}) (interface{}, error) {
+	left, right = jinjaKnown(left), jinjaKnown(right)
+	if isJinjaUnknown(left) {
+		return left, nil
+	}
+	if isJinjaUnknown(right) {
+		return right, nil
+	}
+
+	if operator == "~" {
+		return jinjaString(left) + jinjaString(right), nil
+	}
+	if operator == "+" {
+		if s, ok := left.(string); ok {
+			if t, ok := right.(string); ok {
+				return s + t, nil
+			}
+		}
+		if s, ok := left.([]interface{}); ok {
+			if t, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, s...), t...), nil
+			}
+		}
+	}
+
+	x, xok := jinjaFloat(left)
+	y, yok := jinjaFloat(right)
+	if !xok || !yok {
+		return jinjaUnknown{Reason: fmt.Sprintf("unsupported operands for %s: %v and %v", operator, left, right)}, nil
+	}
+	_, leftFloat := left.(float64)
+	_, rightFloat := right.(float64)
+	integers := !leftFloat && !rightFloat
+
+	var result float64
+	switch operator {
+	case "+":
+		result = x + y
+	case "-":
+		result = x - y
+	case "*":
+		result = x * y
+	case "**":
+		result = math.Pow(x, y)
+	case "/", "//", "%":
+		if y == 0 {
+			return jinjaUnknown{Reason: "division by zero"}, nil
+		}
+		switch operator {
+		case "/":
+			return x / y, nil
+		case "//":
+			result = math.Floor(x / y)
+		default:
+			result = x - y*math.Floor(x/y)
+		}
+	}
+	if integers {
+		return int64(result), nil
+	}
+	return result, nil
+}
+
+// jinjaGetItem returns an attribute or item of a value. A missing attribute
+// is undefined, and so is any attribute of an undefined value.
+func jinjaGetItem(value interface{}, key interface{}) interface{} {
+	switch v := value.(type) {
+	case jinjaUnknown:
+		return v
+	case jinjaUndefined:
+		return jinjaUndefined{Name: fmt.Sprintf("%s.%v", v.Name, key)}
+	case map[string]interface{}:
+		if item, ok := v[fmt.Sprint(key)]; ok {
+			return item
+		}
+		return jinjaUndefined{Name: fmt.Sprint(key)}
+	case []interface{}:
+		if f, ok := jinjaFloat(key); ok {
+			i := int(f)
+			if i < 0 {
+				i += len(v)
+			}
+			if i >= 0 && i < len(v) {
+				return v[i]
+			}
+		}
+		if s, ok := key.(string); ok {
+			if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < len(v) {
+				return v[i]
+			}
+		}
+	}
+	return jinjaUndefined{Name: fmt.Sprint(key)}
+}
+
+// jinjaString converts a value to a string, as Python does for the values of
+// inventories.
+func jinjaString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if v {
+			return "True"
+		}
+		return "False"
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// jinjaApplyFilter applies a filter among default, bool, lower, upper, int,
+// float, string, length, trim, first and last.
+func jinjaApplyFilter(name string, value interface{}, args []interface{}) (interface{}, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "ansible.builtin."), "ansible.legacy.")
+
+	if name == "default" || name == "d" {
+		var fallback interface{} = ""
+		if len(args) > 0 {
+			fallback = args[0]
+		}
+		checkFalsy := len(args) > 1 && jinjaTruthy(args[1])
+		switch v := value.(type) {
+		case jinjaUndefined:
+			return fallback, nil
+		case jinjaUnknown:
+			return v, nil
+		}
+		if checkFalsy && !jinjaTruthy(value) {
+			return fallback, nil
+		}
+		return value, nil
+	}
+
+	value = jinjaKnown(value)
+	if isJinjaUnknown(value) {
+		return value, nil
+	}
+
+	switch name {
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "yes", "on", "1", "true":
+				return true, nil
+			}
+			return false, nil
+		case int64:
+			return v == 1, nil
+		case float64:
+			return v == 1, nil
+		}
+		return false, nil
+	case "lower", "upper", "trim":
+		s := jinjaString(value)
+		switch name {
+		case "lower":
+			return strings.ToLower(s), nil
+		case "upper":
+			return strings.ToUpper(s), nil
+		}
+		return strings.TrimSpace(s), nil
+	case "string":
+		return jinjaString(value), nil
+	case "int", "float":
+		var f float64
+		switch v := value.(type) {
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				// Like Jinja2, values that cannot be converted default to 0
+				parsed = 0
+				if len(args) > 0 {
+					if d, ok := jinjaFloat(args[0]); ok {
+						parsed = d
+					}
+				}
+			}
+			f = parsed
+		default:
+			f, _ = jinjaFloat(value)
+		}
+		if name == "int" {
+			return int64(f), nil
+		}
+		return f, nil
+	case "length", "count":
+		switch v := value.(type) {
+		case string:
+			return int64(len([]rune(v))), nil
+		case []interface{}:
+			return int64(len(v)), nil
+		case map[string]interface{}:
+			return int64(len(v)), nil
+		}
+		return jinjaUnknown{Reason: fmt.Sprintf("length filter is not supported for %T", value)}, nil
+	case "first", "last":
+		var items []interface{}
+		switch v := value.(type) {
+		case []interface{}:
+			items = v
+		case string:
+			for _, r := range v {
+				items = append(items, string(r))
+			}
+		}
+		if len(items) == 0 {
+			return jinjaUndefined{Name: name}, nil
+		}
+		if name == "first" {
+			return items[0], nil
+		}
+		return items[len(items)-1], nil
+	}
+
+	return nil, fmt.Errorf("unsupported filter %s", name)
+}
+
+// jinjaApplyTest applies a test, returning a boolean or an unknown value.
+func jinjaApplyTest(name string, value interface{}, args []interface{}) (interface{}, error) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "ansible.builtin."), "ansible.legacy.")
+
+	switch name {
+	case "defined", "undefined":
+		switch value.(type) {
+		case jinjaUnknown:
+			return value, nil
+		case jinjaUndefined:
+			return name == "undefined", nil
+		}
+		return name == "defined", nil
+	}
+
+	value = jinjaKnown(value)
+	if isJinjaUnknown(value) {
+		return value, nil
+	}
+	arg := func() (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("test %s requires an argument", name)
+		}
+		return jinjaKnown(args[0]), nil
+	}
+
+	switch name {
+	case "none":
+		return value == nil, nil
+	case "true", "false":
+		b, ok := value.(bool)
+		return ok && b == (name == "true"), nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok, nil
+	case "string":
+		_, ok := value.(string)
+		return ok, nil
+	case "number":
+		_, ok := jinjaFloat(value)
+		_, isBool := value.(bool)
+		return ok && !isBool, nil
+	case "integer":
+		_, ok := value.(int64)
+		return ok, nil
+	case "float":
+		_, ok := value.(float64)
+		return ok, nil
+	case "mapping":
+		_, ok := value.(map[string]interface{})
+		return ok, nil
+	case "sequence", "iterable":
+		switch value.(type) {
+		case string, []interface{}, map[string]interface{}:
+			return true, nil
+		}
+		return false, nil
+	case "truthy", "falsy":
+		return jinjaTruthy(value) == (name == "truthy"), nil
+	case "even", "odd":
+		f, ok := jinjaFloat(value)
+		if !ok {
+			return jinjaUnknown{Reason: fmt.Sprintf("test %s requires a number", name)}, nil
+		}
+		return (int64(f)%2 == 0) == (name == "even"), nil
+	case "divisibleby":
+		a, err := arg()
+		if err != nil {
+			return nil, err
+		}
+		x, xok := jinjaFloat(value)
+		y, yok := jinjaFloat(a)
+		if !xok || !yok || y == 0 {
+			return jinjaUnknown{Reason: "test divisibleby requires numbers"}, nil
+		}
+		return math.Mod(x, y) == 0, nil
+	}
+
+	operators := map[string]string{
+		"eq": "==", "equalto": "==", "==": "==", "ne": "!=", "!=": "!=",
+		"gt": ">", "greaterthan": ">", ">": ">", "ge": ">=", ">=": ">=",
+		"lt": "<", "lessthan": "<", "<": "<", "le": "<=", "<=": "<=",
+		"in": "in",
+	}
+	if operator, ok := operators[name]; ok {
+		a, err := arg()
+		if err != nil {
+			return nil, err
+		}
+		return jinjaCompare(operator, value, a), nil
+	}
+
+	return nil, fmt.Errorf("unsupported test %s", name)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
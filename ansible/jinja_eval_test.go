@@ -0,0 +1,89 @@
+package ansible
+
+import "testing"
+
+func TestEvaluateAnsibleCondition(t *testing.T) {
+	// Numbers are int64, as converted from YAML by jinjaValueFromYAML
+	vars := map[string]interface{}{
+		"env":      "prod",
+		"port":     int64(8080),
+		"enabled":  true,
+		"flag":     "false",
+		"packages": []interface{}{"nginx", "git"},
+		"app":      map[string]interface{}{"name": "web", "replicas": int64(3)},
+		"result":   jinjaUnknown{Reason: "variable result is registered"},
+	}
+	lookup := func(name string) interface{} {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return jinjaUndefined{Name: name}
+	}
+
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{condition: "env == 'prod'", want: conditionTrue},
+		{condition: "env != 'prod'", want: conditionFalse},
+		{condition: "{{ env == 'prod' }}", want: conditionTrue},
+		{condition: "port > 1024 and enabled", want: conditionTrue},
+		{condition: "port < 1024 or not enabled", want: conditionFalse},
+		{condition: "'git' in packages", want: conditionTrue},
+		{condition: "'vim' not in packages", want: conditionTrue},
+		{condition: "app.name == 'web'", want: conditionTrue},
+		{condition: "app['replicas'] >= 3", want: conditionTrue},
+		{condition: "packages | length == 2", want: conditionTrue},
+		{condition: "env | upper == 'PROD'", want: conditionTrue},
+		{condition: "flag", want: conditionFalse},
+		{condition: "missing is defined", want: conditionFalse},
+		{condition: "missing is undefined", want: conditionTrue},
+		{condition: "missing | default('x') == 'x'", want: conditionTrue},
+		{condition: "result is changed", want: conditionUnknown},
+		{condition: "result.rc == 0", want: conditionUnknown},
+		{condition: "result.rc == 0 and env == 'dev'", want: conditionFalse},
+		{condition: "result.rc == 0 or env == 'prod'", want: conditionTrue},
+		{condition: "env | regex_search('pr')", want: conditionUnknown},
+		{condition: "env ==", want: conditionUnknown},
+	}
+
+	for _, test := range tests {
+		t.Run(test.condition, func(t *testing.T) {
+			got, reason := evaluateAnsibleCondition(test.condition, lookup)
+			if got != test.want {
+				t.Errorf("got %s (%s), want %s", got, reason, test.want)
+			}
+			if got == conditionUnknown && reason == "" {
+				t.Errorf("got unknown without a reason")
+			}
+		})
+	}
+}
+
+func TestEvaluateJinjaExpression(t *testing.T) {
+	lookup := func(name string) interface{} { return jinjaUndefined{Name: name} }
+
+	tests := []struct {
+		expression string
+		want       interface{}
+	}{
+		{expression: "1 + 2 * 3", want: int64(7)},
+		{expression: "'a' ~ 'b'", want: "ab"},
+		{expression: "[1, 2, 3] | length", want: int64(3)},
+		{expression: "'x' if true else 'y'", want: "x"},
+		{expression: "10 // 3", want: int64(3)},
+		{expression: "not false", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := evaluateJinjaExpression(test.expression, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jinjaEqual(got, test.want) {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
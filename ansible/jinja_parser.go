@@ -0,0 +1,1081 @@
+package ansible
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Jinja2 expressions are found in most strings of a playbook, e.g.
+// "{{ app_dir }}/config", in the bare conditionals of keywords such as when,
+// and in template files. The tokenizer and parser below cover the Jinja2
+// syntax used with Ansible, to find the variables, filters, tests and lookups
+// that expressions use. They do not build a syntax tree or evaluate anything.
+
+// Kinds of Jinja2 tokens
+const (
+	jinjaTokenEOF = iota
+	jinjaTokenName
+	jinjaTokenNumber
+	jinjaTokenOperator
+	jinjaTokenString
+)
+
+type jinjaToken struct {
+	Kind  int
+	Line  int
+	Value string
+}
+
+// jinjaOperators are the operators and delimiters of Jinja2 expressions,
+// longest first.
+var jinjaOperators = []string{
+	"**", "//", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "~", "<", ">", "=", ".", ",", ":", "|", "(", ")", "[", "]", "{", "}",
+}
+
+// jinjaKeywords are the names that are operators rather than variables.
+var jinjaKeywords = map[string]bool{
+	"and":  true,
+	"else": true,
+	"if":   true,
+	"in":   true,
+	"is":   true,
+	"not":  true,
+	"or":   true,
+}
+
+// jinjaConstants are the literal names of Jinja2.
+var jinjaConstants = map[string]bool{
+	"false": true,
+	"False": true,
+	"none":  true,
+	"None":  true,
+	"true":  true,
+	"True":  true,
+}
+
+// jinjaGlobals are the global functions and special names of Jinja2 and
+// Ansible templates, which are not variables.
+var jinjaGlobals = map[string]bool{
+	"cycler":    true,
+	"dict":      true,
+	"joiner":    true,
+	"lipsum":    true,
+	"lookup":    true,
+	"namespace": true,
+	"now":       true,
+	"q":         true,
+	"query":     true,
+	"range":     true,
+	"self":      true,
+	"super":     true,
+	"undef":     true,
+}
+
+// jinjaLookupFunctions are the functions calling a lookup plugin.
+var jinjaLookupFunctions = map[string]bool{
+	"lookup": true,
+	"q":      true,
+	"query":  true,
+}
+
+// jinjaAnalysis is what the expressions of a string or a template use. Lines
+// start at 1 at the beginning of the analyzed text.
+type jinjaAnalysis struct {
+	Extends    []jinjaUsage
+	Filters    []jinjaUsage
+	Imports    []jinjaUsage
+	Includes   []jinjaUsage
+	Lookups    []jinjaLookup
+	Macros     []jinjaUsage
+	References []jinjaReference
+	Tests      []jinjaUsage
+}
+
+// jinjaUsage is a use of a named filter, test, macro or template. The name of
+// a template is empty if it is not a literal string.
+type jinjaUsage struct {
+	Line int
+	Name string
+}
+
+// jinjaLookup is a call to a lookup plugin, e.g. lookup('file', 'motd'). The
+// plugin is empty if it is not a literal string.
+type jinjaLookup struct {
+	Function string
+	Line     int
+	Plugin   string
+}
+
+// jinjaReference is a variable used by an expression, along with the filters
+// and tests applied to the expression it is part of, e.g. the default filter
+// of `foo.bar | default('x')`.
+type jinjaReference struct {
+	Filters []string
+	Line    int
+	Name    string
+	Tests   []string
+}
+
+// analyzeJinjaExpression analyzes a bare expression, such as a when
+// conditional. A string with delimiters is analyzed as a template, as Ansible
+// accepts both.
+func analyzeJinjaExpression(expression string) (jinjaAnalysis, error) {
+	if isTemplated(expression) {
+		return analyzeJinjaTemplate(expression)
+	}
+
+	p := newJinjaParser()
+	tokens, _, err := tokenizeJinja(expression, 0, 1, "")
+	if err != nil {
+		return p.analysis, err
+	}
+	p.tokens = tokens
+	if err := p.parseExpression(true); err != nil {
+		return p.analysis, err
+	}
+	if err := p.expectEnd(); err != nil {
+		return p.analysis, err
+	}
+	return p.analysis, nil
+}
+
+// analyzeJinjaTemplate analyzes a template, i.e. text with {{ }} expressions
+// and {% %} statements. The whole template is analyzed even if a tag cannot
+// be parsed, and the first error is returned.
+func analyzeJinjaTemplate(template string) (jinjaAnalysis, error) {
+	p := newJinjaParser()
+	var firstErr error
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	pos, line := 0, 1
+	for {
+		start := indexJinjaTag(template, pos)
+		if start == -1 {
+			break
+		}
+		line += strings.Count(template[pos:start], "\n")
+		open := template[start : start+2]
+		pos = start + 2
+		if pos < len(template) && (template[pos] == '-' || template[pos] == '+') {
+			pos++
+		}
+
+		switch open {
+		case "{#":
+			end := strings.Index(template[pos:], "#}")
+			if end == -1 {
+				setErr(fmt.Errorf("line %d: unclosed comment", line))
+				pos = len(template)
+				continue
+			}
+			line += strings.Count(template[pos:pos+end], "\n")
+			pos += end + 2
+			continue
+		case "{{":
+			tokens, next, err := tokenizeJinja(template, pos, line, "}}")
+			line += strings.Count(template[pos:next], "\n")
+			pos = next
+			if err != nil {
+				setErr(err)
+				continue
+			}
+			p.tokens, p.pos = tokens, 0
+			if err := p.parseExpression(true); err != nil {
+				setErr(err)
+			} else if err := p.expectEnd(); err != nil {
+				setErr(err)
+			}
+		case "{%":
+			tokens, next, err := tokenizeJinja(template, pos, line, "%}")
+			line += strings.Count(template[pos:next], "\n")
+			pos = next
+			if err != nil {
+				setErr(err)
+				continue
+			}
+			p.tokens, p.pos = tokens, 0
+			if len(tokens) > 1 && tokens[0].Value == "raw" {
+				// The content of a raw block is text
+				end := indexJinjaEndRaw(template, pos)
+				if end == -1 {
+					setErr(fmt.Errorf("line %d: unclosed raw block", line))
+					pos = len(template)
+					continue
+				}
+				line += strings.Count(template[pos:end], "\n")
+				pos = end
+				continue
+			}
+			if err := p.parseStatement(); err != nil {
+				setErr(err)
+			}
+		}
+	}
+
+	return p.analysis, firstErr
+}
+
+// indexJinjaTag returns the position of the next tag opening delimiter.
+func indexJinjaTag(template string, pos int) int {
+	for i := pos; i+1 < len(template); i++ {
+		if template[i] == '{' && (template[i+1] == '{' || template[i+1] == '%' || template[i+1] == '#') {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexJinjaEndRaw returns the position following the endraw tag.
+func indexJinjaEndRaw(template string, pos int) int {
+	for {
+		start := strings.Index(template[pos:], "{%")
+		if start == -1 {
+			return -1
+		}
+		pos += start + 2
+		rest := strings.TrimLeft(template[pos:], "-+ \t\r\n")
+		if strings.HasPrefix(rest, "endraw") {
+			end := strings.Index(template[pos:], "%}")
+			if end == -1 {
+				return -1
+			}
+			return pos + end + 2
+		}
+	}
+}
+
+// tokenizeJinja splits the expression starting at pos into tokens, up to the
+// closing delimiter end of a tag, or the end of the text if end is empty. It
+// returns the position following the closing delimiter.
+func tokenizeJinja(text string, pos int, line int, end string) ([]jinjaToken, int, error) {
+	var tokens []jinjaToken
+	depth := 0
+	for {
+		// Skip whitespace
+		for pos < len(text) && strings.ContainsRune(" \t\r\n", rune(text[pos])) {
+			if text[pos] == '\n' {
+				line++
+			}
+			pos++
+		}
+
+		if pos >= len(text) {
+			if end != "" {
+				return tokens, pos, fmt.Errorf("line %d: missing %s", line, end)
+			}
+			break
+		}
+
+		// The closing delimiter, with optional whitespace control
+		if end != "" && depth == 0 {
+			rest := text[pos:]
+			if strings.HasPrefix(rest, "-"+end) || strings.HasPrefix(rest, "+"+end) {
+				pos += len(end) + 1
+				break
+			}
+			if strings.HasPrefix(rest, end) {
+				pos += len(end)
+				break
+			}
+		}
+
+		c := text[pos]
+		switch {
+		case c == '_' || isASCIILetter(c):
+			start := pos
+			for pos < len(text) && (text[pos] == '_' || isASCIILetter(text[pos]) || isASCIIDigit(text[pos])) {
+				pos++
+			}
+			tokens = append(tokens, jinjaToken{Kind: jinjaTokenName, Line: line, Value: text[start:pos]})
+		case isASCIIDigit(c):
+			start := pos
+			for pos < len(text) && (isASCIIDigit(text[pos]) || text[pos] == '_' || text[pos] == '.' || text[pos] == 'e' || text[pos] == 'E') {
+				// A dot only continues the number if followed by a digit, e.g.
+				// not in foo.0.bar
+				if text[pos] == '.' && (pos+1 >= len(text) || !isASCIIDigit(text[pos+1]) || strings.Contains(text[start:pos], ".")) {
+					break
+				}
+				pos++
+			}
+			tokens = append(tokens, jinjaToken{Kind: jinjaTokenNumber, Line: line, Value: text[start:pos]})
+		case c == '\'' || c == '"':
+			start, startLine := pos, line
+			var value strings.Builder
+			pos++
+			for pos < len(text) && text[pos] != c {
+				if text[pos] == '\n' {
+					line++
+				}
+				if text[pos] == '\\' && pos+1 < len(text) {
+					pos++
+					switch text[pos] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					default:
+						value.WriteByte(text[pos])
+					}
+					pos++
+					continue
+				}
+				value.WriteByte(text[pos])
+				pos++
+			}
+			if pos >= len(text) {
+				return tokens, pos, fmt.Errorf("line %d: unclosed string %s", startLine, text[start:min(len(text), start+20)])
+			}
+			pos++
+			tokens = append(tokens, jinjaToken{Kind: jinjaTokenString, Line: startLine, Value: value.String()})
+		default:
+			operator := ""
+			for _, op := range jinjaOperators {
+				if strings.HasPrefix(text[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return tokens, pos, fmt.Errorf("line %d: unexpected character %q", line, c)
+			}
+			switch operator {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			pos += len(operator)
+			tokens = append(tokens, jinjaToken{Kind: jinjaTokenOperator, Line: line, Value: operator})
+		}
+	}
+
+	tokens = append(tokens, jinjaToken{Kind: jinjaTokenEOF, Line: line})
+	return tokens, pos, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// jinjaParser is a recursive descent parser following the grammar of the
+// Jinja2 parser, recording what the expressions use as it goes.
+type jinjaParser struct {
+	analysis jinjaAnalysis
+	pos      int
+	// scopes holds the names bound by the template, e.g. loop variables and
+	// macro arguments, which are not references to Ansible variables
+	scopes []map[string]bool
+	tokens []jinjaToken
+}
+
+func newJinjaParser() *jinjaParser {
+	return &jinjaParser{scopes: []map[string]bool{{}}}
+}
+
+func (p *jinjaParser) peek() jinjaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *jinjaParser) peekAt(offset int) jinjaToken {
+	if p.pos+offset >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *jinjaParser) next() jinjaToken {
+	token := p.tokens[p.pos]
+	if token.Kind != jinjaTokenEOF {
+		p.pos++
+	}
+	return token
+}
+
+// isOperator reports whether the next token is the operator.
+func (p *jinjaParser) isOperator(operator string) bool {
+	token := p.peek()
+	return token.Kind == jinjaTokenOperator && token.Value == operator
+}
+
+// isName reports whether the next token is the name, e.g. a keyword.
+func (p *jinjaParser) isName(name string) bool {
+	token := p.peek()
+	return token.Kind == jinjaTokenName && token.Value == name
+}
+
+func (p *jinjaParser) skipOperator(operator string) bool {
+	if p.isOperator(operator) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *jinjaParser) skipName(name string) bool {
+	if p.isName(name) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *jinjaParser) expectOperator(operator string) error {
+	if !p.skipOperator(operator) {
+		return p.unexpected("expected " + operator)
+	}
+	return nil
+}
+
+func (p *jinjaParser) expectName() (jinjaToken, error) {
+	token := p.peek()
+	if token.Kind != jinjaTokenName {
+		return token, p.unexpected("expected a name")
+	}
+	return p.next(), nil
+}
+
+func (p *jinjaParser) expectEnd() error {
+	if p.peek().Kind != jinjaTokenEOF {
+		return p.unexpected("expected the end of the expression")
+	}
+	return nil
+}
+
+func (p *jinjaParser) unexpected(message string) error {
+	token := p.peek()
+	if token.Kind == jinjaTokenEOF {
+		return fmt.Errorf("line %d: unexpected end of expression, %s", token.Line, message)
+	}
+	return fmt.Errorf("line %d: unexpected %q, %s", token.Line, token.Value, message)
+}
+
+// bind adds a name to the innermost scope.
+func (p *jinjaParser) bind(name string) {
+	p.scopes[len(p.scopes)-1][name] = true
+}
+
+func (p *jinjaParser) pushScope(names ...string) {
+	scope := map[string]bool{}
+	for _, name := range names {
+		scope[name] = true
+	}
+	p.scopes = append(p.scopes, scope)
+}
+
+func (p *jinjaParser) popScope() {
+	if len(p.scopes) > 1 {
+		p.scopes = p.scopes[:len(p.scopes)-1]
+	}
+}
+
+func (p *jinjaParser) isBound(name string) bool {
+	for _, scope := range p.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+//// STATEMENTS
+
+// parseStatement parses the content of a {% %} tag.
+func (p *jinjaParser) parseStatement() error {
+	keyword, err := p.expectName()
+	if err != nil {
+		return err
+	}
+
+	switch keyword.Value {
+	case "if", "elif":
+		return p.parseExpression(true)
+	case "for":
+		return p.parseFor()
+	case "set":
+		return p.parseSet()
+	case "macro":
+		return p.parseMacro()
+	case "call":
+		return p.parseCall()
+	case "filter":
+		return p.parseFilterBlock()
+	case "with":
+		return p.parseWith()
+	case "include":
+		return p.parseTemplateRef(&p.analysis.Includes)
+	case "import":
+		if err := p.parseTemplateRef(&p.analysis.Imports); err != nil {
+			return err
+		}
+		if p.skipName("as") {
+			name, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			p.bind(name.Value)
+		}
+		return nil
+	case "from":
+		return p.parseFromImport()
+	case "extends":
+		return p.parseTemplateRef(&p.analysis.Extends)
+	case "do", "autoescape":
+		return p.parseExpression(true)
+	case "endfor", "endmacro", "endcall", "endwith":
+		p.popScope()
+	}
+
+	// The other statements, e.g. else, block or endif, have no expressions
+	return nil
+}
+
+// parseFor parses `for target in iterable [if condition] [recursive]`. The
+// loop variables are bound until the endfor tag.
+func (p *jinjaParser) parseFor() error {
+	var targets []string
+	for {
+		if p.skipOperator("(") {
+			continue
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		targets = append(targets, name.Value)
+		p.skipOperator(")")
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+	if !p.skipName("in") {
+		return p.unexpected("expected in")
+	}
+	if err := p.parseExpression(false); err != nil {
+		return err
+	}
+
+	p.pushScope(append(targets, "loop")...)
+	if p.skipName("if") {
+		if err := p.parseExpression(true); err != nil {
+			return err
+		}
+	}
+	p.skipName("recursive")
+	return p.expectEnd()
+}
+
+// parseSet parses `set target = value` or the block form `set target`.
+func (p *jinjaParser) parseSet() error {
+	var targets []string
+	for {
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if p.isOperator(".") {
+			// Assigning an attribute of a namespace, e.g. ns.found = true
+			if !p.isBound(name.Value) {
+				p.addReference(name)
+			}
+			p.next()
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+		} else {
+			targets = append(targets, name.Value)
+		}
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+
+	if p.skipOperator("=") {
+		if err := p.parseExpression(true); err != nil {
+			return err
+		}
+	} else {
+		// The block form may be filtered, e.g. set x | upper
+		if err := p.parseFilters(len(p.analysis.References)); err != nil {
+			return err
+		}
+	}
+	for _, target := range targets {
+		p.bind(target)
+	}
+	return p.expectEnd()
+}
+
+// parseMacro parses `macro name(arg, arg=default)`. The arguments are bound
+// until the endmacro tag.
+func (p *jinjaParser) parseMacro() error {
+	name, err := p.expectName()
+	if err != nil {
+		return err
+	}
+	p.bind(name.Value)
+	p.analysis.Macros = append(p.analysis.Macros, jinjaUsage{Line: name.Line, Name: name.Value})
+
+	p.pushScope("caller", "kwargs", "varargs")
+	return p.parseSignature()
+}
+
+// parseCall parses `call(args) macro(...)`. The arguments are bound until the
+// endcall tag.
+func (p *jinjaParser) parseCall() error {
+	p.pushScope()
+	if p.isOperator("(") {
+		if err := p.parseSignature(); err != nil {
+			return err
+		}
+	}
+	return p.parseExpression(true)
+}
+
+// parseSignature parses the arguments of a macro, binding their names in the
+// current scope.
+func (p *jinjaParser) parseSignature() error {
+	if err := p.expectOperator("("); err != nil {
+		return err
+	}
+	for !p.isOperator(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return err
+		}
+		if p.skipOperator("=") {
+			if err := p.parseExpression(true); err != nil {
+				return err
+			}
+		}
+		p.bind(name.Value)
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+	return p.expectOperator(")")
+}
+
+// parseFilterBlock parses `filter name(args) | name`.
+func (p *jinjaParser) parseFilterBlock() error {
+	start := len(p.analysis.References)
+	if err := p.parseFilter(start); err != nil {
+		return err
+	}
+	if err := p.parseFilters(start); err != nil {
+		return err
+	}
+	return p.expectEnd()
+}
+
+// parseWith parses `with name = value, ...`. The names are bound until the
+// endwith tag.
+func (p *jinjaParser) parseWith() error {
+	var targets []string
+	for p.peek().Kind == jinjaTokenName {
+		name := p.next()
+		if err := p.expectOperator("="); err != nil {
+			return err
+		}
+		if err := p.parseExpression(true); err != nil {
+			return err
+		}
+		targets = append(targets, name.Value)
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+	p.pushScope(targets...)
+	return p.expectEnd()
+}
+
+// parseTemplateRef parses the template name of an include, import or extends
+// statement, and the optional context and ignore missing modifiers.
+func (p *jinjaParser) parseTemplateRef(refs *[]jinjaUsage) error {
+	token := p.peek()
+	ref := jinjaUsage{Line: token.Line}
+	if token.Kind == jinjaTokenString && (p.peekAt(1).Kind == jinjaTokenEOF || p.peekAt(1).Kind == jinjaTokenName) {
+		ref.Name = token.Value
+	}
+	if err := p.parseExpression(false); err != nil {
+		return err
+	}
+	*refs = append(*refs, ref)
+
+	for _, modifier := range []string{"ignore", "missing", "with", "without", "context"} {
+		p.skipName(modifier)
+	}
+	return nil
+}
+
+// parseFromImport parses `from template import name [as alias], ...`.
+func (p *jinjaParser) parseFromImport() error {
+	if err := p.parseTemplateRef(&p.analysis.Imports); err != nil {
+		return err
+	}
+	if !p.skipName("import") {
+		return p.unexpected("expected import")
+	}
+	for p.peek().Kind == jinjaTokenName {
+		name := p.next()
+		if name.Value == "with" || name.Value == "without" {
+			p.skipName("context")
+			break
+		}
+		if p.skipName("as") {
+			alias, err := p.expectName()
+			if err != nil {
+				return err
+			}
+			name = alias
+		}
+		p.bind(name.Value)
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+	return nil
+}
+
+//// EXPRESSIONS
+
+// parseExpression parses an expression, including the inline if expression
+// unless withCondition is false, e.g. for the iterable of a for loop.
+func (p *jinjaParser) parseExpression(withCondition bool) error {
+	if err := p.parseOr(); err != nil {
+		return err
+	}
+	if withCondition && p.skipName("if") {
+		if err := p.parseOr(); err != nil {
+			return err
+		}
+		if p.skipName("else") {
+			return p.parseExpression(true)
+		}
+	}
+	return nil
+}
+
+func (p *jinjaParser) parseOr() error {
+	if err := p.parseAnd(); err != nil {
+		return err
+	}
+	for p.skipName("or") {
+		if err := p.parseAnd(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *jinjaParser) parseAnd() error {
+	if err := p.parseNot(); err != nil {
+		return err
+	}
+	for p.skipName("and") {
+		if err := p.parseNot(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *jinjaParser) parseNot() error {
+	if p.skipName("not") {
+		return p.parseNot()
+	}
+	return p.parseCompare()
+}
+
+func (p *jinjaParser) parseCompare() error {
+	if err := p.parseMath(); err != nil {
+		return err
+	}
+	for {
+		switch {
+		case p.isOperator("==") || p.isOperator("!=") || p.isOperator("<") || p.isOperator(">") || p.isOperator("<=") || p.isOperator(">="):
+			p.next()
+		case p.isName("in"):
+			p.next()
+		case p.isName("not") && p.peekAt(1).Kind == jinjaTokenName && p.peekAt(1).Value == "in":
+			p.next()
+			p.next()
+		default:
+			return nil
+		}
+		if err := p.parseMath(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseMath parses the binary arithmetic and concatenation operators, whose
+// precedence does not matter here.
+func (p *jinjaParser) parseMath() error {
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+	for {
+		token := p.peek()
+		if token.Kind != jinjaTokenOperator || !strings.Contains(" + - * / // % ** ~ ", " "+token.Value+" ") {
+			return nil
+		}
+		p.next()
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseUnary parses an operand, with its attributes, subscripts and calls,
+// followed by its filters and tests.
+func (p *jinjaParser) parseUnary() error {
+	start := len(p.analysis.References)
+	if p.skipOperator("-") || p.skipOperator("+") {
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+	} else {
+		if err := p.parsePrimary(); err != nil {
+			return err
+		}
+		if err := p.parsePostfix(); err != nil {
+			return err
+		}
+	}
+	return p.parseFilters(start)
+}
+
+// parseFilters parses the filters and tests applied to the operand whose
+// references start at the given index, and records them on the references.
+func (p *jinjaParser) parseFilters(start int) error {
+	for {
+		switch {
+		case p.skipOperator("|"):
+			if err := p.parseFilter(start); err != nil {
+				return err
+			}
+		case p.isName("is"):
+			p.next()
+			p.skipName("not")
+			name, err := p.parseDottedName()
+			if err != nil {
+				return err
+			}
+			p.analysis.Tests = append(p.analysis.Tests, name)
+			for i := start; i < len(p.analysis.References); i++ {
+				p.analysis.References[i].Tests = append(p.analysis.References[i].Tests, name.Name)
+			}
+			// Tests take arguments in parentheses, or a single argument,
+			// e.g. divisibleby 3
+			next := p.peek()
+			switch {
+			case p.isOperator("("):
+				if err := p.parseCallArgs(nil); err != nil {
+					return err
+				}
+			case next.Kind == jinjaTokenString || next.Kind == jinjaTokenNumber || p.isOperator("[") || p.isOperator("{") ||
+				(next.Kind == jinjaTokenName && !jinjaKeywords[next.Value]):
+				if err := p.parsePrimary(); err != nil {
+					return err
+				}
+				if err := p.parsePostfix(); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// parseFilter parses a filter and its arguments, after the pipe.
+func (p *jinjaParser) parseFilter(start int) error {
+	name, err := p.parseDottedName()
+	if err != nil {
+		return err
+	}
+	p.analysis.Filters = append(p.analysis.Filters, name)
+	for i := start; i < len(p.analysis.References); i++ {
+		p.analysis.References[i].Filters = append(p.analysis.References[i].Filters, name.Name)
+	}
+	if p.isOperator("(") {
+		return p.parseCallArgs(nil)
+	}
+	return nil
+}
+
+// parseDottedName parses the name of a filter or test, which may be fully
+// qualified, e.g. community.general.json_query.
+func (p *jinjaParser) parseDottedName() (jinjaUsage, error) {
+	token, err := p.expectName()
+	if err != nil {
+		return jinjaUsage{}, err
+	}
+	name := token.Value
+	for p.isOperator(".") && p.peekAt(1).Kind == jinjaTokenName {
+		p.next()
+		name += "." + p.next().Value
+	}
+	return jinjaUsage{Line: token.Line, Name: name}, nil
+}
+
+// parsePostfix parses the attributes, subscripts and calls of an operand.
+func (p *jinjaParser) parsePostfix() error {
+	for {
+		switch {
+		case p.skipOperator("."):
+			token := p.next()
+			if token.Kind != jinjaTokenName && token.Kind != jinjaTokenNumber {
+				p.pos--
+				return p.unexpected("expected an attribute")
+			}
+		case p.skipOperator("["):
+			if err := p.parseSubscript(); err != nil {
+				return err
+			}
+		case p.isOperator("("):
+			if err := p.parseCallArgs(nil); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// parseSubscript parses an index or a slice, after the opening bracket.
+func (p *jinjaParser) parseSubscript() error {
+	for !p.skipOperator("]") {
+		switch {
+		case p.skipOperator(":"), p.skipOperator(","):
+		default:
+			if err := p.parseExpression(true); err != nil {
+				return err
+			}
+			if !p.isOperator(":") && !p.isOperator(",") && !p.isOperator("]") {
+				return p.unexpected("expected ]")
+			}
+		}
+	}
+	return nil
+}
+
+// parseCallArgs parses the positional and keyword arguments of a call. The
+// first argument is returned through first if it is a literal string.
+func (p *jinjaParser) parseCallArgs(first *string) error {
+	if err := p.expectOperator("("); err != nil {
+		return err
+	}
+	for i := 0; !p.isOperator(")"); i++ {
+		if p.peek().Kind == jinjaTokenEOF {
+			return p.unexpected("expected )")
+		}
+		if i == 0 && first != nil && p.peek().Kind == jinjaTokenString && (p.peekAt(1).Value == "," || p.peekAt(1).Value == ")") {
+			*first = p.peek().Value
+		}
+		// Keyword arguments and argument unpacking
+		if p.peek().Kind == jinjaTokenName && p.peekAt(1).Kind == jinjaTokenOperator && p.peekAt(1).Value == "=" {
+			p.next()
+			p.next()
+		} else if !p.skipOperator("**") {
+			p.skipOperator("*")
+		}
+		if err := p.parseExpression(true); err != nil {
+			return err
+		}
+		if !p.skipOperator(",") {
+			break
+		}
+	}
+	return p.expectOperator(")")
+}
+
+// parsePrimary parses a name, a literal, or a parenthesized expression.
+func (p *jinjaParser) parsePrimary() error {
+	token := p.peek()
+	switch token.Kind {
+	case jinjaTokenName:
+		if jinjaKeywords[token.Value] {
+			return p.unexpected("expected an expression")
+		}
+		p.next()
+		switch {
+		case jinjaConstants[token.Value]:
+		case jinjaLookupFunctions[token.Value] && p.isOperator("("):
+			lookup := jinjaLookup{Function: token.Value, Line: token.Line}
+			if err := p.parseCallArgs(&lookup.Plugin); err != nil {
+				return err
+			}
+			p.analysis.Lookups = append(p.analysis.Lookups, lookup)
+		case jinjaGlobals[token.Value] || p.isBound(token.Value):
+		default:
+			p.addReference(token)
+		}
+		return nil
+	case jinjaTokenNumber:
+		p.next()
+		return nil
+	case jinjaTokenString:
+		// Adjacent strings are concatenated
+		for p.peek().Kind == jinjaTokenString {
+			p.next()
+		}
+		return nil
+	case jinjaTokenOperator:
+		switch token.Value {
+		case "(":
+			p.next()
+			for !p.skipOperator(")") {
+				if err := p.parseExpression(true); err != nil {
+					return err
+				}
+				if !p.skipOperator(",") && !p.isOperator(")") {
+					return p.unexpected("expected )")
+				}
+			}
+			return nil
+		case "[":
+			p.next()
+			for !p.skipOperator("]") {
+				if err := p.parseExpression(true); err != nil {
+					return err
+				}
+				if !p.skipOperator(",") && !p.isOperator("]") {
+					return p.unexpected("expected ]")
+				}
+			}
+			return nil
+		case "{":
+			p.next()
+			for !p.skipOperator("}") {
+				if err := p.parseExpression(true); err != nil {
+					return err
+				}
+				if err := p.expectOperator(":"); err != nil {
+					return err
+				}
+				if err := p.parseExpression(true); err != nil {
+					return err
+				}
+				if !p.skipOperator(",") && !p.isOperator("}") {
+					return p.unexpected("expected }")
+				}
+			}
+			return nil
+		}
+	}
+	return p.unexpected("expected an expression")
+}
+
+func (p *jinjaParser) addReference(token jinjaToken) {
+	p.analysis.References = append(p.analysis.References, jinjaReference{Line: token.Line, Name: token.Value})
+}
@@ -0,0 +1,104 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeJinjaTemplate(t *testing.T) {
+	tests := []struct {
+		name       string
+		template   string
+		references []jinjaReference
+		filters    []string
+		tests      []string
+		includes   []string
+		wantErr    bool
+	}{
+		{
+			name:       "variable",
+			template:   "Hello {{ user }}!",
+			references: []jinjaReference{{Line: 1, Name: "user"}},
+		},
+		{
+			name:       "attribute and filter",
+			template:   "{{ app.port | default(8080) }}",
+			references: []jinjaReference{{Filters: []string{"default"}, Line: 1, Name: "app"}},
+			filters:    []string{"default"},
+		},
+		{
+			name:       "test",
+			template:   "{% if proxy is defined %}{{ proxy }}{% endif %}",
+			references: []jinjaReference{{Line: 1, Name: "proxy", Tests: []string{"defined"}}, {Line: 1, Name: "proxy"}},
+			tests:      []string{"defined"},
+		},
+		{
+			name:       "loop variable",
+			template:   "{% for user in users %}\n{{ user.name }} {{ loop.index }}\n{% endfor %}",
+			references: []jinjaReference{{Line: 1, Name: "users"}},
+		},
+		{
+			name:       "set variable",
+			template:   "{% set port = base_port + 1 %}{{ port }}",
+			references: []jinjaReference{{Line: 1, Name: "base_port"}},
+		},
+		{
+			name:       "include",
+			template:   "{% include 'header.j2' %}\n{{ title }}",
+			references: []jinjaReference{{Line: 2, Name: "title"}},
+			includes:   []string{"header.j2"},
+		},
+		{
+			name:       "string literals and comments",
+			template:   "{# {{ ignored }} #}{{ 'name' ~ \"{{ x }}\" }}",
+			references: nil,
+		},
+		{
+			name:     "invalid expression",
+			template: "{{ user | }}",
+			wantErr:  true,
+		},
+		{
+			name:     "unclosed expression",
+			template: "{{ user ",
+			wantErr:  true,
+		},
+		{
+			name:     "unclosed statement",
+			template: "{% if user ",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			analysis, err := analyzeJinjaTemplate(test.template)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(analysis.References, test.references) {
+				t.Errorf("got references %+v, want %+v", analysis.References, test.references)
+			}
+			if got := jinjaUsageNamesOf(analysis.Filters); !reflect.DeepEqual(got, test.filters) {
+				t.Errorf("got filters %v, want %v", got, test.filters)
+			}
+			if got := jinjaUsageNamesOf(analysis.Tests); !reflect.DeepEqual(got, test.tests) {
+				t.Errorf("got tests %v, want %v", got, test.tests)
+			}
+			if got := jinjaUsageNamesOf(analysis.Includes); !reflect.DeepEqual(got, test.includes) {
+				t.Errorf("got includes %v, want %v", got, test.includes)
+			}
+		})
+	}
+}
+
+func jinjaUsageNamesOf(usages []jinjaUsage) []string {
+	var names []string
+	for _, usage := range usages {
+		names = append(names, usage.Name)
+	}
+	return names
+}
@@ -0,0 +1,168 @@
+package ansible
+
+import "strings"
+
+// Types of the plugins used by Jinja2 expressions
+const (
+	jinjaPluginFilter = "filter"
+	jinjaPluginLookup = "lookup"
+	jinjaPluginTest   = "test"
+)
+
+// How the name of a filter, test or lookup plugin is resolved
+const (
+	// The name is fully qualified, e.g. community.general.json_query
+	jinjaResolutionFQCN = "fqcn"
+	// The short name is a plugin of ansible-core
+	jinjaResolutionBuiltin = "builtin"
+	// The short name is a plugin of Jinja2 itself, which has no collection
+	jinjaResolutionJinja2 = "jinja2"
+	// The short name is routed by ansible-core to a collection, as the plugin
+	// was moved out of Ansible 2.9
+	jinjaResolutionRouted = "routed"
+	// The short name is not known, e.g. a plugin of the playbook directory or
+	// of a role
+	jinjaResolutionUnknown = "unknown"
+)
+
+// ansibleBuiltinFilters are the filters of ansible-core, which take
+// precedence over the Jinja2 filters with the same name.
+var ansibleBuiltinFilters = toSet(
+	"b64decode", "b64encode", "basename", "bool", "checksum", "combinations", "combine", "comment",
+	"dict2items", "difference", "dirname", "expanduser", "expandvars", "extract", "fileglob", "flatten",
+	"from_json", "from_yaml", "from_yaml_all", "hash", "human_readable", "human_to_bytes", "intersect",
+	"items2dict", "log", "mandatory", "max", "md5", "min", "password_hash", "path_join", "permutations",
+	"pow", "product", "quote", "random", "realpath", "regex_escape", "regex_findall", "regex_replace",
+	"regex_search", "rekey_on_member", "relpath", "root", "sha1", "shuffle", "split", "splitext",
+	"strftime", "subelements", "symmetric_difference", "ternary", "to_datetime", "to_json",
+	"to_nice_json", "to_nice_yaml", "to_uuid", "to_yaml", "type_debug", "union", "unique", "unvault",
+	"urldecode", "urlsplit", "vault", "win_basename", "win_dirname", "win_splitdrive", "zip", "zip_longest",
+)
+
+// ansibleBuiltinTests are the tests of ansible-core.
+var ansibleBuiltinTests = toSet(
+	"abs", "all", "any", "change", "changed", "contains", "directory", "exists", "failed", "failure",
+	"falsy", "file", "finished", "is_abs", "is_dir", "is_file", "is_link", "is_mount", "is_same_file",
+	"isnan", "link", "match", "mount", "nan", "reachable", "regex", "same_file", "search", "skip",
+	"skipped", "started", "subset", "succeeded", "success", "successful", "superset", "truthy",
+	"unreachable", "vault_encrypted", "vaulted_file", "version", "version_compare",
+)
+
+// ansibleBuiltinLookups are the lookup plugins of ansible-core.
+var ansibleBuiltinLookups = toSet(
+	"config", "csvfile", "dict", "env", "file", "fileglob", "first_found", "indexed_items", "ini",
+	"inventory_hostnames", "items", "lines", "list", "nested", "password", "pipe", "random_choice",
+	"sequence", "subelements", "template", "together", "unvault", "url", "varnames", "vars",
+)
+
+// jinja2BuiltinFilters are the filters of Jinja2.
+var jinja2BuiltinFilters = toSet(
+	"abs", "attr", "batch", "capitalize", "center", "count", "d", "default", "dictsort", "e", "escape",
+	"filesizeformat", "first", "float", "forceescape", "format", "groupby", "indent", "int", "items",
+	"join", "last", "length", "list", "lower", "map", "max", "min", "pprint", "random", "reject",
+	"rejectattr", "replace", "reverse", "round", "safe", "select", "selectattr", "slice", "sort",
+	"string", "striptags", "sum", "title", "tojson", "trim", "truncate", "unique", "upper", "urlencode",
+	"urlize", "wordcount", "wordwrap", "xmlattr",
+)
+
+// jinja2BuiltinTests are the tests of Jinja2.
+var jinja2BuiltinTests = toSet(
+	"boolean", "callable", "defined", "divisibleby", "eq", "equalto", "escaped", "even", "false",
+	"filter", "float", "ge", "greaterthan", "gt", "in", "integer", "iterable", "le", "lessthan", "lower",
+	"lt", "mapping", "ne", "none", "number", "odd", "sameas", "sequence", "string", "test", "true",
+	"undefined", "upper",
+)
+
+// ansibleRoutedPlugins are the short names that ansible-core still routes to
+// the collections the plugins moved to, by plugin type.
+var ansibleRoutedPlugins = map[string]map[string]string{
+	jinjaPluginFilter: {
+		"hwaddr":                   "ansible.netcommon.hwaddr",
+		"ipaddr":                   "ansible.netcommon.ipaddr",
+		"ipv4":                     "ansible.netcommon.ipv4",
+		"ipv6":                     "ansible.netcommon.ipv6",
+		"ipwrap":                   "ansible.netcommon.ipwrap",
+		"json_query":               "community.general.json_query",
+		"k8s_config_resource_name": "kubernetes.core.k8s_config_resource_name",
+		"macaddr":                  "ansible.netcommon.macaddr",
+		"random_mac":               "community.general.random_mac",
+	},
+	jinjaPluginLookup: {
+		"aws_secret":       "amazon.aws.aws_secret",
+		"aws_ssm":          "amazon.aws.aws_ssm",
+		"cartesian":        "community.general.cartesian",
+		"chef_databag":     "community.general.chef_databag",
+		"consul_kv":        "community.general.consul_kv",
+		"credstash":        "community.general.credstash",
+		"cyberarkpassword": "community.general.cyberarkpassword",
+		"dig":              "community.general.dig",
+		"dnstxt":           "community.general.dnstxt",
+		"etcd":             "community.general.etcd",
+		"filetree":         "community.general.filetree",
+		"flattened":        "community.general.flattened",
+		"hashi_vault":      "community.hashi_vault.hashi_vault",
+		"hiera":            "community.general.hiera",
+		"k8s":              "kubernetes.core.k8s",
+		"keyring":          "community.general.keyring",
+		"lastpass":         "community.general.lastpass",
+		"onepassword":      "community.general.onepassword",
+		"passwordstore":    "community.general.passwordstore",
+		"redis":            "community.general.redis",
+		"shelvefile":       "community.general.shelvefile",
+	},
+}
+
+// jinjaPluginResolution is the plugin a filter, test or lookup name resolves
+// to.
+type jinjaPluginResolution struct {
+	Collection string
+	FQCN       string
+	Resolution string
+}
+
+// resolveJinjaPlugin resolves the name of a filter, test or lookup plugin the
+// way ansible-core does: fully qualified names first, then the plugins of
+// ansible-core, of Jinja2, and the routing of plugins moved to collections.
+func resolveJinjaPlugin(pluginType string, name string) jinjaPluginResolution {
+	if parts := strings.Split(name, "."); len(parts) >= 3 {
+		collection := parts[0] + "." + parts[1]
+		if collection == "ansible.legacy" {
+			collection = "ansible.builtin"
+		}
+		return jinjaPluginResolution{
+			Collection: collection,
+			FQCN:       collection + "." + strings.Join(parts[2:], "."),
+			Resolution: jinjaResolutionFQCN,
+		}
+	}
+
+	var builtin, jinja2 map[string]bool
+	switch pluginType {
+	case jinjaPluginFilter:
+		builtin, jinja2 = ansibleBuiltinFilters, jinja2BuiltinFilters
+	case jinjaPluginTest:
+		builtin, jinja2 = ansibleBuiltinTests, jinja2BuiltinTests
+	case jinjaPluginLookup:
+		builtin = ansibleBuiltinLookups
+	}
+
+	switch {
+	case builtin[name]:
+		return jinjaPluginResolution{Collection: "ansible.builtin", FQCN: "ansible.builtin." + name, Resolution: jinjaResolutionBuiltin}
+	case jinja2[name]:
+		return jinjaPluginResolution{Resolution: jinjaResolutionJinja2}
+	}
+	if fqcn, ok := ansibleRoutedPlugins[pluginType][name]; ok {
+		parts := strings.SplitN(fqcn, ".", 3)
+		return jinjaPluginResolution{Collection: parts[0] + "." + parts[1], FQCN: fqcn, Resolution: jinjaResolutionRouted}
+	}
+	return jinjaPluginResolution{Resolution: jinjaResolutionUnknown}
+}
+
+func toSet(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,139 @@
+package ansible
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Statuses of the test cases of a JUnit report
+const (
+	junitStatusError   = "error"
+	junitStatusFailed  = "failed"
+	junitStatusPassed  = "passed"
+	junitStatusSkipped = "skipped"
+)
+
+// junitTestCaseName matches the name given by the junit callback to the test
+// case of a task on a host, e.g. `[web1] Configure web: nginx : Copy config`.
+var junitTestCaseName = regexp.MustCompile(`^\[([^\]]+)\] (.*?): (.*)$`)
+
+type junitTestSuites struct {
+	Suites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Cases     []junitTestCase `xml:"testcase"`
+	Name      string          `xml:"name,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+}
+
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Errors    []junitResult `xml:"error"`
+	Failures  []junitResult `xml:"failure"`
+	Name      string        `xml:"name,attr"`
+	Skipped   *junitResult  `xml:"skipped"`
+	Time      *float64      `xml:"time,attr"`
+}
+
+// junitResult is a failure, error or skipped element of a test case.
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// ansibleJUnitReport is the content of a report written by the junit callback.
+type ansibleJUnitReport struct {
+	Err       error
+	TestCases []AnsibleJUnitTestCaseInfo
+}
+
+// parseAnsibleJUnitReport parses a report written by the junit callback,
+// whose root is a testsuites element, or a testsuite element for the reports
+// of other tools.
+func parseAnsibleJUnitReport(content []byte, path string) ansibleJUnitReport {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.NewDecoder(bytes.NewReader(content)).Decode(&root); err != nil {
+		return ansibleJUnitReport{Err: err}
+	}
+
+	var suites junitTestSuites
+	switch root.XMLName.Local {
+	case "testsuites":
+		if err := xml.Unmarshal(content, &suites); err != nil {
+			return ansibleJUnitReport{Err: err}
+		}
+	case "testsuite":
+		var suite junitTestSuite
+		if err := xml.Unmarshal(content, &suite); err != nil {
+			return ansibleJUnitReport{Err: err}
+		}
+		suites.Suites = append(suites.Suites, suite)
+	default:
+		return ansibleJUnitReport{Err: fmt.Errorf("unexpected root element %s, expected testsuites or testsuite", root.XMLName.Local)}
+	}
+
+	var report ansibleJUnitReport
+	for _, suite := range suites.Suites {
+		timestamp := parseJUnitTimestamp(suite.Timestamp)
+		for _, testCase := range suite.Cases {
+			item := AnsibleJUnitTestCaseInfo{
+				Classname: testCase.Classname,
+				Name:      testCase.Name,
+				Path:      path,
+				Status:    junitStatusPassed,
+				Testsuite: suite.Name,
+				Time:      testCase.Time,
+				Timestamp: timestamp,
+			}
+
+			if match := junitTestCaseName.FindStringSubmatch(testCase.Name); match != nil {
+				item.Host, item.PlaybookName, item.TaskName = match[1], match[2], match[3]
+			}
+			// The class is the path of the task, with or without its line
+			item.TaskPath, item.TaskLine = splitAnsibleCallbackPath(testCase.Classname)
+			if _, role := ansibleRoleFromPath(item.TaskPath); role != "" {
+				if strings.HasPrefix(item.TaskName, role+" : ") {
+					item.TaskName, item.Role = strings.TrimPrefix(item.TaskName, role+" : "), role
+				}
+			} else if !strings.Contains(item.TaskPath, "/") {
+				item.Role, item.TaskName = splitAnsibleLogTaskName(item.TaskName)
+			}
+
+			switch {
+			case len(testCase.Errors) > 0:
+				item.Status, item.Message, item.Output = junitStatusError, testCase.Errors[0].Message, strings.TrimSpace(testCase.Errors[0].Output)
+			case len(testCase.Failures) > 0:
+				item.Status, item.Message, item.Output = junitStatusFailed, testCase.Failures[0].Message, strings.TrimSpace(testCase.Failures[0].Output)
+			case testCase.Skipped != nil:
+				item.Status, item.Message = junitStatusSkipped, testCase.Skipped.Message
+				if item.Message == "" {
+					item.Message = strings.TrimSpace(testCase.Skipped.Output)
+				}
+			}
+			report.TestCases = append(report.TestCases, item)
+		}
+	}
+
+	return report
+}
+
+// parseJUnitTimestamp parses the timestamp of a test suite. JUnit timestamps
+// have no time zone, and are read as UTC.
+func parseJUnitTimestamp(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package ansible
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Play and task keywords accept several forms, e.g. `serial: 5`,
+// `serial: "30%"` and `serial: [1, 5, "20%"]`, and can all be templated. The
+// keyword types below decode any form, so that an unexpected form does not
+// fail the whole file. Each keeps the value as written in Raw, and Value
+// holds the value converted to the column type, or nil when it is templated
+// or cannot be converted.
+
+// ansibleBoolKeyword is a boolean keyword such as become or gather_facts.
+type ansibleBoolKeyword struct {
+	Raw   interface{}
+	Value *bool
+}
+
+func (k *ansibleBoolKeyword) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&k.Raw); err != nil {
+		return err
+	}
+	k.Value = parseAnsibleBool(k.Raw)
+	return nil
+}
+
+// ansibleIntKeyword is an integer keyword such as timeout. For serial, which
+// is a number of hosts, a percentage of hosts or a list of either for
+// successive batches, Value is only set for a number of hosts.
+type ansibleIntKeyword struct {
+	Raw   interface{}
+	Value *int
+}
+
+func (k *ansibleIntKeyword) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&k.Raw); err != nil {
+		return err
+	}
+	switch v := k.Raw.(type) {
+	case int:
+		k.Value = &v
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			k.Value = &i
+		}
+	}
+	return nil
+}
+
+// ansibleStringKeyword is a string keyword that may also be given as a list,
+// such as the when conditionals or the loop items. Value is only set for a
+// single value.
+type ansibleStringKeyword struct {
+	Raw   interface{}
+	Value *string
+}
+
+func (k *ansibleStringKeyword) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&k.Raw); err != nil {
+		return err
+	}
+	if node.Kind == yaml.ScalarNode && k.Raw != nil {
+		k.Value = &node.Value
+	}
+	return nil
+}
+
+// ansibleTagsKeyword is the tags keyword, which is a list of tags or a comma
+// separated string of tags.
+type ansibleTagsKeyword struct {
+	Raw   interface{}
+	Value []string
+}
+
+func (k *ansibleTagsKeyword) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode(&k.Raw); err != nil {
+		return err
+	}
+	k.Value = parseAnsibleTags(k.Raw)
+	return nil
+}
+
+// parseAnsibleBool converts a value the way Ansible's boolean filter does,
+// e.g. "yes" and 1 are true.
+func parseAnsibleBool(value interface{}) *bool {
+	var b bool
+	switch v := value.(type) {
+	case bool:
+		b = v
+	case int:
+		if v != 0 && v != 1 {
+			return nil
+		}
+		b = v == 1
+	case float64:
+		if v != 0 && v != 1 {
+			return nil
+		}
+		b = v == 1
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "y", "yes", "on", "1", "true", "t":
+			b = true
+		case "n", "no", "off", "0", "false", "f":
+			b = false
+		default:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &b
+}
+
+// parseAnsibleTags returns the list of tags, or nil if any of them is
+// templated since the tags are then only known at runtime.
+func parseAnsibleTags(value interface{}) []string {
+	var items []interface{}
+	switch v := value.(type) {
+	case string:
+		for _, tag := range strings.Split(v, ",") {
+			items = append(items, strings.TrimSpace(tag))
+		}
+	case []interface{}:
+		items = v
+	case int, float64:
+		items = []interface{}{v}
+	default:
+		return nil
+	}
+
+	tags := []string{}
+	for _, item := range items {
+		if item == nil || isTemplated(item) {
+			return nil
+		}
+		switch item.(type) {
+		case string, int, float64, bool:
+		default:
+			// Nested lists and maps are not valid tags
+			return nil
+		}
+		if tag := fmt.Sprint(item); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
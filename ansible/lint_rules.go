@@ -0,0 +1,426 @@
+package ansible
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleLintRule is a check run against every task. Rule IDs match the
+// ansible-lint rules they implement, so existing `# noqa` comments apply.
+type ansibleLintRule struct {
+	Check       func(task *ansibleLintTask) []ansibleLintMatch
+	Description string
+	ID          string
+	Severity    string
+}
+
+// ansibleLintTask is the parsed form of a task that rules are checked against.
+type ansibleLintTask struct {
+	Args       map[string]interface{}
+	Module     string
+	ModuleFQCN string
+	Node       *yaml.Node
+	Raw        map[string]interface{}
+}
+
+// ansibleLintMatch is a rule violation. Node points at the offending key, or
+// is nil to report the task itself.
+type ansibleLintMatch struct {
+	Message string
+	Node    *yaml.Node
+}
+
+// ansibleLintResult is a match of a rule against a task.
+type ansibleLintResult struct {
+	Match ansibleLintMatch
+	Rule  ansibleLintRule
+}
+
+var ansibleLintRules = []ansibleLintRule{
+	{
+		ID:          "name[missing]",
+		Severity:    "low",
+		Description: "All tasks should be named.",
+		Check:       checkLintNameMissing,
+	},
+	{
+		ID:          "fqcn[action-core]",
+		Severity:    "low",
+		Description: "Use the fully qualified collection name for builtin modules.",
+		Check:       checkLintFQCNActionCore,
+	},
+	{
+		ID:          "command-instead-of-module",
+		Severity:    "medium",
+		Description: "Use a module rather than running a command that the module replaces.",
+		Check:       checkLintCommandInsteadOfModule,
+	},
+	{
+		ID:          "no-changed-when",
+		Severity:    "medium",
+		Description: "Commands should not change things if nothing needs doing.",
+		Check:       checkLintNoChangedWhen,
+	},
+	{
+		ID:          "package-latest",
+		Severity:    "medium",
+		Description: "Package installs should not use latest.",
+		Check:       checkLintPackageLatest,
+	},
+	{
+		ID:          "ignore-errors",
+		Severity:    "medium",
+		Description: "Use failed_when and specify error conditions instead of using ignore_errors.",
+		Check:       checkLintIgnoreErrors,
+	},
+	{
+		ID:          "risky-file-permissions",
+		Severity:    "high",
+		Description: "File permissions unset or incorrect.",
+		Check:       checkLintRiskyFilePermissions,
+	},
+	{
+		ID:          "risky-octal",
+		Severity:    "high",
+		Description: "Octal file permissions must contain leading zero or be a string.",
+		Check:       checkLintRiskyOctal,
+	},
+}
+
+// lintAnsibleTask runs every rule against the task, honouring `# noqa`
+// comments and the skip_ansible_lint tag.
+func lintAnsibleTask(task *ansibleLintTask) []ansibleLintResult {
+	var findings []ansibleLintResult
+
+	if hasTag(task.Raw["tags"], "skip_ansible_lint") {
+		return findings
+	}
+	skipAll, skipped := noqaRules(task.Node)
+	if skipAll {
+		return findings
+	}
+
+	for _, rule := range ansibleLintRules {
+		if skipped[rule.ID] || skipped[strings.SplitN(rule.ID, "[", 2)[0]] {
+			continue
+		}
+		for _, match := range rule.Check(task) {
+			findings = append(findings, ansibleLintResult{Rule: rule, Match: match})
+		}
+	}
+
+	return findings
+}
+
+func checkLintNameMissing(task *ansibleLintTask) []ansibleLintMatch {
+	if name, ok := task.Raw["name"].(string); ok && strings.TrimSpace(name) != "" {
+		return nil
+	}
+	return []ansibleLintMatch{{Message: "All tasks should be named."}}
+}
+
+func checkLintFQCNActionCore(task *ansibleLintTask) []ansibleLintMatch {
+	if task.Module == "" || strings.Contains(task.Module, ".") || !ansibleBuiltinModules[task.Module] {
+		return nil
+	}
+	return []ansibleLintMatch{{
+		Message: fmt.Sprintf("Use FQCN for builtin module actions (%s).", task.Module),
+		Node:    mappingKey(task.Node, task.Module),
+	}}
+}
+
+// ansibleCommandModules are the modules running an arbitrary command.
+var ansibleCommandModules = map[string]bool{
+	"command": true,
+	"shell":   true,
+	"raw":     true,
+}
+
+// ansibleCommandReplacements maps executables to the module to use instead.
+// https://ansible.readthedocs.io/projects/lint/rules/command-instead-of-module/
+var ansibleCommandReplacements = map[string]string{
+	"apt-get":    "ansible.builtin.apt",
+	"chkconfig":  "ansible.builtin.service",
+	"curl":       "ansible.builtin.get_url or ansible.builtin.uri",
+	"git":        "ansible.builtin.git",
+	"journalctl": "community.general.journald",
+	"luseradd":   "ansible.builtin.user",
+	"mount":      "ansible.posix.mount",
+	"patch":      "ansible.posix.patch",
+	"rpm":        "ansible.builtin.yum or ansible.builtin.rpm_key",
+	"sed":        "ansible.builtin.template, ansible.builtin.replace or ansible.builtin.lineinfile",
+	"service":    "ansible.builtin.service",
+	"svn":        "ansible.builtin.subversion",
+	"systemctl":  "ansible.builtin.systemd_service",
+	"unzip":      "ansible.builtin.unarchive",
+	"wget":       "ansible.builtin.get_url or ansible.builtin.uri",
+	"yum":        "ansible.builtin.yum",
+}
+
+// ansibleCommandAllowedOptions are the sub commands that no module replaces.
+var ansibleCommandAllowedOptions = map[string][]string{
+	"git":       {"branch", "log", "lfs", "rev-parse"},
+	"rpm":       {"--nodeps"},
+	"systemctl": {"--version", "get-default", "kill", "set-default", "set-property", "show-environment", "status", "reset-failed"},
+	"yum":       {"clean", "history", "info"},
+}
+
+func checkLintCommandInsteadOfModule(task *ansibleLintTask) []ansibleLintMatch {
+	if !ansibleCommandModules[shortModuleName(task.Module)] {
+		return nil
+	}
+
+	words := commandWords(task.Args)
+	if len(words) == 0 {
+		return nil
+	}
+	executable := words[0][strings.LastIndex(words[0], "/")+1:]
+	replacement, ok := ansibleCommandReplacements[executable]
+	if !ok {
+		return nil
+	}
+	if len(words) > 1 {
+		for _, option := range ansibleCommandAllowedOptions[executable] {
+			if words[1] == option {
+				return nil
+			}
+		}
+	}
+
+	return []ansibleLintMatch{{
+		Message: fmt.Sprintf("%s used in place of %s module.", executable, replacement),
+	}}
+}
+
+func checkLintNoChangedWhen(task *ansibleLintTask) []ansibleLintMatch {
+	if !ansibleCommandModules[shortModuleName(task.Module)] {
+		return nil
+	}
+	if _, ok := task.Raw["changed_when"]; ok {
+		return nil
+	}
+	for _, key := range []string{"creates", "removes"} {
+		if _, ok := task.Args[key]; ok {
+			return nil
+		}
+	}
+	return []ansibleLintMatch{{Message: "Commands should not change things if nothing needs doing."}}
+}
+
+// ansiblePackageModules are the package managers whose `state: latest`
+// installs whatever version is current at run time.
+var ansiblePackageModules = map[string]bool{
+	"apk":            true,
+	"apt":            true,
+	"bower":          true,
+	"bundler":        true,
+	"dnf":            true,
+	"dnf5":           true,
+	"easy_install":   true,
+	"gem":            true,
+	"homebrew":       true,
+	"jenkins_plugin": true,
+	"npm":            true,
+	"openbsd_pkg":    true,
+	"package":        true,
+	"pacman":         true,
+	"pip":            true,
+	"pkg5":           true,
+	"pkgng":          true,
+	"pkgutil":        true,
+	"portage":        true,
+	"slackpkg":       true,
+	"snap":           true,
+	"swdepot":        true,
+	"win_chocolatey": true,
+	"yarn":           true,
+	"yum":            true,
+	"zypper":         true,
+}
+
+func checkLintPackageLatest(task *ansibleLintTask) []ansibleLintMatch {
+	if !ansiblePackageModules[shortModuleName(task.Module)] || task.Args["state"] != "latest" {
+		return nil
+	}
+	// Upgrading already installed packages only is an explicit choice
+	for _, key := range []string{"update_only", "only_upgrade"} {
+		if isTruthy(task.Args[key]) {
+			return nil
+		}
+	}
+	return []ansibleLintMatch{{Message: "Package installs should not use latest."}}
+}
+
+func checkLintIgnoreErrors(task *ansibleLintTask) []ansibleLintMatch {
+	value, ok := task.Raw["ignore_errors"]
+	if !ok {
+		return nil
+	}
+	// Ignoring errors in check mode is a common and safe idiom, as is
+	// registering the result to handle the failure in a later task
+	if s, ok := value.(string); ok && strings.Contains(s, "ansible_check_mode") {
+		return nil
+	}
+	if _, ok := task.Raw["register"]; ok || !isTruthy(value) {
+		return nil
+	}
+	return []ansibleLintMatch{{
+		Message: "Use failed_when and specify error conditions instead of using ignore_errors.",
+		Node:    mappingKey(task.Node, "ignore_errors"),
+	}}
+}
+
+// ansibleFileCreatingModules are the modules that may create a file, mapped to
+// the argument that must be set for them to do so, if any.
+var ansibleFileCreatingModules = map[string]string{
+	"archive":     "",
+	"assemble":    "",
+	"blockinfile": "create",
+	"copy":        "",
+	"file":        "",
+	"get_url":     "",
+	"ini_file":    "create",
+	"lineinfile":  "create",
+	"replace":     "",
+	"template":    "",
+	"unarchive":   "",
+}
+
+func checkLintRiskyFilePermissions(task *ansibleLintTask) []ansibleLintMatch {
+	module := shortModuleName(task.Module)
+	createArg, ok := ansibleFileCreatingModules[module]
+	if !ok || task.Args["mode"] != nil {
+		return nil
+	}
+	if createArg != "" && !isTruthy(task.Args[createArg]) {
+		return nil
+	}
+	// The file module only creates files with these states
+	if module == "file" {
+		state, _ := task.Args["state"].(string)
+		if state != "touch" && state != "directory" {
+			return nil
+		}
+	}
+	// Copied files keep the permissions of their source
+	if module == "copy" && isTruthy(task.Args["remote_src"]) {
+		return nil
+	}
+	return []ansibleLintMatch{{Message: "File permissions unset or incorrect."}}
+}
+
+var octalModeRegex = regexp.MustCompile(`^[0-7]{3}$`)
+
+func checkLintRiskyOctal(task *ansibleLintTask) []ansibleLintMatch {
+	if _, ok := ansibleFileCreatingModules[shortModuleName(task.Module)]; !ok {
+		return nil
+	}
+	mode := findModeNode(task.Node, task.Module)
+	// A YAML integer without a leading zero is decimal, so `mode: 644`
+	// results in permissions 1204
+	if mode == nil || mode.Kind != yaml.ScalarNode || mode.Tag != "!!int" || !octalModeRegex.MatchString(mode.Value) {
+		return nil
+	}
+	return []ansibleLintMatch{{
+		Message: fmt.Sprintf("Octal file permissions must contain leading zero or be a string (mode: %s).", mode.Value),
+		Node:    mode,
+	}}
+}
+
+// findModeNode returns the node of the mode argument, when the module
+// arguments are given as a mapping.
+func findModeNode(taskNode *yaml.Node, module string) *yaml.Node {
+	if args := mappingValue(taskNode, module); args != nil && args.Kind == yaml.MappingNode {
+		if mode := mappingValue(args, "mode"); mode != nil {
+			return mode
+		}
+	}
+	return mappingValue(mappingValue(taskNode, "args"), "mode")
+}
+
+// commandWords returns the words of the command run by a command-like module.
+func commandWords(args map[string]interface{}) []string {
+	if argv, ok := args["argv"].([]interface{}); ok {
+		var words []string
+		for _, a := range argv {
+			words = append(words, fmt.Sprint(a))
+		}
+		return words
+	}
+	for _, key := range []string{"cmd", "_raw_params"} {
+		if s, ok := args[key].(string); ok {
+			return strings.Fields(s)
+		}
+	}
+	return nil
+}
+
+// noqaRules returns the rules skipped through `# noqa` comments anywhere in
+// the task, or skipAll for a bare `# noqa`.
+func noqaRules(node *yaml.Node) (bool, map[string]bool) {
+	skipped := map[string]bool{}
+	skipAll := false
+
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		for _, comment := range []string{n.HeadComment, n.LineComment, n.FootComment} {
+			for _, line := range strings.Split(comment, "\n") {
+				i := strings.Index(line, "noqa")
+				if i < 0 {
+					continue
+				}
+				rules := strings.Fields(strings.TrimLeft(line[i+len("noqa"):], ": "))
+				if len(rules) == 0 {
+					skipAll = true
+				}
+				for _, rule := range rules {
+					skipped[rule] = true
+				}
+			}
+		}
+		for _, child := range n.Content {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return skipAll, skipped
+}
+
+func hasTag(tags interface{}, tag string) bool {
+	switch v := tags.(type) {
+	case string:
+		for _, t := range strings.Split(v, ",") {
+			if strings.TrimSpace(t) == tag {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, t := range v {
+			if fmt.Sprint(t) == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isTruthy reports whether a keyword or argument value is true, accepting the
+// boolean strings Ansible accepts.
+func isTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v == 1
+	case string:
+		switch strings.ToLower(v) {
+		case "yes", "true", "on", "y", "t", "1":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of the events of an Ansible log file
+const (
+	logEventHandler = "handler"
+	logEventMessage = "message"
+	logEventPlay    = "play"
+	logEventRecap   = "recap"
+	logEventResult  = "result"
+	logEventTask    = "task"
+	logEventWarning = "warning"
+)
+
+// ansibleLogLine matches the prefix of the lines written by Ansible to its
+// log_path, e.g. `2024-01-02 10:00:00,123 p=1234 u=deploy n=ansible | `. The
+// logger name was added in ansible-core 2.10.
+var ansibleLogLine = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2},\d{3}) p=(\d+) u=(\S*)(?: n=\S+)? \| ?(.*)$`)
+
+// ansibleLogHeader matches the banners of plays and tasks, e.g.
+// `TASK [nginx : Copy config] ****`.
+var ansibleLogHeader = regexp.MustCompile(`^(PLAY|TASK|RUNNING HANDLER) \[(.*)\] \**$`)
+
+// ansibleLogResult matches the result of a task on a host, e.g.
+// `changed: [web1] => (item=foo)` or `fatal: [web2]: FAILED! => {...}`.
+var ansibleLogResult = regexp.MustCompile(`^(ok|changed|failed|fatal|skipping|rescued|ignored): \[([^\]]+)\](.*)$`)
+
+// ansibleLogRecap matches a host of the play recap.
+var ansibleLogRecap = regexp.MustCompile(`^(\S+)\s+: ok=(\d+)\s+changed=(\d+)\s+unreachable=(\d+)\s+failed=(\d+)(?:\s+skipped=(\d+))?(?:\s+rescued=(\d+))?(?:\s+ignored=(\d+))?`)
+
+// ansibleLogContent is the content of a log file.
+type ansibleLogContent struct {
+	Err    error
+	Events []AnsibleLogEventInfo
+	Recaps []AnsibleLogRecapInfo
+}
+
+// ansibleLogRun is the state of a run writing to the log. Runs are told apart
+// by their process ID, as concurrent runs may share the log file.
+type ansibleLogRun struct {
+	inRecap      bool
+	playbookName string
+	role         string
+	taskName     string
+}
+
+// parseAnsibleLog parses a log file written by Ansible. Lines without the
+// prefix of the log, e.g. the continuation of a multi-line result, are added
+// to the previous event.
+func parseAnsibleLog(content []byte, path string) ansibleLogContent {
+	var parsed ansibleLogContent
+	runs := map[int]*ansibleLogRun{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		match := ansibleLogLine.FindStringSubmatch(text)
+		if match == nil {
+			if n := len(parsed.Events); n > 0 {
+				event := &parsed.Events[n-1]
+				event.Message += "\n" + text
+				if event.Detail != "" {
+					event.Detail += "\n" + text
+				}
+			}
+			continue
+		}
+
+		timestamp, _ := time.ParseInLocation("2006-01-02 15:04:05,000", match[1], time.Local)
+		pid, _ := strconv.Atoi(match[2])
+		run, ok := runs[pid]
+		if !ok {
+			run = &ansibleLogRun{}
+			runs[pid] = run
+		}
+
+		message := match[4]
+		// The hosts of the event, if several, e.g. for included files
+		var hosts []string
+		event := AnsibleLogEventInfo{
+			EventType: logEventMessage,
+			Line:      line,
+			Message:   message,
+			Path:      path,
+			PID:       pid,
+			Timestamp: timestamp,
+			User:      match[3],
+		}
+
+		switch {
+		case strings.HasPrefix(message, "PLAY RECAP"):
+			event.EventType = logEventRecap
+			run.inRecap = true
+		case ansibleLogHeader.MatchString(message):
+			header := ansibleLogHeader.FindStringSubmatch(message)
+			run.inRecap = false
+			switch header[1] {
+			case "PLAY":
+				event.EventType = logEventPlay
+				run.playbookName, run.role, run.taskName = header[2], "", ""
+			case "TASK":
+				event.EventType = logEventTask
+				run.role, run.taskName = splitAnsibleLogTaskName(header[2])
+			default:
+				event.EventType = logEventHandler
+				run.role, run.taskName = splitAnsibleLogTaskName(header[2])
+			}
+		case run.inRecap && ansibleLogRecap.MatchString(message):
+			recap := ansibleLogRecap.FindStringSubmatch(message)
+			event.EventType = logEventRecap
+			event.Host = recap[1]
+			counts := make([]int, 7)
+			for i := range counts {
+				counts[i], _ = strconv.Atoi(recap[i+2])
+			}
+			parsed.Recaps = append(parsed.Recaps, AnsibleLogRecapInfo{
+				Changed:      counts[1],
+				Failed:       counts[3],
+				Host:         recap[1],
+				Ignored:      counts[6],
+				Line:         line,
+				Ok:           counts[0],
+				Path:         path,
+				PID:          pid,
+				PlaybookName: run.playbookName,
+				Rescued:      counts[5],
+				Skipped:      counts[4],
+				Timestamp:    timestamp,
+				Unreachable:  counts[2],
+				User:         match[3],
+			})
+		case ansibleLogResult.MatchString(message):
+			result := ansibleLogResult.FindStringSubmatch(message)
+			event.EventType = logEventResult
+			event.Status = result[1]
+			// Delegated tasks name both hosts, e.g. [web1 -> localhost]
+			event.Host = strings.SplitN(result[2], " -> ", 2)[0]
+			rest := result[3]
+			if strings.HasPrefix(rest, ": UNREACHABLE!") {
+				event.Status = "unreachable"
+			}
+			if i := strings.Index(rest, "=> "); i >= 0 {
+				event.Detail = rest[i+3:]
+				rest = rest[:i]
+			}
+			if strings.HasPrefix(event.Detail, "(item=") && strings.HasSuffix(event.Detail, ")") {
+				event.Item = event.Detail[6 : len(event.Detail)-1]
+				event.Detail = ""
+			} else if i := strings.Index(rest, "(item="); i >= 0 && strings.HasSuffix(strings.TrimSpace(rest), ")") {
+				event.Item = strings.TrimSuffix(strings.TrimSpace(rest[i+6:]), ")")
+			}
+		case strings.HasPrefix(message, "included: "):
+			event.EventType = logEventResult
+			event.Status = "included"
+			if i := strings.LastIndex(message, " for "); i >= 0 {
+				event.Detail = strings.TrimPrefix(message[:i], "included: ")
+				hosts = strings.Split(message[i+5:], ", ")
+			}
+		case strings.HasPrefix(message, "[WARNING]") || strings.HasPrefix(message, "[DEPRECATION WARNING]"):
+			event.EventType = logEventWarning
+		}
+
+		if event.EventType != logEventMessage && event.EventType != logEventWarning {
+			event.PlaybookName = run.playbookName
+			if event.EventType != logEventPlay && event.EventType != logEventRecap {
+				event.Role, event.TaskName = run.role, run.taskName
+			}
+		}
+		if len(hosts) == 0 {
+			parsed.Events = append(parsed.Events, event)
+		}
+		for _, host := range hosts {
+			event.Host = host
+			parsed.Events = append(parsed.Events, event)
+		}
+	}
+	parsed.Err = scanner.Err()
+
+	return parsed
+}
+
+// splitAnsibleLogTaskName splits the name of a task from its banner into the
+// role and the name, e.g. `nginx : Copy config`.
+func splitAnsibleLogTaskName(name string) (string, string) {
+	if i := strings.Index(name, " : "); i > 0 && !strings.Contains(name[:i], " ") {
+		return name[:i], name[i+3:]
+	}
+	return "", name
+}
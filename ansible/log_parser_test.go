@@ -0,0 +1,96 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnsibleLog(t *testing.T) {
+	content := `2024-01-02 10:00:00,001 p=100 u=deploy n=ansible | PLAY [Web servers] *************************************************************
+2024-01-02 10:00:01,002 p=100 u=deploy n=ansible | TASK [nginx : Install nginx] ***************************************************
+2024-01-02 10:00:02,003 p=100 u=deploy n=ansible | changed: [web1]
+2024-01-02 10:00:02,004 p=100 u=deploy n=ansible | ok: [web2] => (item=nginx)
+2024-01-02 10:00:03,005 p=100 u=deploy n=ansible | fatal: [web3]: FAILED! => {"changed": false,
+  "msg": "No package matching 'nginx'"}
+2024-01-02 10:00:03,006 p=100 u=deploy n=ansible | fatal: [web4]: UNREACHABLE! => {"changed": false, "unreachable": true}
+2024-01-02 10:00:04,007 p=100 u=deploy n=ansible | included: /src/tasks/extra.yml for web1, web2
+2024-01-02 10:00:05,008 p=100 u=deploy n=ansible | RUNNING HANDLER [nginx : Restart nginx] ****************************************
+2024-01-02 10:00:05,009 p=100 u=deploy n=ansible | changed: [web1 -> localhost]
+2024-01-02 10:00:06,010 p=100 u=deploy n=ansible | [WARNING]: Could not match supplied host pattern
+2024-01-02 10:00:07,011 p=100 u=deploy n=ansible | PLAY RECAP *********************************************************************
+2024-01-02 10:00:07,012 p=100 u=deploy n=ansible | web1                       : ok=3    changed=2    unreachable=0    failed=0    skipped=1    rescued=0    ignored=0
+2024-01-02 10:00:07,013 p=100 u=deploy n=ansible | web3                       : ok=0    changed=0    unreachable=0    failed=1    skipped=0    rescued=0    ignored=0
+`
+
+	type event struct {
+		EventType, Status, Host, Item, Role, TaskName, PlaybookName string
+		Line                                                        int
+	}
+	want := []event{
+		{EventType: logEventPlay, PlaybookName: "Web servers", Line: 1},
+		{EventType: logEventTask, Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 2},
+		{EventType: logEventResult, Status: "changed", Host: "web1", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 3},
+		{EventType: logEventResult, Status: "ok", Host: "web2", Item: "nginx", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 4},
+		{EventType: logEventResult, Status: "fatal", Host: "web3", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 5},
+		{EventType: logEventResult, Status: "unreachable", Host: "web4", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 7},
+		{EventType: logEventResult, Status: "included", Host: "web1", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 8},
+		{EventType: logEventResult, Status: "included", Host: "web2", Role: "nginx", TaskName: "Install nginx", PlaybookName: "Web servers", Line: 8},
+		{EventType: logEventHandler, Role: "nginx", TaskName: "Restart nginx", PlaybookName: "Web servers", Line: 9},
+		{EventType: logEventResult, Status: "changed", Host: "web1", Role: "nginx", TaskName: "Restart nginx", PlaybookName: "Web servers", Line: 10},
+		{EventType: logEventWarning, Line: 11},
+		{EventType: logEventRecap, PlaybookName: "Web servers", Line: 12},
+		{EventType: logEventRecap, Host: "web1", PlaybookName: "Web servers", Line: 13},
+		{EventType: logEventRecap, Host: "web3", PlaybookName: "Web servers", Line: 14},
+	}
+
+	parsed := parseAnsibleLog([]byte(content), "/var/log/ansible.log")
+	if parsed.Err != nil {
+		t.Fatal(parsed.Err)
+	}
+
+	var got []event
+	for _, e := range parsed.Events {
+		got = append(got, event{EventType: e.EventType, Status: e.Status, Host: e.Host, Item: e.Item, Role: e.Role, TaskName: e.TaskName, PlaybookName: e.PlaybookName, Line: e.Line})
+		if e.PID != 100 || e.User != "deploy" || e.Path != "/var/log/ansible.log" {
+			t.Errorf("line %d: got pid %d, user %s and path %s", e.Line, e.PID, e.User, e.Path)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got events\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The continuation line of the failed result is part of its detail
+	if detail := parsed.Events[4].Detail; detail != "{\"changed\": false,\n  \"msg\": \"No package matching 'nginx'\"}" {
+		t.Errorf("got detail %q", detail)
+	}
+
+	wantRecaps := []AnsibleLogRecapInfo{
+		{Changed: 2, Host: "web1", Line: 13, Ok: 3, Skipped: 1},
+		{Failed: 1, Host: "web3", Line: 14},
+	}
+	if len(parsed.Recaps) != len(wantRecaps) {
+		t.Fatalf("got %d recaps, want %d", len(parsed.Recaps), len(wantRecaps))
+	}
+	for i, recap := range parsed.Recaps {
+		w := wantRecaps[i]
+		if recap.Host != w.Host || recap.Line != w.Line || recap.Ok != w.Ok || recap.Changed != w.Changed || recap.Failed != w.Failed || recap.Skipped != w.Skipped || recap.PlaybookName != "Web servers" {
+			t.Errorf("got recap %+v, want %+v", recap, w)
+		}
+	}
+}
+
+func TestSplitAnsibleLogTaskName(t *testing.T) {
+	tests := []struct {
+		banner, role, name string
+	}{
+		{banner: "nginx : Copy config", role: "nginx", name: "Copy config"},
+		{banner: "Copy config", name: "Copy config"},
+		{banner: "Check a : b", name: "Check a : b"},
+	}
+	for _, test := range tests {
+		role, name := splitAnsibleLogTaskName(test.banner)
+		if role != test.role || name != test.name {
+			t.Errorf("%q: got %q and %q, want %q and %q", test.banner, role, name, test.role, test.name)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleModuleSchema is the argument spec of a module, used to validate the
+// arguments passed by tasks.
+type ansibleModuleSchema struct {
+	// FreeForm modules take their main argument as a free-form string, kept
+	// in the `_raw_params` argument
+	FreeForm bool
+	Options  map[string]ansibleModuleOption
+	// Partial is set if the options of some doc fragments extended by the
+	// module could not be read, in which case unknown options are not
+	// reported
+	Partial bool
+}
+
+type ansibleModuleOption struct {
+	Aliases  []string
+	Choices  []string
+	Elements string
+	Required bool
+	Type     string
+}
+
+// ansibleModuleSchemaIndex maps a fully qualified module name to its schema,
+// and redirected names to their target.
+type ansibleModuleSchemaIndex struct {
+	Redirects map[string]string
+	Schemas   map[string]*ansibleModuleSchema
+}
+
+type ansibleValidationError struct {
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Option  string `json:"option,omitempty"`
+}
+
+// getAnsibleModuleSchemaIndex builds the schema index from the documentation
+// of every installed collection. It is memoized since reading every module of
+// every collection is expensive and the result is shared by all tasks.
+var getAnsibleModuleSchemaIndex = plugin.HydrateFunc(getAnsibleModuleSchemaIndexUncached).Memoize()
+
+func getAnsibleModuleSchemaIndexUncached(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	index := &ansibleModuleSchemaIndex{
+		Redirects: map[string]string{},
+		Schemas:   map[string]*ansibleModuleSchema{},
+	}
+
+	dirs, err := listAnsibleCollectionDirs(GetConfig(d.Connection))
+	if err != nil {
+		return nil, err
+	}
+
+	// Modules may extend the doc fragments of any collection
+	fragments := newAnsibleDocFragmentIndex()
+	for _, dir := range dirs {
+		if err := fragments.addCollection(dir); err != nil {
+			plugin.Logger(ctx).Warn("getAnsibleModuleSchemaIndex", "read_doc_fragments_error", err, "path", dir)
+		}
+	}
+
+	for _, dir := range dirs {
+		modules, err := readAnsibleCollectionModules(ctx, dir)
+		if err != nil {
+			// Validation is best effort, so a broken collection only means
+			// its modules are not validated
+			plugin.Logger(ctx).Warn("getAnsibleModuleSchemaIndex", "read_collection_error", err, "path", dir)
+			continue
+		}
+		for _, module := range modules {
+			if module.Redirect != "" {
+				index.Redirects[module.FQCN] = module.Redirect
+			}
+			// Only modules with a DOCUMENTATION block have a known schema
+			if module.Options != nil || module.ShortDescription != "" {
+				schema := newAnsibleModuleSchemaFromDoc(module.Options)
+				fragments.extend(ctx, schema, module.DocFragments)
+				index.Schemas[module.FQCN] = schema
+			}
+		}
+	}
+
+	return index, nil
+}
+
+// lookup returns the schema of the module, following redirects. Installed
+// collection docs take precedence over the bundled ansible.builtin snapshot.
+func (index *ansibleModuleSchemaIndex) lookup(fqcn string) *ansibleModuleSchema {
+	if strings.HasPrefix(fqcn, "ansible.legacy.") {
+		fqcn = "ansible.builtin." + strings.TrimPrefix(fqcn, "ansible.legacy.")
+	}
+
+	// Guard against redirect loops
+	for i := 0; i < 10; i++ {
+		if schema, ok := index.Schemas[fqcn]; ok {
+			return schema
+		}
+		if schema, ok := ansibleBuiltinModuleSchemas[fqcn]; ok {
+			return schema
+		}
+		target, ok := index.Redirects[fqcn]
+		if !ok {
+			return nil
+		}
+		fqcn = target
+	}
+
+	return nil
+}
+
+// newAnsibleModuleSchemaFromDoc converts the `options` of a module's
+// DOCUMENTATION block. The `free_form` pseudo option documents the free-form
+// argument of modules such as command and shell.
+func newAnsibleModuleSchemaFromDoc(options map[string]interface{}) *ansibleModuleSchema {
+	schema := &ansibleModuleSchema{Options: map[string]ansibleModuleOption{}}
+
+	for name, raw := range options {
+		if name == "free_form" {
+			schema.FreeForm = true
+			continue
+		}
+
+		doc, _ := raw.(map[string]interface{})
+		option := ansibleModuleOption{Type: "str"}
+		if t, ok := doc["type"].(string); ok {
+			option.Type = t
+		}
+		if e, ok := doc["elements"].(string); ok {
+			option.Elements = e
+		}
+		if r, ok := doc["required"].(bool); ok {
+			option.Required = r
+		}
+		if aliases, ok := doc["aliases"].([]interface{}); ok {
+			for _, a := range aliases {
+				option.Aliases = append(option.Aliases, fmt.Sprint(a))
+			}
+		}
+		if choices, ok := doc["choices"].([]interface{}); ok {
+			for _, c := range choices {
+				option.Choices = append(option.Choices, fmt.Sprint(c))
+			}
+		}
+		schema.Options[name] = option
+	}
+
+	return schema
+}
+
+// ansibleDocFragmentIndex maps the fully qualified names of the doc fragments
+// of the installed collections, e.g. community.docker.docker, to their files,
+// and memoizes the options read from them.
+type ansibleDocFragmentIndex struct {
+	Files   map[string]string
+	Options map[string]map[string]ansibleModuleOption
+}
+
+func newAnsibleDocFragmentIndex() *ansibleDocFragmentIndex {
+	index := &ansibleDocFragmentIndex{Files: map[string]string{}, Options: map[string]map[string]ansibleModuleOption{}}
+	for name, options := range ansibleBuiltinDocFragments {
+		index.Options[name] = options
+	}
+	return index
+}
+
+// addCollection adds the doc fragments of the plugins/doc_fragments directory
+// of a collection.
+func (index *ansibleDocFragmentIndex) addCollection(dir string) error {
+	collection, err := readAnsibleCollection(dir)
+	if err != nil {
+		return err
+	}
+	files, err := listAnsibleCollectionPluginFiles(dir, collection.FQCN, "doc_fragments")
+	if err != nil {
+		return err
+	}
+	for name, path := range files {
+		if _, exists := index.Files[name]; !exists {
+			index.Files[name] = path
+		}
+	}
+	return nil
+}
+
+// extend adds the options of the doc fragments extended by a module to its
+// schema, the module's own options taking precedence. The schema is partial
+// if a fragment cannot be resolved.
+func (index *ansibleDocFragmentIndex) extend(ctx context.Context, schema *ansibleModuleSchema, fragments []string) {
+	for _, fragment := range fragments {
+		options, ok := index.lookup(ctx, fragment)
+		if !ok {
+			plugin.Logger(ctx).Debug("ansibleDocFragmentIndex.extend", "unresolved_fragment", fragment)
+			schema.Partial = true
+			continue
+		}
+		for name, option := range options {
+			if _, exists := schema.Options[name]; !exists {
+				schema.Options[name] = option
+			}
+		}
+	}
+}
+
+// lookup returns the options of a doc fragment. Like Ansible, a name is
+// first looked up as a fragment file, e.g. community.docker.docker for its
+// DOCUMENTATION attribute, then as a file followed by an attribute, e.g.
+// amazon.aws.common.modules for the MODULES attribute of amazon.aws.common.
+// Names without a collection are those of ansible-core.
+func (index *ansibleDocFragmentIndex) lookup(ctx context.Context, fragment string) (map[string]ansibleModuleOption, bool) {
+	name := strings.ToLower(fragment)
+	if !strings.Contains(name, ".") || !strings.Contains(strings.SplitN(name, ".", 2)[1], ".") {
+		name = "ansible.builtin." + name
+	}
+	if options, ok := index.Options[name]; ok {
+		return options, options != nil
+	}
+
+	var options map[string]ansibleModuleOption
+	if path, ok := index.Files[name]; ok {
+		options = readAnsibleDocFragmentOptions(ctx, path, "DOCUMENTATION")
+	} else if i := strings.LastIndex(name, "."); i > 0 {
+		if path, ok := index.Files[name[:i]]; ok {
+			options = readAnsibleDocFragmentOptions(ctx, path, strings.ToUpper(name[i+1:]))
+		}
+	}
+	index.Options[name] = options
+
+	return options, options != nil
+}
+
+// readAnsibleDocFragmentOptions returns the options documented by an
+// attribute of the ModuleDocFragment class of a doc fragment file, or nil if
+// they cannot be read.
+func readAnsibleDocFragmentOptions(ctx context.Context, path string, attribute string) map[string]ansibleModuleOption {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		plugin.Logger(ctx).Warn("readAnsibleDocFragmentOptions", "read_file_error", err, "path", path)
+		return nil
+	}
+	block := extractPythonStringAssignment(string(content), attribute, true)
+	if block == "" {
+		return nil
+	}
+
+	var doc struct {
+		Options map[string]interface{} `yaml:"options"`
+	}
+	if err := yaml.Unmarshal([]byte(block), &doc); err != nil {
+		plugin.Logger(ctx).Warn("readAnsibleDocFragmentOptions", "documentation_error", err, "path", path, "attribute", attribute)
+		return nil
+	}
+
+	// A fragment may only document other keys, e.g. notes or requirements,
+	// in which case it has no options
+	return newAnsibleModuleSchemaFromDoc(doc.Options).Options
+}
+
+// stringOrStringList converts a YAML value that is a string or a list of
+// strings to a list.
+func stringOrStringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// validateModuleArgs checks task arguments against the module schema for
+// unknown options, missing required options, wrong types and values outside
+// the documented choices. Templated values cannot be checked before runtime
+// and are skipped.
+func validateModuleArgs(schema *ansibleModuleSchema, args map[string]interface{}) []ansibleValidationError {
+	errors := []ansibleValidationError{}
+
+	// Resolve aliases to the option they belong to
+	canonical := map[string]string{}
+	for name, option := range schema.Options {
+		canonical[name] = name
+		for _, alias := range option.Aliases {
+			canonical[alias] = name
+		}
+	}
+
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	provided := map[string]bool{}
+	for _, key := range keys {
+		value := args[key]
+
+		if key == "_raw_params" {
+			if !schema.FreeForm && !isTemplated(value) {
+				errors = append(errors, ansibleValidationError{
+					Kind:    "unsupported_free_form",
+					Message: fmt.Sprintf("module does not take free-form arguments, got: %v", value),
+				})
+			}
+			continue
+		}
+
+		name, ok := canonical[key]
+		if !ok {
+			if schema.Partial {
+				continue
+			}
+			errors = append(errors, ansibleValidationError{
+				Kind:    "unknown_option",
+				Message: fmt.Sprintf("unsupported parameter: %s", key),
+				Option:  key,
+			})
+			continue
+		}
+		provided[name] = true
+
+		if value == nil || isTemplated(value) {
+			continue
+		}
+		option := schema.Options[name]
+		if !isValidOptionType(option.Type, value) {
+			errors = append(errors, ansibleValidationError{
+				Kind:    "invalid_type",
+				Message: fmt.Sprintf("value of %s must be of type %s, got: %v", key, option.Type, value),
+				Option:  key,
+			})
+			continue
+		}
+		if len(option.Choices) > 0 {
+			values := []interface{}{value}
+			if list, ok := value.([]interface{}); ok {
+				values = list
+			}
+			for _, v := range values {
+				if !isTemplated(v) && !isValidOptionChoice(option.Choices, v) {
+					errors = append(errors, ansibleValidationError{
+						Kind:    "invalid_choice",
+						Message: fmt.Sprintf("value of %s must be one of: %s, got: %v", key, strings.Join(option.Choices, ", "), v),
+						Option:  key,
+					})
+				}
+			}
+		}
+	}
+
+	required := []string{}
+	for name, option := range schema.Options {
+		if option.Required && !provided[name] {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+	for _, name := range required {
+		errors = append(errors, ansibleValidationError{
+			Kind:    "missing_required",
+			Message: fmt.Sprintf("missing required argument: %s", name),
+			Option:  name,
+		})
+	}
+
+	return errors
+}
+
+func isTemplated(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && (strings.Contains(s, "{{") || strings.Contains(s, "{%"))
+}
+
+// isValidOptionType mirrors the lenient conversions Ansible applies to module
+// arguments, e.g. "yes" is a valid bool and a comma separated string is a
+// valid list.
+func isValidOptionType(optionType string, value interface{}) bool {
+	_, isMap := value.(map[string]interface{})
+	_, isList := value.([]interface{})
+
+	switch optionType {
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return true
+		case int:
+			return v == 0 || v == 1
+		case string:
+			switch strings.ToLower(v) {
+			case "yes", "no", "true", "false", "on", "off", "y", "n", "t", "f", "1", "0":
+				return true
+			}
+		}
+		return false
+	case "int":
+		switch v := value.(type) {
+		case int:
+			return true
+		case float64:
+			return v == float64(int64(v))
+		case string:
+			_, err := strconv.Atoi(v)
+			return err == nil
+		}
+		return false
+	case "float":
+		switch v := value.(type) {
+		case int, float64:
+			return true
+		case string:
+			_, err := strconv.ParseFloat(v, 64)
+			return err == nil
+		}
+		return false
+	case "str", "path", "bytes", "bits":
+		return !isMap && !isList
+	case "list":
+		return !isMap
+	case "dict":
+		_, isString := value.(string)
+		return isMap || isString
+	}
+
+	// raw, json, jsonarg and unknown types accept anything
+	return true
+}
+
+func isValidOptionChoice(choices []string, value interface{}) bool {
+	s := fmt.Sprint(value)
+	if b, ok := value.(bool); ok {
+		// YAML 1.1 booleans such as `yes` decode as bool, while the choices
+		// are documented with their original spelling
+		if b {
+			return containsAnyString(choices, "true", "True", "yes", "on")
+		}
+		return containsAnyString(choices, "false", "False", "no", "off")
+	}
+	return containsAnyString(choices, s)
+}
+
+func containsAnyString(values []string, candidates ...string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if v == c {
+				return true
+			}
+		}
+	}
+	return false
+}
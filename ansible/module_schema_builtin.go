@@ -0,0 +1,469 @@
+package ansible
+
+// ansibleBuiltinModuleSchemas is a snapshot of the argument specs of the most
+// used ansible.builtin modules, taken from ansible-core 2.17. The builtin
+// modules ship inside ansible-core rather than as an installed collection, so
+// their docs cannot be read from the collections paths.
+var ansibleBuiltinModuleSchemas = map[string]*ansibleModuleSchema{
+	"ansible.builtin.apt": {Options: map[string]ansibleModuleOption{
+		"allow_change_held_packages":   {Type: "bool"},
+		"allow_downgrade":              {Type: "bool", Aliases: []string{"allow-downgrade", "allow_downgrades", "allow-downgrades"}},
+		"allow_unauthenticated":        {Type: "bool", Aliases: []string{"allow-unauthenticated"}},
+		"autoclean":                    {Type: "bool"},
+		"autoremove":                   {Type: "bool"},
+		"cache_valid_time":             {Type: "int"},
+		"clean":                        {Type: "bool"},
+		"deb":                          {Type: "path"},
+		"default_release":              {Type: "str", Aliases: []string{"default-release"}},
+		"dpkg_options":                 {Type: "str"},
+		"fail_on_autoremove":           {Type: "bool"},
+		"force":                        {Type: "bool"},
+		"force_apt_get":                {Type: "bool"},
+		"install_recommends":           {Type: "bool", Aliases: []string{"install-recommends"}},
+		"lock_timeout":                 {Type: "int"},
+		"name":                         {Type: "list", Elements: "str", Aliases: []string{"package", "pkg"}},
+		"only_upgrade":                 {Type: "bool"},
+		"policy_rc_d":                  {Type: "int"},
+		"purge":                        {Type: "bool"},
+		"state":                        {Type: "str", Choices: []string{"absent", "build-dep", "latest", "present", "fixed"}},
+		"update_cache":                 {Type: "bool", Aliases: []string{"update-cache"}},
+		"update_cache_retries":         {Type: "int"},
+		"update_cache_retry_max_delay": {Type: "int"},
+		"upgrade":                      {Type: "str", Choices: []string{"dist", "full", "no", "safe", "yes"}},
+	}},
+	"ansible.builtin.blockinfile": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"append_newline":  {Type: "bool"},
+		"backup":          {Type: "bool"},
+		"block":           {Type: "str", Aliases: []string{"content"}},
+		"create":          {Type: "bool"},
+		"insertafter":     {Type: "str"},
+		"insertbefore":    {Type: "str"},
+		"marker":          {Type: "str"},
+		"marker_begin":    {Type: "str"},
+		"marker_end":      {Type: "str"},
+		"path":            {Type: "path", Required: true, Aliases: []string{"dest", "destfile", "name"}},
+		"prepend_newline": {Type: "bool"},
+		"state":           {Type: "str", Choices: []string{"absent", "present"}},
+		"validate":        {Type: "str"},
+	})},
+	"ansible.builtin.command": {FreeForm: true, Options: map[string]ansibleModuleOption{
+		"argv":                 {Type: "list", Elements: "str"},
+		"chdir":                {Type: "path"},
+		"cmd":                  {Type: "str"},
+		"creates":              {Type: "path"},
+		"executable":           {Type: "path"},
+		"expand_argument_vars": {Type: "bool"},
+		"removes":              {Type: "path"},
+		"stdin":                {Type: "str"},
+		"stdin_add_newline":    {Type: "bool"},
+		"strip_empty_ends":     {Type: "bool"},
+	}},
+	"ansible.builtin.copy": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"backup":         {Type: "bool"},
+		"checksum":       {Type: "str"},
+		"content":        {Type: "str"},
+		"decrypt":        {Type: "bool"},
+		"dest":           {Type: "path", Required: true},
+		"directory_mode": {Type: "raw"},
+		"follow":         {Type: "bool"},
+		"force":          {Type: "bool", Aliases: []string{"thirsty"}},
+		"local_follow":   {Type: "bool"},
+		"remote_src":     {Type: "bool"},
+		"src":            {Type: "path"},
+		"validate":       {Type: "str"},
+	})},
+	"ansible.builtin.cron": {Options: map[string]ansibleModuleOption{
+		"backup":       {Type: "bool"},
+		"cron_file":    {Type: "path"},
+		"day":          {Type: "str", Aliases: []string{"dom"}},
+		"disabled":     {Type: "bool"},
+		"env":          {Type: "bool"},
+		"hour":         {Type: "str"},
+		"insertafter":  {Type: "str"},
+		"insertbefore": {Type: "str"},
+		"job":          {Type: "str", Aliases: []string{"value"}},
+		"minute":       {Type: "str"},
+		"month":        {Type: "str"},
+		"name":         {Type: "str"},
+		"special_time": {Type: "str", Choices: []string{"annually", "daily", "hourly", "monthly", "reboot", "weekly", "yearly"}},
+		"state":        {Type: "str", Choices: []string{"absent", "present"}},
+		"user":         {Type: "str"},
+		"weekday":      {Type: "str", Aliases: []string{"dow"}},
+	}},
+	"ansible.builtin.debug": {Options: map[string]ansibleModuleOption{
+		"msg":       {Type: "str"},
+		"var":       {Type: "str"},
+		"verbosity": {Type: "int"},
+	}},
+	"ansible.builtin.dnf": {Options: map[string]ansibleModuleOption{
+		"allow_downgrade":   {Type: "bool"},
+		"allowerasing":      {Type: "bool"},
+		"autoremove":        {Type: "bool"},
+		"best":              {Type: "bool"},
+		"bugfix":            {Type: "bool"},
+		"cacheonly":         {Type: "bool"},
+		"conf_file":         {Type: "str"},
+		"disable_excludes":  {Type: "str"},
+		"disable_gpg_check": {Type: "bool"},
+		"disable_plugin":    {Type: "list", Elements: "str"},
+		"disablerepo":       {Type: "list", Elements: "str"},
+		"download_dir":      {Type: "str"},
+		"download_only":     {Type: "bool"},
+		"enable_plugin":     {Type: "list", Elements: "str"},
+		"enablerepo":        {Type: "list", Elements: "str"},
+		"exclude":           {Type: "list", Elements: "str"},
+		"install_repoquery": {Type: "bool"},
+		"install_weak_deps": {Type: "bool"},
+		"installroot":       {Type: "str"},
+		"list":              {Type: "str"},
+		"lock_timeout":      {Type: "int"},
+		"name":              {Type: "list", Elements: "str", Aliases: []string{"pkg"}},
+		"nobest":            {Type: "bool"},
+		"releasever":        {Type: "str"},
+		"security":          {Type: "bool"},
+		"skip_broken":       {Type: "bool"},
+		"sslverify":         {Type: "bool"},
+		"state":             {Type: "str", Choices: []string{"absent", "present", "installed", "removed", "latest"}},
+		"update_cache":      {Type: "bool", Aliases: []string{"expire-cache"}},
+		"update_only":       {Type: "bool"},
+		"use_backend":       {Type: "str", Choices: []string{"auto", "dnf", "yum", "yum4", "dnf4", "dnf5"}},
+		"validate_certs":    {Type: "bool"},
+	}},
+	"ansible.builtin.fetch": {Options: map[string]ansibleModuleOption{
+		"dest":              {Type: "str", Required: true},
+		"fail_on_missing":   {Type: "bool"},
+		"flat":              {Type: "bool"},
+		"src":               {Type: "str", Required: true},
+		"validate_checksum": {Type: "bool"},
+	}},
+	"ansible.builtin.file": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"access_time":              {Type: "str"},
+		"access_time_format":       {Type: "str"},
+		"follow":                   {Type: "bool"},
+		"force":                    {Type: "bool"},
+		"modification_time":        {Type: "str"},
+		"modification_time_format": {Type: "str"},
+		"path":                     {Type: "path", Required: true, Aliases: []string{"dest", "name"}},
+		"recurse":                  {Type: "bool"},
+		"src":                      {Type: "path"},
+		"state":                    {Type: "str", Choices: []string{"absent", "directory", "file", "hard", "link", "touch"}},
+	})},
+	"ansible.builtin.get_url": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"backup":               {Type: "bool"},
+		"checksum":             {Type: "str"},
+		"ciphers":              {Type: "list", Elements: "str"},
+		"client_cert":          {Type: "path"},
+		"client_key":           {Type: "path"},
+		"decompress":           {Type: "bool"},
+		"dest":                 {Type: "path", Required: true},
+		"force":                {Type: "bool"},
+		"force_basic_auth":     {Type: "bool"},
+		"headers":              {Type: "dict"},
+		"http_agent":           {Type: "str"},
+		"timeout":              {Type: "int"},
+		"tmp_dest":             {Type: "path"},
+		"unredirected_headers": {Type: "list", Elements: "str"},
+		"url":                  {Type: "str", Required: true},
+		"url_password":         {Type: "str", Aliases: []string{"password"}},
+		"url_username":         {Type: "str", Aliases: []string{"username"}},
+		"use_gssapi":           {Type: "bool"},
+		"use_netrc":            {Type: "bool"},
+		"use_proxy":            {Type: "bool"},
+		"validate_certs":       {Type: "bool"},
+	})},
+	"ansible.builtin.git": {Options: map[string]ansibleModuleOption{
+		"accept_hostkey":    {Type: "bool"},
+		"accept_newhostkey": {Type: "bool"},
+		"archive":           {Type: "path"},
+		"archive_prefix":    {Type: "str"},
+		"bare":              {Type: "bool"},
+		"clone":             {Type: "bool"},
+		"depth":             {Type: "int"},
+		"dest":              {Type: "path"},
+		"executable":        {Type: "path"},
+		"force":             {Type: "bool"},
+		"gpg_allowlist":     {Type: "list", Elements: "str", Aliases: []string{"gpg_whitelist"}},
+		"key_file":          {Type: "path"},
+		"recursive":         {Type: "bool"},
+		"reference":         {Type: "str"},
+		"refspec":           {Type: "str"},
+		"remote":            {Type: "str"},
+		"repo":              {Type: "str", Required: true, Aliases: []string{"name"}},
+		"separate_git_dir":  {Type: "path"},
+		"single_branch":     {Type: "bool"},
+		"ssh_opts":          {Type: "str"},
+		"track_submodules":  {Type: "bool"},
+		"umask":             {Type: "raw"},
+		"update":            {Type: "bool"},
+		"verify_commit":     {Type: "bool"},
+		"version":           {Type: "str"},
+	}},
+	"ansible.builtin.group": {Options: map[string]ansibleModuleOption{
+		"force":      {Type: "bool"},
+		"gid":        {Type: "int"},
+		"gid_max":    {Type: "int"},
+		"gid_min":    {Type: "int"},
+		"local":      {Type: "bool"},
+		"name":       {Type: "str", Required: true},
+		"non_unique": {Type: "bool"},
+		"state":      {Type: "str", Choices: []string{"absent", "present"}},
+		"system":     {Type: "bool"},
+	}},
+	"ansible.builtin.lineinfile": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"backrefs":      {Type: "bool"},
+		"backup":        {Type: "bool"},
+		"create":        {Type: "bool"},
+		"firstmatch":    {Type: "bool"},
+		"insertafter":   {Type: "str"},
+		"insertbefore":  {Type: "str"},
+		"line":          {Type: "str", Aliases: []string{"value"}},
+		"path":          {Type: "path", Required: true, Aliases: []string{"dest", "destfile", "name"}},
+		"regexp":        {Type: "str", Aliases: []string{"regex"}},
+		"search_string": {Type: "str"},
+		"state":         {Type: "str", Choices: []string{"absent", "present"}},
+		"validate":      {Type: "str"},
+	})},
+	"ansible.builtin.pip": {Options: map[string]ansibleModuleOption{
+		"break_system_packages":    {Type: "bool"},
+		"chdir":                    {Type: "path"},
+		"editable":                 {Type: "bool"},
+		"executable":               {Type: "path"},
+		"extra_args":               {Type: "str"},
+		"name":                     {Type: "list", Elements: "str"},
+		"requirements":             {Type: "str"},
+		"state":                    {Type: "str", Choices: []string{"absent", "forcereinstall", "latest", "present"}},
+		"umask":                    {Type: "str"},
+		"version":                  {Type: "str"},
+		"virtualenv":               {Type: "path"},
+		"virtualenv_command":       {Type: "path"},
+		"virtualenv_python":        {Type: "str"},
+		"virtualenv_site_packages": {Type: "bool"},
+	}},
+	"ansible.builtin.ping": {Options: map[string]ansibleModuleOption{
+		"data": {Type: "str"},
+	}},
+	"ansible.builtin.reboot": {Options: map[string]ansibleModuleOption{
+		"boot_time_command": {Type: "str"},
+		"connect_timeout":   {Type: "int"},
+		"msg":               {Type: "str"},
+		"post_reboot_delay": {Type: "int"},
+		"pre_reboot_delay":  {Type: "int"},
+		"reboot_command":    {Type: "str"},
+		"reboot_timeout":    {Type: "int"},
+		"search_paths":      {Type: "list", Elements: "str"},
+		"test_command":      {Type: "str"},
+	}},
+	"ansible.builtin.replace": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"after":    {Type: "str"},
+		"backup":   {Type: "bool"},
+		"before":   {Type: "str"},
+		"encoding": {Type: "str"},
+		"path":     {Type: "path", Required: true, Aliases: []string{"dest", "destfile", "name"}},
+		"regexp":   {Type: "str", Required: true},
+		"replace":  {Type: "str"},
+		"validate": {Type: "str"},
+	})},
+	"ansible.builtin.service": {Options: map[string]ansibleModuleOption{
+		"arguments": {Type: "str", Aliases: []string{"args"}},
+		"enabled":   {Type: "bool"},
+		"name":      {Type: "str", Required: true},
+		"pattern":   {Type: "str"},
+		"runlevel":  {Type: "str"},
+		"sleep":     {Type: "int"},
+		"state":     {Type: "str", Choices: []string{"reloaded", "restarted", "started", "stopped"}},
+		"use":       {Type: "str"},
+	}},
+	"ansible.builtin.shell": {FreeForm: true, Options: map[string]ansibleModuleOption{
+		"chdir":             {Type: "path"},
+		"cmd":               {Type: "str"},
+		"creates":           {Type: "path"},
+		"executable":        {Type: "path"},
+		"removes":           {Type: "path"},
+		"stdin":             {Type: "str"},
+		"stdin_add_newline": {Type: "bool"},
+	}},
+	"ansible.builtin.stat": {Options: map[string]ansibleModuleOption{
+		"checksum_algorithm":  {Type: "str", Aliases: []string{"checksum", "checksum_algo"}, Choices: []string{"md5", "sha1", "sha224", "sha256", "sha384", "sha512"}},
+		"follow":              {Type: "bool"},
+		"get_attributes":      {Type: "bool", Aliases: []string{"attr", "attributes"}},
+		"get_checksum":        {Type: "bool"},
+		"get_mime":            {Type: "bool", Aliases: []string{"mime", "mime_type", "mime-type"}},
+		"get_selinux_context": {Type: "bool"},
+		"path":                {Type: "path", Required: true, Aliases: []string{"dest", "name"}},
+	}},
+	"ansible.builtin.systemd_service": ansibleSystemdServiceSchema,
+	"ansible.builtin.systemd":         ansibleSystemdServiceSchema,
+	"ansible.builtin.template": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"backup":                {Type: "bool"},
+		"block_end_string":      {Type: "str"},
+		"block_start_string":    {Type: "str"},
+		"comment_end_string":    {Type: "str"},
+		"comment_start_string":  {Type: "str"},
+		"dest":                  {Type: "path", Required: true},
+		"follow":                {Type: "bool"},
+		"force":                 {Type: "bool"},
+		"lstrip_blocks":         {Type: "bool"},
+		"newline_sequence":      {Type: "str", Choices: []string{"\n", "\r", "\r\n"}},
+		"output_encoding":       {Type: "str"},
+		"src":                   {Type: "path", Required: true},
+		"trim_blocks":           {Type: "bool"},
+		"validate":              {Type: "str"},
+		"variable_end_string":   {Type: "str"},
+		"variable_start_string": {Type: "str"},
+	})},
+	"ansible.builtin.unarchive": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"copy":           {Type: "bool"},
+		"creates":        {Type: "path"},
+		"decrypt":        {Type: "bool"},
+		"dest":           {Type: "path", Required: true},
+		"exclude":        {Type: "list", Elements: "str"},
+		"extra_opts":     {Type: "list", Elements: "str"},
+		"include":        {Type: "list", Elements: "str"},
+		"io_buffer_size": {Type: "int"},
+		"keep_newer":     {Type: "bool"},
+		"list_files":     {Type: "bool"},
+		"remote_src":     {Type: "bool"},
+		"src":            {Type: "path", Required: true},
+		"validate_certs": {Type: "bool"},
+	})},
+	"ansible.builtin.uri": {Options: withFileCommonOptions(map[string]ansibleModuleOption{
+		"body":                 {Type: "raw"},
+		"body_format":          {Type: "str", Choices: []string{"form-urlencoded", "json", "raw", "form-multipart"}},
+		"ca_path":              {Type: "path"},
+		"ciphers":              {Type: "list", Elements: "str"},
+		"client_cert":          {Type: "path"},
+		"client_key":           {Type: "path"},
+		"creates":              {Type: "path"},
+		"decompress":           {Type: "bool"},
+		"dest":                 {Type: "path"},
+		"follow_redirects":     {Type: "str"},
+		"force":                {Type: "bool"},
+		"force_basic_auth":     {Type: "bool"},
+		"headers":              {Type: "dict"},
+		"http_agent":           {Type: "str"},
+		"method":               {Type: "str"},
+		"remote_src":           {Type: "bool"},
+		"removes":              {Type: "path"},
+		"return_content":       {Type: "bool"},
+		"src":                  {Type: "path"},
+		"status_code":          {Type: "list", Elements: "int"},
+		"timeout":              {Type: "int"},
+		"unix_socket":          {Type: "path"},
+		"unredirected_headers": {Type: "list", Elements: "str"},
+		"url":                  {Type: "str", Required: true},
+		"url_password":         {Type: "str", Aliases: []string{"password"}},
+		"url_username":         {Type: "str", Aliases: []string{"user"}},
+		"use_gssapi":           {Type: "bool"},
+		"use_netrc":            {Type: "bool"},
+		"use_proxy":            {Type: "bool"},
+		"validate_certs":       {Type: "bool"},
+	})},
+	"ansible.builtin.user": {Options: map[string]ansibleModuleOption{
+		"append":                          {Type: "bool"},
+		"authorization":                   {Type: "str"},
+		"comment":                         {Type: "str"},
+		"create_home":                     {Type: "bool", Aliases: []string{"createhome"}},
+		"expires":                         {Type: "float"},
+		"force":                           {Type: "bool"},
+		"generate_ssh_key":                {Type: "bool"},
+		"group":                           {Type: "str"},
+		"groups":                          {Type: "list", Elements: "str"},
+		"hidden":                          {Type: "bool"},
+		"home":                            {Type: "path"},
+		"local":                           {Type: "bool"},
+		"login_class":                     {Type: "str"},
+		"move_home":                       {Type: "bool"},
+		"name":                            {Type: "str", Required: true, Aliases: []string{"user"}},
+		"non_unique":                      {Type: "bool"},
+		"password":                        {Type: "str"},
+		"password_expire_account_disable": {Type: "int"},
+		"password_expire_max":             {Type: "int"},
+		"password_expire_min":             {Type: "int"},
+		"password_expire_warn":            {Type: "int"},
+		"password_lock":                   {Type: "bool"},
+		"profile":                         {Type: "str"},
+		"remove":                          {Type: "bool"},
+		"role":                            {Type: "str"},
+		"seuser":                          {Type: "str"},
+		"shell":                           {Type: "str"},
+		"skeleton":                        {Type: "str"},
+		"ssh_key_bits":                    {Type: "int"},
+		"ssh_key_comment":                 {Type: "str"},
+		"ssh_key_file":                    {Type: "path"},
+		"ssh_key_passphrase":              {Type: "str"},
+		"ssh_key_type":                    {Type: "str"},
+		"state":                           {Type: "str", Choices: []string{"absent", "present"}},
+		"system":                          {Type: "bool"},
+		"uid":                             {Type: "int"},
+		"umask":                           {Type: "str"},
+		"update_password":                 {Type: "str", Choices: []string{"always", "on_create"}},
+	}},
+	"ansible.builtin.wait_for": {Options: map[string]ansibleModuleOption{
+		"active_connection_states": {Type: "list", Elements: "str"},
+		"connect_timeout":          {Type: "int"},
+		"delay":                    {Type: "int"},
+		"exclude_hosts":            {Type: "list", Elements: "str"},
+		"host":                     {Type: "str"},
+		"msg":                      {Type: "str"},
+		"path":                     {Type: "path"},
+		"port":                     {Type: "int"},
+		"search_regex":             {Type: "str"},
+		"sleep":                    {Type: "int"},
+		"state":                    {Type: "str", Choices: []string{"absent", "drained", "present", "started", "stopped"}},
+		"timeout":                  {Type: "int"},
+	}},
+}
+
+// ansibleBuiltinDocFragments are the options of the doc fragments of
+// ansible-core extended by the modules of collections, by fully qualified
+// name. The action_common_attributes and return_common fragments only
+// document attributes and return values.
+var ansibleBuiltinDocFragments = map[string]map[string]ansibleModuleOption{
+	"ansible.builtin.action_common_attributes":             {},
+	"ansible.builtin.action_common_attributes.conn":        {},
+	"ansible.builtin.action_common_attributes.facts":       {},
+	"ansible.builtin.action_common_attributes.files":       {},
+	"ansible.builtin.action_common_attributes.flow":        {},
+	"ansible.builtin.action_common_attributes.info_facts":  {},
+	"ansible.builtin.action_common_attributes.info_module": {},
+	"ansible.builtin.action_common_attributes.raw":         {},
+	"ansible.builtin.backup":                               {"backup": {Type: "bool"}},
+	"ansible.builtin.decrypt":                              {"decrypt": {Type: "bool"}},
+	"ansible.builtin.files":                                withFileCommonOptions(map[string]ansibleModuleOption{}),
+	"ansible.builtin.return_common":                        {},
+	"ansible.builtin.validate":                             {"validate": {Type: "str"}},
+}
+
+var ansibleSystemdServiceSchema = &ansibleModuleSchema{Options: map[string]ansibleModuleOption{
+	"daemon_reexec": {Type: "bool", Aliases: []string{"daemon-reexec"}},
+	"daemon_reload": {Type: "bool", Aliases: []string{"daemon-reload"}},
+	"enabled":       {Type: "bool"},
+	"force":         {Type: "bool"},
+	"masked":        {Type: "bool"},
+	"name":          {Type: "str", Aliases: []string{"service", "unit"}},
+	"no_block":      {Type: "bool"},
+	"scope":         {Type: "str", Choices: []string{"system", "user", "global"}},
+	"state":         {Type: "str", Choices: []string{"reloaded", "restarted", "started", "stopped"}},
+}}
+
+// withFileCommonOptions adds the options shared by the modules that manage
+// file attributes, i.e. the `files` doc fragment.
+func withFileCommonOptions(options map[string]ansibleModuleOption) map[string]ansibleModuleOption {
+	common := map[string]ansibleModuleOption{
+		"attributes":    {Type: "str", Aliases: []string{"attr"}},
+		"group":         {Type: "str"},
+		"mode":          {Type: "raw"},
+		"owner":         {Type: "str"},
+		"selevel":       {Type: "str"},
+		"serole":        {Type: "str"},
+		"setype":        {Type: "str"},
+		"seuser":        {Type: "str"},
+		"unsafe_writes": {Type: "bool"},
+	}
+	for name, option := range common {
+		if _, exists := options[name]; !exists {
+			options[name] = option
+		}
+	}
+	return options
+}
@@ -0,0 +1,95 @@
+package ansible
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func newTestContext() context.Context {
+	return context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAnsibleDocFragmentIndexExtend(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ansible_collections", "acme", "cloud")
+	writeTestFiles(t, dir, map[string]string{
+		"galaxy.yml": "namespace: acme\nname: cloud\nversion: 1.0.0\n",
+		"plugins/doc_fragments/common.py": `class ModuleDocFragment(object):
+    DOCUMENTATION = r'''
+options:
+  profile:
+    type: str
+    aliases: [aws_profile]
+'''
+
+    REGION = r'''
+options:
+  region:
+    type: str
+    required: true
+'''
+`,
+	})
+
+	index := newAnsibleDocFragmentIndex()
+	if err := index.addCollection(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		fragments []string
+		options   []string
+		partial   bool
+	}{
+		{name: "file fragment", fragments: []string{"acme.cloud.common"}, options: []string{"name", "profile"}},
+		{name: "attribute fragment", fragments: []string{"acme.cloud.common.region"}, options: []string{"name", "region"}},
+		{name: "builtin fragments", fragments: []string{"files", "action_common_attributes.files"}, options: []string{"name", "mode", "owner", "setype"}},
+		{name: "unresolved fragment", fragments: []string{"acme.cloud.missing"}, options: []string{"name"}, partial: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema := newAnsibleModuleSchemaFromDoc(map[string]interface{}{"name": map[string]interface{}{"type": "str"}})
+			index.extend(newTestContext(), schema, test.fragments)
+			if schema.Partial != test.partial {
+				t.Errorf("got partial %v, want %v", schema.Partial, test.partial)
+			}
+			for _, option := range test.options {
+				if _, ok := schema.Options[option]; !ok {
+					t.Errorf("missing option %s in %v", option, schema.Options)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateModuleArgsPartialSchema(t *testing.T) {
+	schema := &ansibleModuleSchema{Options: map[string]ansibleModuleOption{"name": {Type: "str", Required: true}}}
+	args := map[string]interface{}{"name": "web", "region": "eu-west-1"}
+
+	if errors := validateModuleArgs(schema, args); len(errors) != 1 || errors[0].Kind != "unknown_option" {
+		t.Errorf("got %v, want an unknown_option error", errors)
+	}
+	schema.Partial = true
+	if errors := validateModuleArgs(schema, args); len(errors) != 0 {
+		t.Errorf("got %v, want no errors for a partial schema", errors)
+	}
+}
@@ -0,0 +1,356 @@
+package ansible
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/relex/aini"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Parsed files are kept in the connection cache, so that tables listing the
+// content of the same file, e.g. ansible_playbook and ansible_task in a join,
+// only parse it once. The cache key includes the modification time and size
+// of the file, and the cache is cleared when a watched file changes.
+
+// ansibleParseResult is the content parsed from a file, along with the parts
+// of the file that could not be parsed.
+type ansibleParseResult struct {
+	Items       interface{}
+	ParseErrors []ansibleParseError
+	// Warnings are problems that do not prevent listing the content, e.g. a
+	// Jinja2 expression that cannot be parsed
+	Warnings []ansibleParseError
+}
+
+// ansibleInventoryResult is a parsed inventory file. Err is set if the file
+// could not be parsed.
+type ansibleInventoryResult struct {
+	Data      *aini.InventoryData
+	Err       error
+	Locations inventoryLocations
+}
+
+// getCachedParse returns the result of parse for the content of the file,
+// from the connection cache if the file has not changed since it was parsed.
+// Only errors reading the file are returned, parse must report its errors in
+// its result.
+func getCachedParse(ctx context.Context, d *plugin.QueryData, name string, file filePath, parse func(content []byte) interface{}) (interface{}, error) {
+	key, err := parseCacheKey(name, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached, ok := d.ConnectionCache.Get(ctx, key); ok {
+		file.Metrics.addCacheHit()
+		return cached, nil
+	}
+
+	// Bound the number of files held in memory while being parsed
+	waitStart := time.Now()
+	release, err := acquireParseSlot(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	file.Metrics.addWait(time.Since(waitStart))
+
+	parseStart := time.Now()
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	result := parse(content)
+	release()
+	file.Metrics.addFileParsed(len(content), time.Since(parseStart))
+
+	// A failure to cache only means the file is parsed again
+	_ = d.ConnectionCache.Set(ctx, key, result)
+
+	return result, nil
+}
+
+// parseCacheKey returns the cache key of a parse result, which changes with
+// the modification time and size of the file.
+func parseCacheKey(name string, file filePath) (string, error) {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ansible_%s_%s_%d_%d", name, file.Path, info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// scanAnsibleFile reports whether the file contains every word, to skip the
+// files that cannot match the quals of a query before parsing them. Files
+// already parsed for the named parse result are not scanned.
+func scanAnsibleFile(ctx context.Context, d *plugin.QueryData, name string, file filePath, words []string) (bool, error) {
+	if len(words) == 0 {
+		return true, nil
+	}
+
+	key, err := parseCacheKey(name, file)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := d.ConnectionCache.Get(ctx, key); ok {
+		return true, nil
+	}
+
+	release, err := acquireParseSlot(ctx, d)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	content, err := os.ReadFile(file.Path)
+	if err != nil {
+		return false, err
+	}
+	if !containsWords(content, words) {
+		file.Metrics.addFileSkipped()
+		return false, nil
+	}
+	return true, nil
+}
+
+// getAnsibleFileClassification returns the detected kind of the file.
+func getAnsibleFileClassification(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleFileClassification, error) {
+	result, err := getCachedParse(ctx, d, "classification", file, func(content []byte) interface{} {
+		return classifyAnsibleFile(file.Path, content)
+	})
+	if err != nil {
+		return ansibleFileClassification{}, err
+	}
+	return result.(ansibleFileClassification), nil
+}
+
+// getAnsiblePlays returns the plays of a playbook.
+func getAnsiblePlays(ctx context.Context, d *plugin.QueryData, file filePath) ([]AnsiblePlaybookInfo, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "plays", file, func(content []byte) interface{} {
+		plays, parseErrors := decodeAnsiblePlays(content)
+		return ansibleParseResult{Items: plays, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]AnsiblePlaybookInfo), parsed.ParseErrors, nil
+}
+
+// getAnsibleTasks returns the tasks of the plays of a playbook, or of a tasks
+// file.
+func getAnsibleTasks(ctx context.Context, d *plugin.QueryData, file filePath) ([]AnsibleTask, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "tasks", file, func(content []byte) interface{} {
+		tasks, parseErrors := decodeAnsibleTasks(content)
+		return ansibleParseResult{Items: tasks, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]AnsibleTask), parsed.ParseErrors, nil
+}
+
+// getAnsibleInventory returns the parsed inventory file, along with the
+// location of its groups and hosts.
+func getAnsibleInventory(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleInventoryResult, error) {
+	result, err := getCachedParse(ctx, d, "inventory", file, func(content []byte) interface{} {
+		data, err := aini.Parse(bytes.NewReader(content))
+		if err != nil {
+			return ansibleInventoryResult{Err: err}
+		}
+		return ansibleInventoryResult{Data: data, Locations: scanInventoryLocations(content)}
+	})
+	if err != nil {
+		return ansibleInventoryResult{}, err
+	}
+	return result.(ansibleInventoryResult), nil
+}
+
+// getAnsibleLintFindings returns the lint findings of a playbook or tasks file.
+func getAnsibleLintFindings(ctx context.Context, d *plugin.QueryData, file filePath) ([]AnsibleLintFinding, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "lint", file, func(content []byte) interface{} {
+		findings, parseErrors := lintAnsibleFile(content, file.Path)
+		return ansibleParseResult{Items: findings, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]AnsibleLintFinding), parsed.ParseErrors, nil
+}
+
+// getAnsibleRequirements returns the roles and collections of a requirements
+// file.
+func getAnsibleRequirements(ctx context.Context, d *plugin.QueryData, file filePath) ([]AnsibleRequirementInfo, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "requirements", file, func(content []byte) interface{} {
+		requirements, err := parseAnsibleRequirements(content)
+		if err != nil {
+			return ansibleParseResult{ParseErrors: []ansibleParseError{newAnsibleParseError(err, nil)}}
+		}
+		return ansibleParseResult{Items: requirements}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	requirements, _ := parsed.Items.([]AnsibleRequirementInfo)
+	return requirements, parsed.ParseErrors, nil
+}
+
+// getAnsibleVariableReferences returns the variables used by the expressions
+// of a playbook, tasks file or vars file.
+func getAnsibleVariableReferences(ctx context.Context, d *plugin.QueryData, file filePath, kind string) ([]AnsibleVariableReferenceInfo, []ansibleParseError, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "variable_references", file, func(content []byte) interface{} {
+		references, parseErrors, warnings := extractAnsibleVariableReferences(content, file.Path, kind)
+		return ansibleParseResult{Items: references, ParseErrors: parseErrors, Warnings: warnings}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]AnsibleVariableReferenceInfo), parsed.ParseErrors, parsed.Warnings, nil
+}
+
+// getAnsibleVariableDefinitions returns the variables defined by a playbook,
+// tasks file or vars file.
+func getAnsibleVariableDefinitions(ctx context.Context, d *plugin.QueryData, file filePath, kind string) (ansibleVariableDefinitions, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "variable_definitions_"+kind, file, func(content []byte) interface{} {
+		definitions, parseErrors := extractAnsibleVariableDefinitions(content, file.Path, kind)
+		return ansibleParseResult{Items: definitions, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return ansibleVariableDefinitions{}, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.(ansibleVariableDefinitions), parsed.ParseErrors, nil
+}
+
+// getAnsibleTemplateUsages returns the filters, tests and lookups used by a
+// playbook, tasks file, vars file or template.
+func getAnsibleTemplateUsages(ctx context.Context, d *plugin.QueryData, file filePath, kind string) ([]AnsibleTemplateUsageInfo, []ansibleParseError, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "template_usages_"+kind, file, func(content []byte) interface{} {
+		usages, parseErrors, warnings := extractAnsibleTemplateUsages(content, file.Path, kind)
+		return ansibleParseResult{Items: usages, ParseErrors: parseErrors, Warnings: warnings}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]AnsibleTemplateUsageInfo), parsed.ParseErrors, parsed.Warnings, nil
+}
+
+// getAnsibleTemplateTasks returns the tasks of a playbook or tasks file
+// rendering a template.
+func getAnsibleTemplateTasks(ctx context.Context, d *plugin.QueryData, file filePath) ([]ansibleTemplateTask, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "template_tasks", file, func(content []byte) interface{} {
+		tasks, parseErrors := extractAnsibleTemplateTasks(content)
+		return ansibleParseResult{Items: tasks, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]ansibleTemplateTask), parsed.ParseErrors, nil
+}
+
+// getAnsibleTemplateAnalysis returns what a Jinja2 template uses and defines.
+func getAnsibleTemplateAnalysis(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleTemplateAnalysis, error) {
+	result, err := getCachedParse(ctx, d, "template", file, func(content []byte) interface{} {
+		analysis, err := analyzeJinjaTemplate(string(content))
+		if err != nil {
+			return ansibleTemplateAnalysis{Analysis: analysis, ParseError: err.Error()}
+		}
+		return ansibleTemplateAnalysis{Analysis: analysis}
+	})
+	if err != nil {
+		return ansibleTemplateAnalysis{}, err
+	}
+	return result.(ansibleTemplateAnalysis), nil
+}
+
+// getAnsibleConditionalTasks returns the tasks of the plays of a playbook,
+// along with their conditions and vars.
+func getAnsibleConditionalTasks(ctx context.Context, d *plugin.QueryData, file filePath) ([]ansibleConditionalTask, []ansibleParseError, error) {
+	result, err := getCachedParse(ctx, d, "conditional_tasks", file, func(content []byte) interface{} {
+		tasks, parseErrors := extractAnsibleConditionalTasks(content)
+		return ansibleParseResult{Items: tasks, ParseErrors: parseErrors}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	parsed := result.(ansibleParseResult)
+	return parsed.Items.([]ansibleConditionalTask), parsed.ParseErrors, nil
+}
+
+// getAnsibleFactCache returns the facts of a fact cache file.
+func getAnsibleFactCache(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleFactCache, error) {
+	result, err := getCachedParse(ctx, d, "fact_cache", file, func(content []byte) interface{} {
+		return parseAnsibleFactCache(content)
+	})
+	if err != nil {
+		return ansibleFactCache{}, err
+	}
+	return result.(ansibleFactCache), nil
+}
+
+// getAnsibleRunLog returns the run parsed from the output of ansible-playbook
+// with the json stdout callback.
+func getAnsibleRunLog(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleRunLog, error) {
+	result, err := getCachedParse(ctx, d, "run_log", file, func(content []byte) interface{} {
+		return parseAnsibleRunLog(content, file.Path)
+	})
+	if err != nil {
+		return ansibleRunLog{}, err
+	}
+	return result.(ansibleRunLog), nil
+}
+
+// getAnsibleLog returns the events and recaps of a log file written by
+// Ansible.
+func getAnsibleLog(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleLogContent, error) {
+	result, err := getCachedParse(ctx, d, "log", file, func(content []byte) interface{} {
+		return parseAnsibleLog(content, file.Path)
+	})
+	if err != nil {
+		return ansibleLogContent{}, err
+	}
+	return result.(ansibleLogContent), nil
+}
+
+// getAnsibleJUnitReport returns the test cases of a report written by the
+// junit callback.
+func getAnsibleJUnitReport(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleJUnitReport, error) {
+	result, err := getCachedParse(ctx, d, "junit_report", file, func(content []byte) interface{} {
+		return parseAnsibleJUnitReport(content, file.Path)
+	})
+	if err != nil {
+		return ansibleJUnitReport{}, err
+	}
+	return result.(ansibleJUnitReport), nil
+}
+
+// getAnsibleRetryFile returns the hosts listed in a retry file.
+func getAnsibleRetryFile(ctx context.Context, d *plugin.QueryData, file filePath) ([]string, error) {
+	result, err := getCachedParse(ctx, d, "retry_file", file, func(content []byte) interface{} {
+		return parseAnsibleRetryFile(content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]string), nil
+}
+
+// getAnsibleVarsFileValues returns the values of a vars file.
+func getAnsibleVarsFileValues(ctx context.Context, d *plugin.QueryData, file filePath) (ansibleVarsFileValues, error) {
+	result, err := getCachedParse(ctx, d, "vars_file_values", file, func(content []byte) interface{} {
+		return parseAnsibleVarsFileValues(content)
+	})
+	if err != nil {
+		return ansibleVarsFileValues{}, err
+	}
+	return result.(ansibleVarsFileValues), nil
+}
@@ -0,0 +1,93 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// Values of the on_parse_error config argument
+const (
+	parseErrorFail   = "fail"
+	parseErrorSkip   = "skip"
+	parseErrorRecord = "record"
+)
+
+// ansibleParseError is a problem found while parsing a file, such as a YAML
+// syntax error or a play that does not have the expected shape.
+type ansibleParseError struct {
+	Line    int
+	Message string
+}
+
+func (e ansibleParseError) Error() string {
+	return e.Message
+}
+
+var yamlErrorLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// newAnsibleParseError converts an error returned by yaml.v3. The line is
+// taken from the error message if it has one, otherwise from the node being
+// decoded, which may be nil.
+func newAnsibleParseError(err error, node *yaml.Node) ansibleParseError {
+	parseError := ansibleParseError{Message: err.Error()}
+	var typeError *yaml.TypeError
+	if errors.As(err, &typeError) {
+		// Drop the "yaml: unmarshal errors:" header of the multi-line message
+		parseError.Message = strings.Join(typeError.Errors, "; ")
+	}
+	if node != nil {
+		parseError.Line = node.Line
+	}
+	if matches := yamlErrorLineRegex.FindStringSubmatch(parseError.Message); matches != nil {
+		if line, err := strconv.Atoi(matches[1]); err == nil {
+			parseError.Line = line
+		}
+	}
+	return parseError
+}
+
+// getParseErrorMode returns the on_parse_error config argument, which
+// defaults to failing the query.
+func getParseErrorMode(d *plugin.QueryData) (string, error) {
+	config := GetConfig(d.Connection)
+	if config.OnParseError == nil {
+		return parseErrorFail, nil
+	}
+
+	switch *config.OnParseError {
+	case parseErrorFail, parseErrorSkip, parseErrorRecord:
+		return *config.OnParseError, nil
+	}
+	return "", fmt.Errorf("on_parse_error must be one of %s, %s or %s, got: %s", parseErrorFail, parseErrorSkip, parseErrorRecord, *config.OnParseError)
+}
+
+// handleParseErrors returns an error for the first parse error of the file
+// if the query should fail, otherwise the errors are only logged and the
+// parts of the file that could not be parsed are skipped.
+func handleParseErrors(ctx context.Context, d *plugin.QueryData, function string, path string, parseErrors []ansibleParseError) error {
+	if len(parseErrors) == 0 {
+		return nil
+	}
+
+	mode, err := getParseErrorMode(d)
+	if err != nil {
+		return err
+	}
+
+	if mode == parseErrorFail {
+		plugin.Logger(ctx).Error(function, "parse_error", parseErrors[0], "path", path)
+		return fmt.Errorf("failed to unmarshal file content %s: %v", path, parseErrors[0])
+	}
+	for _, parseError := range parseErrors {
+		plugin.Logger(ctx).Warn(function, "parse_error", parseError, "path", path, "line", parseError.Line)
+	}
+
+	return nil
+}
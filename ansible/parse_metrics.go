@@ -0,0 +1,161 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// maxRecordedQueries is the number of queries whose parse metrics are kept
+// for each connection.
+const maxRecordedQueries = 100
+
+// parseMetrics are the statistics of the files parsed for a query. They are
+// created by the parent hydrate listing the files, and passed to the list
+// functions along with each file path.
+type parseMetrics struct {
+	Connection          string
+	MaxParseConcurrency int
+	QueryID             int64
+	StartedAt           time.Time
+	Table               string
+
+	bytesParsed   atomic.Int64
+	cacheHits     atomic.Int64
+	filesListed   atomic.Int64
+	filesParsed   atomic.Int64
+	filesSkipped  atomic.Int64
+	parseDuration atomic.Int64
+	waitDuration  atomic.Int64
+}
+
+// parseMetricsRegistry holds the metrics of the most recent queries of each
+// connection, for the ansible_parse_metric table.
+var parseMetricsRegistry = struct {
+	sync.Mutex
+	lastQueryID int64
+	queries     map[string][]*parseMetrics
+}{queries: map[string][]*parseMetrics{}}
+
+// newParseMetrics records the metrics of a new query.
+func newParseMetrics(d *plugin.QueryData) (*parseMetrics, error) {
+	maxParseConcurrency, err := getMaxParseConcurrency(d)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &parseMetrics{
+		Connection:          d.Connection.Name,
+		MaxParseConcurrency: maxParseConcurrency,
+		StartedAt:           time.Now(),
+		Table:               d.Table.Name,
+	}
+
+	parseMetricsRegistry.Lock()
+	defer parseMetricsRegistry.Unlock()
+
+	parseMetricsRegistry.lastQueryID++
+	metrics.QueryID = parseMetricsRegistry.lastQueryID
+	queries := append(parseMetricsRegistry.queries[metrics.Connection], metrics)
+	if len(queries) > maxRecordedQueries {
+		queries = queries[len(queries)-maxRecordedQueries:]
+	}
+	parseMetricsRegistry.queries[metrics.Connection] = queries
+
+	return metrics, nil
+}
+
+// recentParseMetrics returns the metrics of the most recent queries of a
+// connection, oldest first.
+func recentParseMetrics(connection string) []*parseMetrics {
+	parseMetricsRegistry.Lock()
+	defer parseMetricsRegistry.Unlock()
+
+	return append([]*parseMetrics(nil), parseMetricsRegistry.queries[connection]...)
+}
+
+// The methods below accept a nil receiver, for files that are not listed by
+// a parent hydrate.
+
+func (m *parseMetrics) addFileListed() {
+	if m != nil {
+		m.filesListed.Add(1)
+	}
+}
+
+func (m *parseMetrics) addCacheHit() {
+	if m != nil {
+		m.cacheHits.Add(1)
+	}
+}
+
+func (m *parseMetrics) addFileParsed(bytes int, duration time.Duration) {
+	if m != nil {
+		m.filesParsed.Add(1)
+		m.bytesParsed.Add(int64(bytes))
+		m.parseDuration.Add(int64(duration))
+	}
+}
+
+func (m *parseMetrics) addFileSkipped() {
+	if m != nil {
+		m.filesSkipped.Add(1)
+	}
+}
+
+func (m *parseMetrics) addWait(duration time.Duration) {
+	if m != nil {
+		m.waitDuration.Add(int64(duration))
+	}
+}
+
+// getMaxParseConcurrency returns the max_parse_concurrency config argument,
+// which defaults to the number of CPUs.
+func getMaxParseConcurrency(d *plugin.QueryData) (int, error) {
+	config := GetConfig(d.Connection)
+	if config.MaxParseConcurrency == nil {
+		return runtime.NumCPU(), nil
+	}
+	if *config.MaxParseConcurrency < 1 {
+		return 0, fmt.Errorf("max_parse_concurrency must be greater than 0, got: %d", *config.MaxParseConcurrency)
+	}
+	return *config.MaxParseConcurrency, nil
+}
+
+// parseSlots bounds the number of files read and parsed at the same time for
+// each connection, across all the queries running against it.
+var parseSlots = struct {
+	sync.Mutex
+	byConnection map[string]chan struct{}
+}{byConnection: map[string]chan struct{}{}}
+
+// acquireParseSlot waits until a file can be read and parsed, and returns the
+// function releasing the slot.
+func acquireParseSlot(ctx context.Context, d *plugin.QueryData) (func(), error) {
+	size, err := getMaxParseConcurrency(d)
+	if err != nil {
+		return nil, err
+	}
+
+	parseSlots.Lock()
+	slots := parseSlots.byConnection[d.Connection.Name]
+	// The slots are replaced when the config changes, the files being parsed
+	// release their slot in the previous channel
+	if slots == nil || cap(slots) != size {
+		slots = make(chan struct{}, size)
+		parseSlots.byConnection[d.Connection.Name] = slots
+	}
+	parseSlots.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,248 @@
+package ansible
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ansibleTaskSections are the play keys holding a list of tasks, in the order
+// Ansible runs them.
+var ansibleTaskSections = []string{"pre_tasks", "tasks", "post_tasks", "handlers"}
+
+// ansibleBlockSections are the block keys holding a list of tasks.
+var ansibleBlockSections = []string{"block", "rescue", "always"}
+
+// ansibleTaskNode is a task found while walking the nodes of a playbook or a
+// tasks file.
+type ansibleTaskNode struct {
+	Node         *yaml.Node
+	PlaybookName string
+	// Section is the play key the task is declared under, e.g. tasks or
+	// handlers. It is empty for tasks files.
+	Section string
+}
+
+// walkAnsibleTaskNodes calls fn for every task of the document, including the
+// tasks nested in blocks. The top-level items of a playbook are plays, while
+// those of a tasks file (e.g. roles/x/tasks/main.yml) are tasks.
+func walkAnsibleTaskNodes(doc *yaml.Node, fn func(task ansibleTaskNode)) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, item := range root.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if !isAnsiblePlayNode(item) {
+			walkAnsibleTaskList(item, "", "", fn, true)
+			continue
+		}
+
+		var playbookName string
+		if name := mappingValue(item, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			playbookName = name.Value
+		}
+		for _, section := range ansibleTaskSections {
+			if tasks := mappingValue(item, section); tasks != nil {
+				walkAnsibleTaskList(tasks, playbookName, section, fn, false)
+			}
+		}
+	}
+}
+
+// walkAnsibleTaskList walks a list of tasks, or a single task if single is
+// set, recursing into blocks.
+func walkAnsibleTaskList(node *yaml.Node, playbookName string, section string, fn func(task ansibleTaskNode), single bool) {
+	tasks := []*yaml.Node{node}
+	if !single {
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		tasks = node.Content
+	}
+
+	for _, task := range tasks {
+		if task.Kind != yaml.MappingNode {
+			continue
+		}
+		isBlock := false
+		for _, key := range ansibleBlockSections {
+			if nested := mappingValue(task, key); nested != nil {
+				isBlock = true
+				walkAnsibleTaskList(nested, playbookName, section, fn, false)
+			}
+		}
+		if !isBlock {
+			fn(ansibleTaskNode{Node: task, PlaybookName: playbookName, Section: section})
+		}
+	}
+}
+
+// isAnsiblePlayNode reports whether a top-level mapping is a play, which
+// targets hosts or imports another playbook.
+func isAnsiblePlayNode(node *yaml.Node) bool {
+	for _, key := range []string{"hosts", "import_playbook", "ansible.builtin.import_playbook", "ansible.legacy.import_playbook"} {
+		if mappingValue(node, key) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// documentRoot returns the top-level node of a decoded document.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc == nil {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// mappingValue returns the value node of the key in a mapping node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingKey returns the key node of the key in a mapping node.
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// sourceLocation is the position of a play, task or inventory entry in its
+// file. Lines and columns start at 1.
+type sourceLocation struct {
+	EndLine     int
+	StartColumn int
+	StartLine   int
+}
+
+// yamlNodeLocation returns the location of a node in the lines of its file.
+// yaml.v3 only records where nodes start, so the node ends on the last line
+// indented deeper than its start column, which covers multi-line scalars.
+// Trailing blank and comment lines are left out.
+func yamlNodeLocation(node *yaml.Node, lines []string) sourceLocation {
+	end := lastNodeLine(node)
+	for end < len(lines) {
+		line := lines[end]
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && len(line)-len(strings.TrimLeft(line, " ")) < node.Column-1 {
+			break
+		}
+		end++
+	}
+	for end > node.Line {
+		trimmed := strings.TrimSpace(lines[end-1])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		end--
+	}
+
+	return sourceLocation{
+		EndLine:     end,
+		StartColumn: node.Column,
+		StartLine:   node.Line,
+	}
+}
+
+// lastNodeLine returns the greatest start line of the node and its children.
+func lastNodeLine(node *yaml.Node) int {
+	line := node.Line
+	for _, child := range node.Content {
+		if l := lastNodeLine(child); l > line {
+			line = l
+		}
+	}
+	return line
+}
+
+// yamlNodeSource returns the original text of a node, dedented to the start
+// column of the node so that it is valid YAML on its own.
+func yamlNodeSource(location sourceLocation, lines []string) string {
+	if location.StartLine < 1 || location.EndLine > len(lines) {
+		return ""
+	}
+
+	indent := location.StartColumn - 1
+	source := make([]string, 0, location.EndLine-location.StartLine+1)
+	for i, line := range lines[location.StartLine-1 : location.EndLine] {
+		line = strings.TrimRight(line, "\r")
+		if i == 0 {
+			// The first line may start with the `- ` of a sequence item
+			line = line[min(indent, len(line)):]
+		} else {
+			line = strings.TrimPrefix(line, strings.Repeat(" ", min(indent, len(line)-len(strings.TrimLeft(line, " ")))))
+		}
+		source = append(source, line)
+	}
+
+	return strings.Join(source, "\n")
+}
+
+// yamlNodeContentHash returns a SHA-256 hash of the decoded value of a node.
+// The value is encoded as JSON, which sorts map keys, so the hash ignores
+// comments, whitespace, quoting styles and key order.
+func yamlNodeContentHash(node *yaml.Node) (string, error) {
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(normalizeYAMLValue(value))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeYAMLValue converts the maps with non-string keys that yaml.v3
+// decodes, e.g. for integer keys, to maps that can be encoded as JSON.
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeYAMLValue(item)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeYAMLValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAMLValue(item)
+		}
+	}
+	return value
+}
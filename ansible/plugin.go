@@ -18,10 +18,34 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			NewInstance: ConfigInstance,
 		},
 		TableMap: map[string]*plugin.Table{
-			"ansible_group":    tableAnsibleGroup(ctx),
-			"ansible_host":     tableAnsibleHost(ctx),
-			"ansible_playbook": tableAnsiblePlaybook(ctx),
-			"ansible_task":     tableAnsibleTask(ctx),
+			"ansible_collection":         tableAnsibleCollection(ctx),
+			"ansible_fact":               tableAnsibleFact(ctx),
+			"ansible_fact_interface":     tableAnsibleFactInterface(ctx),
+			"ansible_fact_mount":         tableAnsibleFactMount(ctx),
+			"ansible_fact_package":       tableAnsibleFactPackage(ctx),
+			"ansible_fact_service":       tableAnsibleFactService(ctx),
+			"ansible_file":               tableAnsibleFile(ctx),
+			"ansible_file_error":         tableAnsibleFileError(ctx),
+			"ansible_group":              tableAnsibleGroup(ctx),
+			"ansible_host":               tableAnsibleHost(ctx),
+			"ansible_junit_testcase":     tableAnsibleJUnitTestCase(ctx),
+			"ansible_lint_finding":       tableAnsibleLintFinding(ctx),
+			"ansible_log_event":          tableAnsibleLogEvent(ctx),
+			"ansible_log_recap":          tableAnsibleLogRecap(ctx),
+			"ansible_module":             tableAnsibleModule(ctx),
+			"ansible_parse_metric":       tableAnsibleParseMetric(ctx),
+			"ansible_playbook":           tableAnsiblePlaybook(ctx),
+			"ansible_requirement":        tableAnsibleRequirement(ctx),
+			"ansible_retry_host":         tableAnsibleRetryHost(ctx),
+			"ansible_run":                tableAnsibleRun(ctx),
+			"ansible_run_host_stats":     tableAnsibleRunHostStats(ctx),
+			"ansible_run_task_result":    tableAnsibleRunTaskResult(ctx),
+			"ansible_task":               tableAnsibleTask(ctx),
+			"ansible_task_applicability": tableAnsibleTaskApplicability(ctx),
+			"ansible_template":           tableAnsibleTemplate(ctx),
+			"ansible_template_usage":     tableAnsibleTemplateUsage(ctx),
+			"ansible_variable_issue":     tableAnsibleVariableIssue(ctx),
+			"ansible_variable_reference": tableAnsibleVariableReference(ctx),
 		},
 	}
 
@@ -0,0 +1,186 @@
+package ansible
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
+)
+
+// ansibleQualFilter holds the quals of the optional key columns of the
+// ansible_playbook and ansible_task tables, so that rows that cannot match
+// are not streamed, and files that cannot match are not parsed.
+type ansibleQualFilter struct {
+	// become holds the values of the become quals, which must all match
+	become []bool
+	// strings maps a column to the accepted values of each of its quals, e.g.
+	// the values of an in list
+	strings map[string][][]string
+	// tags holds the tags that must all be in the tags column
+	tags []string
+}
+
+// newAnsibleQualFilter returns the filter for the quals of the query on the
+// become and tags columns, and on the given string columns.
+func newAnsibleQualFilter(d *plugin.QueryData, stringColumns ...string) ansibleQualFilter {
+	filter := ansibleQualFilter{strings: map[string][][]string{}}
+
+	for _, column := range stringColumns {
+		if d.Quals[column] == nil {
+			continue
+		}
+		for _, qual := range d.Quals[column].Quals {
+			if qual.Operator == quals.QualOperatorEqual {
+				filter.strings[column] = append(filter.strings[column], qualStringValues(qual))
+			}
+		}
+	}
+
+	if d.Quals["become"] != nil {
+		for _, qual := range d.Quals["become"].Quals {
+			if qual.Operator == quals.QualOperatorEqual {
+				filter.become = append(filter.become, qual.Value.GetBoolValue())
+			}
+		}
+	}
+
+	if d.Quals["tags"] != nil {
+		for _, qual := range d.Quals["tags"].Quals {
+			if qual.Operator == quals.QualOperatorJsonbExistsOne {
+				filter.tags = append(filter.tags, qual.Value.GetStringValue())
+			}
+		}
+	}
+
+	return filter
+}
+
+// qualStringValues returns the value of a qual, or the values of the list
+// for a qual such as `name in ('a', 'b')`.
+func qualStringValues(qual *quals.Qual) []string {
+	if list := qual.Value.GetListValue(); list != nil {
+		var values []string
+		for _, value := range list.Values {
+			values = append(values, value.GetStringValue())
+		}
+		return values
+	}
+	return []string{qual.Value.GetStringValue()}
+}
+
+// matchString reports whether the value of a string column matches its quals.
+func (f ansibleQualFilter) matchString(column string, value string) bool {
+	for _, values := range f.strings[column] {
+		matched := false
+		for _, v := range values {
+			if v == value {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// matchBecome reports whether the become column matches its quals. A
+// templated become is null, so it never matches.
+func (f ansibleQualFilter) matchBecome(become *bool) bool {
+	for _, value := range f.become {
+		if become == nil || *become != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTags reports whether the tags column has every tag of its quals.
+func (f ansibleQualFilter) matchTags(tags []string) bool {
+	for _, tag := range f.tags {
+		found := false
+		for _, t := range tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// scanWords returns the words a file must contain to have a matching row,
+// for a cheap check before parsing the file. The check must never skip a
+// matching file, so only the words that are always written as is in YAML
+// are returned, e.g. not the words of a value that may be quoted or escaped.
+func (f ansibleQualFilter) scanWords() []string {
+	var words []string
+	for column, columnQuals := range f.strings {
+		for _, values := range columnQuals {
+			// Any of the values of an in list may match
+			if len(values) != 1 {
+				continue
+			}
+			value := values[0]
+			// Short module names, e.g. apt, are resolved to ansible.builtin
+			if column == "module_fqcn" {
+				value = shortModuleName(value)
+			}
+			words = append(words, literalWords(value)...)
+		}
+	}
+	if len(f.become) > 0 {
+		words = append(words, "become")
+	}
+	for _, tag := range f.tags {
+		words = append(words, literalWords(tag)...)
+	}
+	return words
+}
+
+// literalWords splits a value on whitespace, which may be folded across lines
+// in YAML, and drops the words with characters that may be escaped.
+func literalWords(value string) []string {
+	var words []string
+	for _, word := range strings.Fields(value) {
+		escapable := strings.IndexFunc(word, func(r rune) bool {
+			return r == '"' || r == '\'' || r == '\\' || r > unicode.MaxASCII || !unicode.IsPrint(r)
+		})
+		if escapable == -1 {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// containsWords reports whether the content has every word.
+func containsWords(content []byte, words []string) bool {
+	for _, word := range words {
+		if !bytes.Contains(content, []byte(word)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPlay reports whether a play matches the quals of the ansible_playbook
+// table.
+func (f ansibleQualFilter) matchPlay(play AnsiblePlaybookInfo) bool {
+	hosts := ""
+	if play.Hosts.Value != nil {
+		hosts = *play.Hosts.Value
+	}
+	return f.matchString("name", play.Name) && f.matchString("hosts", hosts) && f.matchBecome(play.Become.Value) && f.matchTags(play.Tags.Value)
+}
+
+// matchTask reports whether a task matches the quals of the ansible_task
+// table.
+func (f ansibleQualFilter) matchTask(task AnsibleTask) bool {
+	return f.matchString("name", task.Name) && f.matchString("playbook_name", task.PlaybookName) && f.matchString("module_fqcn", task.ModuleFQCN) && f.matchBecome(task.Become.Value) && f.matchTags(task.Tags.Value)
+}
@@ -0,0 +1,121 @@
+package ansible
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ansibleRetryFilePaths returns the paths of the retry files that Ansible may
+// have written for a playbook: in the retry_files_save_path directory of
+// ansible.cfg if set, and next to the playbook otherwise.
+func ansibleRetryFilePaths(playbookPath string) []string {
+	name := strings.TrimSuffix(filepath.Base(playbookPath), filepath.Ext(playbookPath)) + ".retry"
+	paths := []string{filepath.Join(filepath.Dir(playbookPath), name)}
+	if dir := ansibleRetryFilesSavePath(filepath.Dir(playbookPath)); dir != "" && filepath.Join(dir, name) != paths[0] {
+		paths = append([]string{filepath.Join(dir, name)}, paths...)
+	}
+	return paths
+}
+
+// ansibleRetryFilesSavePath returns the retry_files_save_path setting of the
+// ansible.cfg used when running playbooks from the given directory, resolved
+// against the directory of the config file, or an empty string if not set.
+func ansibleRetryFilesSavePath(dir string) string {
+	path := findAnsibleConfigFile(dir)
+	if path == "" {
+		return ""
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	value := ansibleConfigValue(content, "defaults", "retry_files_save_path")
+	if value == "" {
+		return ""
+	}
+	value = os.ExpandEnv(value)
+	if strings.HasPrefix(value, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			value = filepath.Join(home, value[1:])
+		}
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(filepath.Dir(path), value)
+	}
+	return value
+}
+
+// findAnsibleConfigFile returns the config file Ansible reads, in the order it
+// looks for it: ANSIBLE_CONFIG, ansible.cfg in the current directory, which is
+// taken as the directory of the playbook, ~/.ansible.cfg and
+// /etc/ansible/ansible.cfg.
+func findAnsibleConfigFile(dir string) string {
+	candidates := []string{os.Getenv("ANSIBLE_CONFIG"), filepath.Join(dir, "ansible.cfg")}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".ansible.cfg"))
+	}
+	candidates = append(candidates, "/etc/ansible/ansible.cfg")
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// ansibleConfigValue returns the value of a key of an INI section of
+// ansible.cfg, or an empty string if not set.
+func ansibleConfigValue(content []byte, section string, key string) string {
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != section {
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 || strings.TrimSpace(line[:i]) != key {
+			continue
+		}
+		value := strings.TrimSpace(line[i+1:])
+		// Inline comments must follow a space
+		if j := strings.Index(value, " ;"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		}
+		if j := strings.Index(value, " #"); j >= 0 {
+			value = strings.TrimSpace(value[:j])
+		}
+		return strings.Trim(value, `"'`)
+	}
+	return ""
+}
+
+// parseAnsibleRetryFile returns the hosts listed in a retry file, one per
+// line, without duplicates.
+func parseAnsibleRetryFile(content []byte) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, line := range strings.Split(string(content), "\n") {
+		host := strings.TrimSpace(line)
+		if host == "" || seen[host] {
+			continue
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
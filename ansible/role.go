@@ -0,0 +1,126 @@
+package ansible
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+
+	filehelpers "github.com/turbot/go-kit/files"
+)
+
+// ansibleRoleFromPath returns the directory and name of the role holding a
+// file, e.g. roles/web for roles/web/tasks/main.yml, or empty strings if the
+// file is not in a role.
+func ansibleRoleFromPath(path string) (string, string) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	// The role directory is followed by at least a directory and a file
+	for i := len(parts) - 3; i >= 0; i-- {
+		if parts[i] == "roles" && parts[i+1] != "" {
+			dir := strings.Join(parts[:i+2], "/")
+			return filepath.FromSlash(dir), parts[i+1]
+		}
+	}
+	return "", ""
+}
+
+// listAnsibleRoleDirs returns the directories of the roles holding the given
+// files, and of the roles in a roles directory next to them, as Ansible looks
+// for the roles of a playbook.
+func listAnsibleRoleDirs(files []string) ([]string, error) {
+	seen := map[string]bool{}
+	var dirs []string
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, file := range files {
+		if dir, _ := ansibleRoleFromPath(file); dir != "" {
+			add(dir)
+		}
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(file), "roles", "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if filehelpers.DirectoryExists(match) {
+				add(match)
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	return dirs, nil
+}
+
+// listAnsibleTemplateFiles returns the Jinja2 templates, i.e. the .j2 files,
+// of the templates directories next to the given playbook files and in the
+// roles found from them.
+func listAnsibleTemplateFiles(files []string) ([]string, error) {
+	roleDirs, err := listAnsibleRoleDirs(files)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var templateDirs []string
+	for _, file := range files {
+		dir := filepath.Join(filepath.Dir(file), "templates")
+		if !seen[dir] {
+			seen[dir] = true
+			templateDirs = append(templateDirs, dir)
+		}
+	}
+	for _, dir := range roleDirs {
+		dir = filepath.Join(dir, "templates")
+		if !seen[dir] {
+			seen[dir] = true
+			templateDirs = append(templateDirs, dir)
+		}
+	}
+
+	var templates []string
+	for _, dir := range templateDirs {
+		if !filehelpers.DirectoryExists(dir) {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".j2") {
+				templates = append(templates, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(templates)
+
+	return templates, nil
+}
+
+// listAnsiblePlaybookFiles returns the files matched by the
+// playbook_file_paths config argument, ignoring the path qualifiers.
+func listAnsiblePlaybookFiles(d *plugin.QueryData) ([]string, error) {
+	var files []string
+	for _, path := range GetConfig(d.Connection).PlayBookFilePaths {
+		matches, err := d.GetSourceFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if !filehelpers.DirectoryExists(match) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
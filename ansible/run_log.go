@@ -0,0 +1,217 @@
+package ansible
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ansibleJSONCallbackOutput is the output of ansible-playbook with the json
+// stdout callback.
+type ansibleJSONCallbackOutput struct {
+	CustomStats map[string]interface{} `json:"custom_stats"`
+	Plays       []struct {
+		Play  ansibleJSONCallbackItem `json:"play"`
+		Tasks []struct {
+			Hosts map[string]map[string]interface{} `json:"hosts"`
+			Task  ansibleJSONCallbackItem           `json:"task"`
+		} `json:"tasks"`
+	} `json:"plays"`
+	Stats map[string]ansibleJSONCallbackStats `json:"stats"`
+}
+
+// ansibleJSONCallbackItem is a play or a task of the json callback output.
+type ansibleJSONCallbackItem struct {
+	Duration struct {
+		End   string `json:"end"`
+		Start string `json:"start"`
+	} `json:"duration"`
+	Name string `json:"name"`
+	// Path is the file and line of the play or task, e.g. site.yml:12
+	Path string `json:"path"`
+}
+
+type ansibleJSONCallbackStats struct {
+	Changed     int `json:"changed"`
+	Failures    int `json:"failures"`
+	Ignored     int `json:"ignored"`
+	Ok          int `json:"ok"`
+	Rescued     int `json:"rescued"`
+	Skipped     int `json:"skipped"`
+	Unreachable int `json:"unreachable"`
+}
+
+// ansibleRunLog is a run parsed from the json callback output, with its task
+// results and the stats of its hosts.
+type ansibleRunLog struct {
+	Err         error
+	HostStats   []AnsibleRunHostStatsInfo
+	Run         AnsibleRunInfo
+	TaskResults []AnsibleRunTaskResultInfo
+}
+
+// parseAnsibleRunLog parses the output of ansible-playbook with the json
+// stdout callback. The warnings and deprecation messages that Ansible may
+// print before the JSON document are skipped.
+func parseAnsibleRunLog(content []byte, path string) ansibleRunLog {
+	start := 0
+	if !bytes.HasPrefix(content, []byte("{")) {
+		start = bytes.Index(content, []byte("\n{"))
+		if start < 0 {
+			return ansibleRunLog{Err: errors.New("no JSON document found, the output may not use the json stdout callback")}
+		}
+		start++
+	}
+
+	var output ansibleJSONCallbackOutput
+	if err := json.NewDecoder(bytes.NewReader(content[start:])).Decode(&output); err != nil {
+		return ansibleRunLog{Err: err}
+	}
+
+	run := AnsibleRunInfo{CustomStats: output.CustomStats, HostCount: len(output.Stats), Path: path}
+	var results []AnsibleRunTaskResultInfo
+	for _, play := range output.Plays {
+		run.Plays = append(run.Plays, play.Play.Name)
+		if run.PlaybookPath == "" {
+			run.PlaybookPath, _ = splitAnsibleCallbackPath(play.Play.Path)
+		}
+		playStart, playEnd := parseAnsibleCallbackTime(play.Play.Duration.Start), parseAnsibleCallbackTime(play.Play.Duration.End)
+		if playStart != nil && (run.StartedAt == nil || playStart.Before(*run.StartedAt)) {
+			run.StartedAt = playStart
+		}
+		if playEnd != nil && (run.EndedAt == nil || playEnd.After(*run.EndedAt)) {
+			run.EndedAt = playEnd
+		}
+
+		for _, task := range play.Tasks {
+			run.TaskCount++
+
+			taskPath, taskLine := splitAnsibleCallbackPath(task.Task.Path)
+			taskName, role := task.Task.Name, ""
+			// The names of role tasks are prefixed with the role
+			if _, name := ansibleRoleFromPath(taskPath); name != "" && strings.HasPrefix(taskName, name+" : ") {
+				taskName, role = strings.TrimPrefix(taskName, name+" : "), name
+			}
+			taskStart, taskEnd := parseAnsibleCallbackTime(task.Task.Duration.Start), parseAnsibleCallbackTime(task.Task.Duration.End)
+
+			hosts := make([]string, 0, len(task.Hosts))
+			for host := range task.Hosts {
+				hosts = append(hosts, host)
+			}
+			sort.Strings(hosts)
+			for _, host := range hosts {
+				result := task.Hosts[host]
+				item := AnsibleRunTaskResultInfo{
+					Changed:      resultFlag(result, "changed"),
+					EndedAt:      taskEnd,
+					Failed:       resultFlag(result, "failed"),
+					Host:         host,
+					Path:         path,
+					PlaybookName: play.Play.Name,
+					Result:       result,
+					Role:         role,
+					Skipped:      resultFlag(result, "skipped"),
+					StartedAt:    taskStart,
+					TaskLine:     taskLine,
+					TaskName:     taskName,
+					TaskPath:     taskPath,
+					Unreachable:  resultFlag(result, "unreachable"),
+				}
+				item.Action, _ = result["action"].(string)
+				switch msg := result["msg"].(type) {
+				case nil:
+				case string:
+					item.Msg = msg
+				default:
+					if encoded, err := json.Marshal(msg); err == nil {
+						item.Msg = string(encoded)
+					}
+				}
+				if taskStart != nil && taskEnd != nil {
+					duration := taskEnd.Sub(*taskStart).Seconds()
+					item.Duration = &duration
+				}
+				results = append(results, item)
+			}
+		}
+	}
+	if run.StartedAt != nil && run.EndedAt != nil {
+		duration := run.EndedAt.Sub(*run.StartedAt).Seconds()
+		run.Duration = &duration
+	}
+
+	hosts := make([]string, 0, len(output.Stats))
+	for host := range output.Stats {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var stats []AnsibleRunHostStatsInfo
+	for _, host := range hosts {
+		s := output.Stats[host]
+		stats = append(stats, AnsibleRunHostStatsInfo{
+			Changed:     s.Changed,
+			Failures:    s.Failures,
+			Host:        host,
+			Ignored:     s.Ignored,
+			Ok:          s.Ok,
+			Path:        path,
+			Rescued:     s.Rescued,
+			Skipped:     s.Skipped,
+			Unreachable: s.Unreachable,
+		})
+		run.Changed += s.Changed
+		run.Failures += s.Failures
+		run.Ignored += s.Ignored
+		run.Ok += s.Ok
+		run.Rescued += s.Rescued
+		run.Skipped += s.Skipped
+		run.Unreachable += s.Unreachable
+	}
+	run.Success = run.Failures == 0 && run.Unreachable == 0
+
+	return ansibleRunLog{HostStats: stats, Run: run, TaskResults: results}
+}
+
+// splitAnsibleCallbackPath splits the path of a play or task, e.g.
+// /src/site.yml:12, into the file and the line.
+func splitAnsibleCallbackPath(path string) (string, int) {
+	i := strings.LastIndex(path, ":")
+	if i < 0 {
+		return path, 0
+	}
+	line, err := strconv.Atoi(path[i+1:])
+	if err != nil {
+		return path, 0
+	}
+	return path[:i], line
+}
+
+// parseAnsibleCallbackTime parses the times of the json callback, which are
+// in UTC, e.g. 2024-01-02T10:00:00.123456Z.
+func parseAnsibleCallbackTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// resultFlag returns a boolean of a task result. Loops report the flags of
+// their items as well as of the whole task, which is the one returned.
+func resultFlag(result map[string]interface{}, key string) bool {
+	switch value := result[key].(type) {
+	case bool:
+		return value
+	case string:
+		b, _ := strconv.ParseBool(value)
+		return b
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package ansible
+
+import (
+	"testing"
+)
+
+const testAnsibleRunLog = `[WARNING]: provided hosts list is empty
+{
+  "custom_stats": {},
+  "plays": [
+    {
+      "play": {
+        "duration": {"start": "2024-01-02T10:00:00.000000Z", "end": "2024-01-02T10:00:30.000000Z"},
+        "name": "Web servers",
+        "path": "/src/site.yml:1"
+      },
+      "tasks": [
+        {
+          "hosts": {
+            "web2": {"action": "apt", "changed": false, "failed": true, "msg": "No package matching 'nginx'"},
+            "web1": {"action": "apt", "changed": true, "msg": {"lines": 2}}
+          },
+          "task": {
+            "duration": {"start": "2024-01-02T10:00:01.000000Z", "end": "2024-01-02T10:00:11.500000Z"},
+            "name": "nginx : Install nginx",
+            "path": "/src/roles/nginx/tasks/main.yml:2"
+          }
+        }
+      ]
+    }
+  ],
+  "stats": {
+    "web1": {"changed": 1, "failures": 0, "ignored": 0, "ok": 2, "rescued": 0, "skipped": 0, "unreachable": 0},
+    "web2": {"changed": 0, "failures": 1, "ignored": 0, "ok": 1, "rescued": 0, "skipped": 0, "unreachable": 0}
+  }
+}
+`
+
+func TestParseAnsibleRunLog(t *testing.T) {
+	log := parseAnsibleRunLog([]byte(testAnsibleRunLog), "/logs/run.json")
+	if log.Err != nil {
+		t.Fatal(log.Err)
+	}
+
+	run := log.Run
+	if run.PlaybookPath != "/src/site.yml" || run.HostCount != 2 || run.TaskCount != 1 || run.Success {
+		t.Errorf("got run %+v", run)
+	}
+	if run.Ok != 3 || run.Changed != 1 || run.Failures != 1 {
+		t.Errorf("got totals ok=%d changed=%d failures=%d", run.Ok, run.Changed, run.Failures)
+	}
+	if run.Duration == nil || *run.Duration != 30 {
+		t.Errorf("got duration %v, want 30", run.Duration)
+	}
+
+	if len(log.TaskResults) != 2 {
+		t.Fatalf("got %d task results, want 2", len(log.TaskResults))
+	}
+	// Results are sorted by host
+	web1, web2 := log.TaskResults[0], log.TaskResults[1]
+	if web1.Host != "web1" || !web1.Changed || web1.Failed || web1.Msg != `{"lines":2}` {
+		t.Errorf("got result %+v", web1)
+	}
+	if web2.Host != "web2" || web2.Changed || !web2.Failed || web2.Msg != "No package matching 'nginx'" {
+		t.Errorf("got result %+v", web2)
+	}
+	if web1.Role != "nginx" || web1.TaskName != "Install nginx" || web1.TaskPath != "/src/roles/nginx/tasks/main.yml" || web1.TaskLine != 2 || web1.Action != "apt" {
+		t.Errorf("got task %+v", web1)
+	}
+	if web1.Duration == nil || *web1.Duration != 10.5 {
+		t.Errorf("got task duration %v, want 10.5", web1.Duration)
+	}
+
+	if len(log.HostStats) != 2 || log.HostStats[1].Host != "web2" || log.HostStats[1].Failures != 1 {
+		t.Errorf("got host stats %+v", log.HostStats)
+	}
+}
+
+func TestParseAnsibleRunLogErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not JSON", content: "PLAY [all] ****\n"},
+		{name: "truncated JSON", content: "{\"plays\": ["},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if log := parseAnsibleRunLog([]byte(test.content), "/logs/run.json"); log.Err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func TestSplitAnsibleCallbackPath(t *testing.T) {
+	tests := []struct {
+		path, file string
+		line       int
+	}{
+		{path: "/src/site.yml:12", file: "/src/site.yml", line: 12},
+		{path: "/src/site.yml", file: "/src/site.yml"},
+		{path: "C:/src/site.yml", file: "C:/src/site.yml"},
+	}
+	for _, test := range tests {
+		file, line := splitAnsibleCallbackPath(test.path)
+		if file != test.file || line != test.line {
+			t.Errorf("%q: got %q and %d, want %q and %d", test.path, file, line, test.file, test.line)
+		}
+	}
+}
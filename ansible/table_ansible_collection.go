@@ -0,0 +1,342 @@
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	filehelpers "github.com/turbot/go-kit/files"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleCollection(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_collection",
+		Description: "Ansible collections installed in the configured collections paths",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleCollectionPaths,
+			Hydrate:       listAnsibleCollections,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "namespace",
+				Description: "The namespace of the collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "fqcn",
+				Description: "The fully qualified collection name, i.e. <namespace>.<name>.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("FQCN"),
+			},
+			{
+				Name:        "version",
+				Description: "The version of the collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "description",
+				Description: "A short summary description of the collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "repository",
+				Description: "The URL of the originating SCM repository.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "documentation",
+				Description: "The URL to any online docs.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "homepage",
+				Description: "The URL to the homepage of the collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "issues",
+				Description: "The URL to the collection issue tracker.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "metadata_file",
+				Description: "The file the metadata was read from. Possible values are: MANIFEST.json, galaxy.yml.",
+				Type:        proto.ColumnType_STRING,
+			},
+
+			// JSON columns
+			{
+				Name:        "authors",
+				Description: "A list of the collection's content authors.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "dependencies",
+				Description: "A map of the collections this collection depends on, and their version range.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "license",
+				Description: "A list of SPDX license identifiers, or the license file when no identifier is given.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "tags",
+				Description: "A list of tags applied to the collection.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "modules",
+				Description: "A list of the fully qualified names of the modules provided by the collection.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "plugins",
+				Description: "A map of plugin type (e.g. filter, lookup, callback) to the fully qualified names of the plugin files provided by the collection.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the collection directory.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleCollectionInfo struct {
+	Authors       []string
+	Dependencies  map[string]interface{}
+	Description   string
+	Documentation string
+	FQCN          string
+	Homepage      string
+	Issues        string
+	License       []string
+	MetadataFile  string
+	Modules       []string
+	Name          string
+	Namespace     string
+	Path          string
+	Plugins       map[string][]string
+	Repository    string
+	Tags          []string
+	Version       string
+}
+
+// ansibleCollectionMetadata holds the keys shared by galaxy.yml and the
+// collection_info section of MANIFEST.json.
+type ansibleCollectionMetadata struct {
+	Authors       []string               `json:"authors" yaml:"authors"`
+	Dependencies  map[string]interface{} `json:"dependencies" yaml:"dependencies"`
+	Description   string                 `json:"description" yaml:"description"`
+	Documentation string                 `json:"documentation" yaml:"documentation"`
+	Homepage      string                 `json:"homepage" yaml:"homepage"`
+	Issues        string                 `json:"issues" yaml:"issues"`
+	License       interface{}            `json:"license" yaml:"license"`
+	LicenseFile   string                 `json:"license_file" yaml:"license_file"`
+	Name          string                 `json:"name" yaml:"name"`
+	Namespace     string                 `json:"namespace" yaml:"namespace"`
+	Repository    string                 `json:"repository" yaml:"repository"`
+	Tags          []string               `json:"tags" yaml:"tags"`
+	Version       string                 `json:"version" yaml:"version"`
+}
+
+//// LIST FUNCTION
+
+func listAnsibleCollections(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the collections paths
+	// or available by the optional key column
+	path := h.Item.(filePath).Path
+
+	collection, err := readAnsibleCollection(path)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_collection.listAnsibleCollections", "read_collection_error", err, "path", path)
+		return nil, err
+	}
+
+	d.StreamListItem(ctx, collection)
+
+	return nil, nil
+}
+
+// readAnsibleCollection reads the collection metadata from MANIFEST.json, which
+// is written by ansible-galaxy on install, falling back to galaxy.yml for
+// collections used straight from source.
+func readAnsibleCollection(dir string) (*AnsibleCollectionInfo, error) {
+	var metadata ansibleCollectionMetadata
+	metadataFile := "MANIFEST.json"
+
+	content, err := os.ReadFile(filepath.Join(dir, metadataFile))
+	if err == nil {
+		var manifest struct {
+			CollectionInfo ansibleCollectionMetadata `json:"collection_info"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal file content %s: %v", filepath.Join(dir, metadataFile), err)
+		}
+		metadata = manifest.CollectionInfo
+	} else {
+		metadataFile = "galaxy.yml"
+		content, err = os.ReadFile(filepath.Join(dir, metadataFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %v", filepath.Join(dir, metadataFile), err)
+		}
+		if err := yaml.Unmarshal(content, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal file content %s: %v", filepath.Join(dir, metadataFile), err)
+		}
+	}
+
+	// The directory layout is authoritative for how Ansible resolves the
+	// collection, so fall back to it if the metadata is incomplete
+	if metadata.Namespace == "" {
+		metadata.Namespace = filepath.Base(filepath.Dir(dir))
+	}
+	if metadata.Name == "" {
+		metadata.Name = filepath.Base(dir)
+	}
+	fqcn := metadata.Namespace + "." + metadata.Name
+
+	var license []string
+	switch v := metadata.License.(type) {
+	case string:
+		license = []string{v}
+	case []interface{}:
+		for _, l := range v {
+			license = append(license, fmt.Sprint(l))
+		}
+	}
+	if len(license) == 0 && metadata.LicenseFile != "" {
+		license = []string{metadata.LicenseFile}
+	}
+
+	plugins, err := listAnsibleCollectionPlugins(dir, fqcn)
+	if err != nil {
+		return nil, err
+	}
+	modules := plugins["modules"]
+	delete(plugins, "modules")
+
+	return &AnsibleCollectionInfo{
+		Authors:       metadata.Authors,
+		Dependencies:  metadata.Dependencies,
+		Description:   metadata.Description,
+		Documentation: metadata.Documentation,
+		FQCN:          fqcn,
+		Homepage:      metadata.Homepage,
+		Issues:        metadata.Issues,
+		License:       license,
+		MetadataFile:  metadataFile,
+		Modules:       modules,
+		Name:          metadata.Name,
+		Namespace:     metadata.Namespace,
+		Path:          dir,
+		Plugins:       plugins,
+		Repository:    metadata.Repository,
+		Tags:          metadata.Tags,
+		Version:       metadata.Version,
+	}, nil
+}
+
+// listAnsibleCollectionPlugins returns the fully qualified names of the
+// plugins in each `plugins/<type>` directory of the collection.
+func listAnsibleCollectionPlugins(dir string, fqcn string) (map[string][]string, error) {
+	plugins := map[string][]string{}
+
+	types, err := os.ReadDir(filepath.Join(dir, "plugins"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plugins, nil
+		}
+		return nil, err
+	}
+
+	for _, pluginType := range types {
+		// Shared code and documentation fragments are not callable plugins
+		if !pluginType.IsDir() || pluginType.Name() == "module_utils" || pluginType.Name() == "doc_fragments" || strings.HasPrefix(pluginType.Name(), "_") {
+			continue
+		}
+
+		files, err := listAnsibleCollectionPluginFiles(dir, fqcn, pluginType.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		plugins[pluginType.Name()] = names
+	}
+
+	return plugins, nil
+}
+
+// listAnsibleCollectionPluginFiles maps the fully qualified name of each plugin
+// of the given type to its file. Plugins in sub directories are named after
+// the directory, e.g. `ns.coll.subdir.module`.
+func listAnsibleCollectionPluginFiles(dir string, fqcn string, pluginType string) (map[string]string, error) {
+	files := map[string]string{}
+
+	typeDir := filepath.Join(dir, "plugins", pluginType)
+	if !filehelpers.DirectoryExists(typeDir) {
+		return files, nil
+	}
+
+	err := filepath.WalkDir(typeDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == "__pycache__" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Modules may also be written in PowerShell for Windows hosts, in
+		// which case the documentation lives in a .py file of the same name
+		ext := filepath.Ext(entry.Name())
+		if (ext != ".py" && ext != ".ps1") || strings.HasPrefix(entry.Name(), "__") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(typeDir, strings.TrimSuffix(path, ext))
+		if err != nil {
+			return err
+		}
+		name := fqcn + "." + strings.ReplaceAll(rel, string(filepath.Separator), ".")
+		if _, exists := files[name]; !exists || ext == ".py" {
+			files[name] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
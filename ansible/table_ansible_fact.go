@@ -0,0 +1,268 @@
+package ansible
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFact(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_fact",
+		Description: "Facts gathered by Ansible for each host, read from a fact cache",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFactCacheFilePaths,
+			Hydrate:       listAnsibleFacts,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "host",
+				Description: "The name of the host, which is the name of its fact cache file without the .json, .yml or .yaml extension.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "timestamp",
+				Description: "Time when the facts were collected, i.e. the modification time of the fact cache file.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "distribution",
+				Description: "The name of the operating system distribution, e.g. Ubuntu.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "distribution_version",
+				Description: "The version of the operating system distribution, e.g. 22.04.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "kernel",
+				Description: "The version of the kernel.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "architecture",
+				Description: "The architecture of the host, e.g. x86_64.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "memtotal_mb",
+				Description: "The total memory of the host, in megabytes.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MemtotalMB"),
+			},
+			{
+				Name:        "processor_vcpus",
+				Description: "The number of virtual CPUs of the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("ProcessorVcpus"),
+			},
+			{
+				Name:        "default_ipv4_address",
+				Description: "The IPv4 address of the interface of the default route.",
+				Type:        proto.ColumnType_INET,
+				Transform:   transform.FromField("DefaultIPv4Address").NullIfZero(),
+			},
+			{
+				Name:        "facts",
+				Description: "All the facts of the host, as cached.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the fact cache file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFactInfo struct {
+	Architecture        string
+	DefaultIPv4Address  string
+	Distribution        string
+	DistributionVersion string
+	Facts               map[string]interface{}
+	Host                string
+	Kernel              string
+	MemtotalMB          *int64
+	Path                string
+	ProcessorVcpus      *int64
+	Timestamp           time.Time
+}
+
+// ansibleFactCache is the content of a fact cache file.
+type ansibleFactCache struct {
+	Err   error
+	Facts map[string]interface{}
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFacts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	host, facts, err := readAnsibleFactCache(ctx, d, file, "ansible_fact.listAnsibleFacts")
+	if err != nil || facts == nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_fact.listAnsibleFacts", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	item := AnsibleFactInfo{
+		Architecture:        factString(facts, "architecture"),
+		Distribution:        factString(facts, "distribution"),
+		DistributionVersion: factString(facts, "distribution_version"),
+		Facts:               facts,
+		Host:                host,
+		Kernel:              factString(facts, "kernel"),
+		MemtotalMB:          factInt(facts, "memtotal_mb"),
+		Path:                path,
+		ProcessorVcpus:      factInt(facts, "processor_vcpus"),
+		Timestamp:           info.ModTime(),
+	}
+	if defaultIPv4, ok := lookupFact(facts, "default_ipv4").(map[string]interface{}); ok {
+		item.DefaultIPv4Address, _ = defaultIPv4["address"].(string)
+	}
+	d.StreamListItem(ctx, item)
+
+	return nil, nil
+}
+
+// readAnsibleFactCache returns the host of a fact cache file and its facts.
+// The facts are nil if the file is skipped, either because the host is not
+// the one requested through the qualifier or because it cannot be parsed.
+func readAnsibleFactCache(ctx context.Context, d *plugin.QueryData, file filePath, function string) (string, map[string]interface{}, error) {
+	path := file.Path
+
+	// Skip the files of the other hosts before reading them
+	host := ansibleFactCacheHost(path)
+	quals := d.EqualsQuals
+	if quals["host"] != nil && quals["host"].GetStringValue() != host {
+		return host, nil, nil
+	}
+
+	cache, err := getAnsibleFactCache(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error(function, "file_error", err, "path", path)
+		return host, nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	if cache.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return host, nil, handleParseErrors(ctx, d, function, path, []ansibleParseError{newAnsibleParseError(cache.Err, nil)})
+	}
+
+	return host, cache.Facts, nil
+}
+
+// ansibleFactCacheHost returns the host of a fact cache file, which is named
+// after it, with the extension of the YAML and JSON files written by some
+// cache plugins removed.
+func ansibleFactCacheHost(path string) string {
+	name := filepath.Base(path)
+	for _, extension := range []string{".json", ".yml", ".yaml"} {
+		if strings.HasSuffix(name, extension) && len(name) > len(extension) {
+			return strings.TrimSuffix(name, extension)
+		}
+	}
+	return name
+}
+
+// parseAnsibleFactCache parses a fact cache file, written in JSON by the
+// jsonfile plugin or in YAML by the yaml plugin. The facts of the host are at
+// the top level, while the output of the setup module holds them under
+// ansible_facts.
+func parseAnsibleFactCache(content []byte) ansibleFactCache {
+	var facts map[string]interface{}
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(content, &facts); err != nil {
+			return ansibleFactCache{Err: err}
+		}
+	} else {
+		if err := yaml.Unmarshal(content, &facts); err != nil {
+			return ansibleFactCache{Err: err}
+		}
+		facts, _ = normalizeYAMLValue(facts).(map[string]interface{})
+	}
+	if nested, ok := facts["ansible_facts"].(map[string]interface{}); ok {
+		facts = nested
+	}
+	return ansibleFactCache{Facts: facts}
+}
+
+// lookupFact returns a fact, with or without the ansible_ prefix, as the
+// facts cached by Ansible are prefixed while those under ansible_facts may
+// not be.
+func lookupFact(facts map[string]interface{}, name string) interface{} {
+	if value, ok := facts["ansible_"+name]; ok {
+		return value
+	}
+	return facts[name]
+}
+
+func factString(facts map[string]interface{}, name string) string {
+	return factStringValue(lookupFact(facts, name))
+}
+
+func factInt(facts map[string]interface{}, name string) *int64 {
+	return factIntValue(lookupFact(facts, name))
+}
+
+// factStringValue returns a string fact, or a number as a string, e.g. a
+// version that a YAML cache holds as a number.
+func factStringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, int:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// factIntValue returns an integer fact, decoded as a float64 from JSON and as
+// an int from YAML.
+func factIntValue(value interface{}) *int64 {
+	var i int64
+	switch v := value.(type) {
+	case float64:
+		i = int64(v)
+	case int:
+		i = int64(v)
+	case string:
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil
+		}
+		i = parsed
+	default:
+		return nil
+	}
+	return &i
+}
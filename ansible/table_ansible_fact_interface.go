@@ -0,0 +1,170 @@
+package ansible
+
+import (
+	"context"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFactInterface(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_fact_interface",
+		Description: "Network interfaces of each host, from the facts gathered by the setup module",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFactCacheFilePaths,
+			Hydrate:       listAnsibleFactInterfaces,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the interface, e.g. eth0.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "active",
+				Description: "True if the interface is up.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Active"),
+			},
+			{
+				Name:        "type",
+				Description: "The type of the interface, e.g. ether, loopback or bridge.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "macaddress",
+				Description: "The MAC address of the interface.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "mtu",
+				Description: "The MTU of the interface.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MTU"),
+			},
+			{
+				Name:        "speed",
+				Description: "The speed of the interface, in Mb/s.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Speed"),
+			},
+			{
+				Name:        "module",
+				Description: "The kernel module of the interface, e.g. virtio_net.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "ipv4_address",
+				Description: "The primary IPv4 address of the interface.",
+				Type:        proto.ColumnType_INET,
+				Transform:   transform.FromField("IPv4Address").NullIfZero(),
+			},
+			{
+				Name:        "ipv4_netmask",
+				Description: "The netmask of the primary IPv4 address.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("IPv4Netmask").NullIfZero(),
+			},
+			{
+				Name:        "ipv4_network",
+				Description: "The network of the primary IPv4 address.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("IPv4Network").NullIfZero(),
+			},
+			{
+				Name:        "ipv4_secondaries",
+				Description: "The secondary IPv4 addresses of the interface, with their address, netmask and network.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IPv4Secondaries"),
+			},
+			{
+				Name:        "ipv6",
+				Description: "The IPv6 addresses of the interface, with their address, prefix and scope.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IPv6"),
+			},
+			{
+				Name:        "path",
+				Description: "Path to the fact cache file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFactInterfaceInfo struct {
+	Active          *bool
+	Host            string
+	IPv4Address     string
+	IPv4Netmask     string
+	IPv4Network     string
+	IPv4Secondaries []interface{}
+	IPv6            []interface{}
+	Macaddress      string
+	Module          string
+	MTU             *int64
+	Name            string
+	Path            string
+	Speed           *int64
+	Type            string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFactInterfaces(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	host, facts, err := readAnsibleFactCache(ctx, d, file, "ansible_fact_interface.listAnsibleFactInterfaces")
+	if err != nil || facts == nil {
+		return nil, err
+	}
+
+	// The interfaces fact only lists their names, while the facts of each
+	// interface are named after it, with dashes and colons replaced
+	names, _ := lookupFact(facts, "interfaces").([]interface{})
+	replacer := strings.NewReplacer("-", "_", ":", "_")
+	for _, value := range names {
+		name := factStringValue(value)
+		if name == "" {
+			continue
+		}
+		item := AnsibleFactInterfaceInfo{Host: host, Name: name, Path: file.Path}
+
+		if details, ok := lookupFact(facts, replacer.Replace(name)).(map[string]interface{}); ok {
+			if active, ok := details["active"].(bool); ok {
+				item.Active = &active
+			}
+			item.Macaddress = factStringValue(details["macaddress"])
+			item.Module = factStringValue(details["module"])
+			item.MTU = factIntValue(details["mtu"])
+			item.Speed = factIntValue(details["speed"])
+			item.Type = factStringValue(details["type"])
+			if ipv4, ok := details["ipv4"].(map[string]interface{}); ok {
+				item.IPv4Address = factStringValue(ipv4["address"])
+				item.IPv4Netmask = factStringValue(ipv4["netmask"])
+				item.IPv4Network = factStringValue(ipv4["network"])
+			}
+			item.IPv4Secondaries, _ = details["ipv4_secondaries"].([]interface{})
+			item.IPv6, _ = details["ipv6"].([]interface{})
+		}
+		d.StreamListItem(ctx, item)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,143 @@
+package ansible
+
+import (
+	"context"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFactMount(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_fact_mount",
+		Description: "Mounted filesystems of each host, from the facts gathered by the setup module",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFactCacheFilePaths,
+			Hydrate:       listAnsibleFactMounts,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "mount",
+				Description: "The mount point, e.g. /var.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "device",
+				Description: "The device mounted, e.g. /dev/sda1.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "fstype",
+				Description: "The type of the filesystem, e.g. ext4 or tmpfs.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "options",
+				Description: "The mount options, e.g. [\"rw\", \"nosuid\", \"nodev\"].",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "size_total",
+				Description: "The size of the filesystem, in bytes.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("SizeTotal"),
+			},
+			{
+				Name:        "size_available",
+				Description: "The space available on the filesystem, in bytes.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("SizeAvailable"),
+			},
+			{
+				Name:        "inode_total",
+				Description: "The number of inodes of the filesystem.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("InodeTotal"),
+			},
+			{
+				Name:        "inode_available",
+				Description: "The number of inodes available on the filesystem.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("InodeAvailable"),
+			},
+			{
+				Name:        "uuid",
+				Description: "The UUID of the filesystem, if any.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("UUID").NullIfEqual("N/A").NullIfZero(),
+			},
+			{
+				Name:        "path",
+				Description: "Path to the fact cache file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFactMountInfo struct {
+	Device         string
+	Fstype         string
+	Host           string
+	InodeAvailable *int64
+	InodeTotal     *int64
+	Mount          string
+	Options        []string
+	Path           string
+	SizeAvailable  *int64
+	SizeTotal      *int64
+	UUID           string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFactMounts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	host, facts, err := readAnsibleFactCache(ctx, d, file, "ansible_fact_mount.listAnsibleFactMounts")
+	if err != nil || facts == nil {
+		return nil, err
+	}
+
+	mounts, _ := lookupFact(facts, "mounts").([]interface{})
+	for _, mount := range mounts {
+		item, ok := mount.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var options []string
+		if value := factStringValue(item["options"]); value != "" {
+			options = strings.Split(value, ",")
+		}
+		d.StreamListItem(ctx, AnsibleFactMountInfo{
+			Device:         factStringValue(item["device"]),
+			Fstype:         factStringValue(item["fstype"]),
+			Host:           host,
+			InodeAvailable: factIntValue(item["inode_available"]),
+			InodeTotal:     factIntValue(item["inode_total"]),
+			Mount:          factStringValue(item["mount"]),
+			Options:        options,
+			Path:           file.Path,
+			SizeAvailable:  factIntValue(item["size_available"]),
+			SizeTotal:      factIntValue(item["size_total"]),
+			UUID:           factStringValue(item["uuid"]),
+		})
+	}
+
+	return nil, nil
+}
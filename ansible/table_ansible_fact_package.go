@@ -0,0 +1,129 @@
+package ansible
+
+import (
+	"context"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFactPackage(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_fact_package",
+		Description: "Packages installed on each host, from the facts gathered by the package_facts module",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFactCacheFilePaths,
+			Hydrate:       listAnsibleFactPackages,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+				{Name: "name", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the package.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "version",
+				Description: "The version of the package.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "release",
+				Description: "The release of the package, e.g. 1.el9 for RPM packages.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "epoch",
+				Description: "The epoch of the package, if any.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Epoch"),
+			},
+			{
+				Name:        "arch",
+				Description: "The architecture of the package, e.g. amd64 or x86_64.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source",
+				Description: "The package manager the package was found with, e.g. apt or rpm.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the fact cache file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFactPackageInfo struct {
+	Arch    string
+	Epoch   *int64
+	Host    string
+	Name    string
+	Path    string
+	Release string
+	Source  string
+	Version string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFactPackages(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	host, facts, err := readAnsibleFactCache(ctx, d, file, "ansible_fact_package.listAnsibleFactPackages")
+	if err != nil || facts == nil {
+		return nil, err
+	}
+
+	// The packages are listed by name, with every installed version of each
+	packages, _ := lookupFact(facts, "packages").(map[string]interface{})
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quals := d.EqualsQuals
+	for _, name := range names {
+		if quals["name"] != nil && quals["name"].GetStringValue() != name {
+			continue
+		}
+		versions, _ := packages[name].([]interface{})
+		for _, version := range versions {
+			item, ok := version.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			d.StreamListItem(ctx, AnsibleFactPackageInfo{
+				Arch:    factStringValue(item["arch"]),
+				Epoch:   factIntValue(item["epoch"]),
+				Host:    host,
+				Name:    name,
+				Path:    file.Path,
+				Release: factStringValue(item["release"]),
+				Source:  factStringValue(item["source"]),
+				Version: factStringValue(item["version"]),
+			})
+		}
+	}
+
+	return nil, nil
+}
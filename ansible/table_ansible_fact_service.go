@@ -0,0 +1,109 @@
+package ansible
+
+import (
+	"context"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFactService(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_fact_service",
+		Description: "Services of each host, from the facts gathered by the service_facts module",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFactCacheFilePaths,
+			Hydrate:       listAnsibleFactServices,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+				{Name: "name", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the service, e.g. sshd.service.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "state",
+				Description: "The state of the service, e.g. running or stopped.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "status",
+				Description: "Whether the service starts at boot, e.g. enabled, disabled or static.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source",
+				Description: "The service manager the service was found with, e.g. systemd or sysv.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the fact cache file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFactServiceInfo struct {
+	Host   string
+	Name   string
+	Path   string
+	Source string
+	State  string
+	Status string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFactServices(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	host, facts, err := readAnsibleFactCache(ctx, d, file, "ansible_fact_service.listAnsibleFactServices")
+	if err != nil || facts == nil {
+		return nil, err
+	}
+
+	services, _ := lookupFact(facts, "services").(map[string]interface{})
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quals := d.EqualsQuals
+	for _, name := range names {
+		if quals["name"] != nil && quals["name"].GetStringValue() != name {
+			continue
+		}
+		service, ok := services[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		d.StreamListItem(ctx, AnsibleFactServiceInfo{
+			Host:   host,
+			Name:   name,
+			Path:   file.Path,
+			Source: factStringValue(service["source"]),
+			State:  factStringValue(service["state"]),
+			Status: factStringValue(service["status"]),
+		})
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,89 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFile(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_file",
+		Description: "Files matched by the configured file paths, classified by the kind of Ansible content they hold",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleUniqueFilePaths,
+			Hydrate:       listAnsibleFiles,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "kind",
+				Description: "The detected kind of the file. Possible values are: playbook, tasks, vars, template, inventory, requirements, role_meta, galaxy, fact_cache, run_log, log, junit_report, unknown.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "confidence",
+				Description: "How reliable the detected kind is. Possible values are: high, when detected from a well known file name or directory or from the config argument matching the file, medium, when detected from distinctive content, and low otherwise.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "reason",
+				Description: "Why the file was detected as this kind.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFileInfo struct {
+	Confidence string
+	Kind       string
+	Path       string
+	Reason     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFiles(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	// The files written by Ansible runs are neither YAML nor INI
+	if argument, ok := ansibleOutputFileKinds[file.Kind]; ok {
+		d.StreamListItem(ctx, AnsibleFileInfo{
+			Confidence: confidenceHigh,
+			Kind:       file.Kind,
+			Path:       path,
+			Reason:     "file is matched by the " + argument + " config argument",
+		})
+		return nil, nil
+	}
+
+	classification, err := getAnsibleFileClassification(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_file.listAnsibleFiles", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	d.StreamListItem(ctx, AnsibleFileInfo{
+		Confidence: classification.Confidence,
+		Kind:       classification.Kind,
+		Path:       path,
+		Reason:     classification.Reason,
+	})
+
+	return nil, nil
+}
@@ -0,0 +1,172 @@
+package ansible
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleFileError(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_file_error",
+		Description: "Problems found while reading or parsing the configured playbook, inventory, requirements, fact cache, log and JUnit report files",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleFilePaths,
+			Hydrate:       listAnsibleFileErrors,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "file_kind",
+				Description: "The kind of the file, from the config argument matching it. Possible values are: playbook, inventory, requirements, fact_cache, run_log, log, junit_report.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line of the problem in the file, starting at 1. Null if the problem is not located.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "message",
+				Description: "The message describing the problem.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleFileErrorInfo struct {
+	FileKind string
+	Line     int
+	Message  string
+	Path     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleFileErrors(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	// Problems are only recorded on request, since the other tables fail or
+	// silently skip them otherwise
+	mode, err := getParseErrorMode(d)
+	if err != nil {
+		return nil, err
+	}
+	if mode != parseErrorRecord {
+		return nil, nil
+	}
+
+	for _, parseError := range parseAnsibleFileErrors(ctx, d, file) {
+		d.StreamListItem(ctx, AnsibleFileErrorInfo{
+			FileKind: file.Kind,
+			Line:     parseError.Line,
+			Message:  parseError.Message,
+			Path:     file.Path,
+		})
+	}
+
+	return nil, nil
+}
+
+// parseAnsibleFileErrors parses a file the way the tables listing its
+// content do, and returns every problem found.
+func parseAnsibleFileErrors(ctx context.Context, d *plugin.QueryData, file filePath) []ansibleParseError {
+	switch file.Kind {
+	case fileKindPlaybook:
+		// The tables listing the content of playbooks and tasks files skip
+		// the other files
+		classification, err := getAnsibleFileClassification(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if !classification.isPlaybookCandidate() && classification.Kind != fileKindTasks {
+			return nil
+		}
+
+		// Tasks files only hold tasks
+		var parseErrors []ansibleParseError
+		if classification.isPlaybookCandidate() {
+			_, parseErrors, err = getAnsiblePlays(ctx, d, file)
+			if err != nil {
+				return []ansibleParseError{{Message: err.Error()}}
+			}
+		}
+		_, taskErrors, err := getAnsibleTasks(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+
+		// A syntax error is found by both
+		seen := map[ansibleParseError]bool{}
+		for _, parseError := range parseErrors {
+			seen[parseError] = true
+		}
+		for _, parseError := range taskErrors {
+			if !seen[parseError] {
+				parseErrors = append(parseErrors, parseError)
+			}
+		}
+		return parseErrors
+	case fileKindInventory:
+		inventory, err := getAnsibleInventory(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if inventory.Err != nil {
+			return []ansibleParseError{newAnsibleParseError(inventory.Err, nil)}
+		}
+	case fileKindRequirements:
+		_, parseErrors, err := getAnsibleRequirements(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		return parseErrors
+	case fileKindFactCache:
+		cache, err := getAnsibleFactCache(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if cache.Err != nil {
+			return []ansibleParseError{newAnsibleParseError(cache.Err, nil)}
+		}
+	case fileKindRunLog:
+		log, err := getAnsibleRunLog(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if log.Err != nil {
+			return []ansibleParseError{newAnsibleParseError(log.Err, nil)}
+		}
+	case fileKindLog:
+		log, err := getAnsibleLog(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if log.Err != nil {
+			return []ansibleParseError{newAnsibleParseError(log.Err, nil)}
+		}
+	case fileKindJUnitReport:
+		report, err := getAnsibleJUnitReport(ctx, d, file)
+		if err != nil {
+			return []ansibleParseError{{Message: err.Error()}}
+		}
+		if report.Err != nil {
+			return []ansibleParseError{newAnsibleParseError(report.Err, nil)}
+		}
+	}
+
+	return nil
+}
@@ -18,7 +18,9 @@ func tableAnsibleGroup(ctx context.Context) *plugin.Table {
 		List: &plugin.ListConfig{
 			ParentHydrate: resolveAnsibleInventoryFilePaths,
 			Hydrate:       listAnsibleGroups,
-			KeyColumns:    plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
 		},
 		Columns: []*plugin.Column{
 			{
@@ -53,6 +55,24 @@ func tableAnsibleGroup(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "start_line",
+				Description: "The line where the group is first declared in the file, starting at 1. Null for the implicit all and ungrouped groups.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.StartLine").NullIfZero(),
+			},
+			{
+				Name:        "end_line",
+				Description: "The last line of the group's first section in the file.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.EndLine").NullIfZero(),
+			},
+			{
+				Name:        "start_column",
+				Description: "The column where the group is first declared in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.StartColumn").NullIfZero(),
+			},
 		},
 	}
 }
@@ -61,6 +81,7 @@ type AnsibleGroupInfo struct {
 	Children []string
 	Group    *aini.Group
 	Hosts    []string
+	Location sourceLocation
 	Parents  []string
 	Path     string
 }
@@ -70,18 +91,26 @@ type AnsibleGroupInfo struct {
 func listAnsibleGroups(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// The path comes from a parent hydrate, defaulting to the config paths or
 	// available by the optional key column
-	path := h.Item.(filePath).Path
+	file := h.Item.(filePath)
+	path := file.Path
 
-	data, err := aini.ParseFile(path)
+	inventory, err := getAnsibleInventory(ctx, d, file)
 	if err != nil {
 		plugin.Logger(ctx).Error("ansible_group.listAnsibleGroups", "read_file_error", err, "path", path)
 		return nil, err
 	}
 
+	if inventory.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, "ansible_group.listAnsibleGroups", path, []ansibleParseError{newAnsibleParseError(inventory.Err, nil)})
+	}
+
 	// Even if you do not define any groups in your inventory file, Ansible creates two default groups: all and ungrouped. The all group contains every host. The ungrouped group contains all hosts that don't have another group aside from all.
 
 	// Stream the data
-	for _, group := range data.Groups {
+	for _, group := range inventory.Data.Groups {
 		var hosts, parents, children []string
 
 		for _, host := range group.Hosts {
@@ -100,6 +129,7 @@ func listAnsibleGroups(ctx context.Context, d *plugin.QueryData, h *plugin.Hydra
 			Children: children,
 			Group:    group,
 			Hosts:    hosts,
+			Location: inventory.Locations.Groups[group.Name],
 			Parents:  parents,
 			Path:     path,
 		})
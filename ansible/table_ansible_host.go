@@ -18,7 +18,9 @@ func tableAnsibleHost(ctx context.Context) *plugin.Table {
 		List: &plugin.ListConfig{
 			ParentHydrate: resolveAnsibleInventoryFilePaths,
 			Hydrate:       listAnsibleHosts,
-			KeyColumns:    plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
 		},
 		Columns: []*plugin.Column{
 			{
@@ -49,14 +51,33 @@ func tableAnsibleHost(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "start_line",
+				Description: "The line where the host is first declared in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.StartLine").NullIfZero(),
+			},
+			{
+				Name:        "end_line",
+				Description: "The line where the host declaration ends in the file.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.EndLine").NullIfZero(),
+			},
+			{
+				Name:        "start_column",
+				Description: "The column where the host is first declared in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Location.StartColumn").NullIfZero(),
+			},
 		},
 	}
 }
 
 type AnsibleHostInfo struct {
-	Groups []string
-	Host   *aini.Host
-	Path   string
+	Groups   []string
+	Host     *aini.Host
+	Location sourceLocation
+	Path     string
 }
 
 //// LIST FUNCTION
@@ -64,25 +85,34 @@ type AnsibleHostInfo struct {
 func listAnsibleHosts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// The path comes from a parent hydrate, defaulting to the config paths or
 	// available by the optional key column
-	path := h.Item.(filePath).Path
+	file := h.Item.(filePath)
+	path := file.Path
 
-	data, err := aini.ParseFile(path)
+	inventory, err := getAnsibleInventory(ctx, d, file)
 	if err != nil {
 		plugin.Logger(ctx).Error("ansible_host.listAnsibleHosts", "read_file_error", err, "path", path)
 		return nil, err
 	}
 
+	if inventory.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, "ansible_host.listAnsibleHosts", path, []ansibleParseError{newAnsibleParseError(inventory.Err, nil)})
+	}
+
 	// Stream the data
-	for _, host := range data.Hosts {
+	for _, host := range inventory.Data.Hosts {
 		var groups []string
 		for _, group := range host.Groups {
 			groups = append(groups, group.Name)
 		}
 
 		d.StreamListItem(ctx, AnsibleHostInfo{
-			Groups: groups,
-			Host:   host,
-			Path:   path,
+			Groups:   groups,
+			Host:     host,
+			Location: inventory.Locations.Hosts[host.Name],
+			Path:     path,
 		})
 	}
 
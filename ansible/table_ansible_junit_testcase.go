@@ -0,0 +1,167 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleJUnitTestCase(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_junit_testcase",
+		Description: "The test case of each task on each host of the runs of ansible-playbook, parsed from the reports of the junit callback",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleJUnitReportFilePaths,
+			Hydrate:       listAnsibleJUnitTestCases,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+				{Name: "playbook_name", Require: plugin.Optional},
+				{Name: "task_name", Require: plugin.Optional},
+				{Name: "status", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the report file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "testsuite",
+				Description: "The name of the test suite, which the junit callback names after the playbook file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "timestamp",
+				Description: "Time when the test suite was written, read as UTC when the report has no time zone.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "classname",
+				Description: "The class of the test case, which the junit callback sets to the path of the task, with its line unless JUNIT_TASK_CLASS is enabled.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the test case, e.g. [web1] Configure web: nginx : Copy config.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "host",
+				Description: "The host of the test case.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play of the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task, without the role prefix. The arguments of the task are appended unless JUNIT_HIDE_TASK_ARGUMENTS is enabled.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "role",
+				Description: "The name of the role of the task, if any.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_path",
+				Description: "Path to the file declaring the task, read from the class of the test case.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_line",
+				Description: "The line where the task is declared in its file, if the class of the test case has it.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "status",
+				Description: "The status of the test case. Possible values are: passed, failed, error, skipped.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "time",
+				Description: "The duration of the task on the host, in seconds.",
+				Type:        proto.ColumnType_DOUBLE,
+				Transform:   transform.FromField("Time"),
+			},
+			{
+				Name:        "message",
+				Description: "The message of a failed, errored or skipped test case, e.g. the error of the task or the reason it was skipped.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "output",
+				Description: "The output of a failed or errored test case, e.g. the result of the task or the traceback of an exception.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleJUnitTestCaseInfo struct {
+	Classname    string
+	Host         string
+	Message      string
+	Name         string
+	Output       string
+	Path         string
+	PlaybookName string
+	Role         string
+	Status       string
+	TaskLine     int
+	TaskName     string
+	TaskPath     string
+	Testsuite    string
+	Time         *float64
+	Timestamp    *time.Time
+}
+
+//// LIST FUNCTION
+
+func listAnsibleJUnitTestCases(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	report, err := getAnsibleJUnitReport(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_junit_testcase.listAnsibleJUnitTestCases", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	if report.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, "ansible_junit_testcase.listAnsibleJUnitTestCases", path, []ansibleParseError{newAnsibleParseError(report.Err, nil)})
+	}
+
+	quals := d.EqualsQuals
+	for _, testCase := range report.TestCases {
+		if quals["host"] != nil && quals["host"].GetStringValue() != testCase.Host {
+			continue
+		}
+		if quals["playbook_name"] != nil && quals["playbook_name"].GetStringValue() != testCase.PlaybookName {
+			continue
+		}
+		if quals["task_name"] != nil && quals["task_name"].GetStringValue() != testCase.TaskName {
+			continue
+		}
+		if quals["status"] != nil && quals["status"].GetStringValue() != testCase.Status {
+			continue
+		}
+		d.StreamListItem(ctx, testCase)
+	}
+
+	return nil, nil
+}
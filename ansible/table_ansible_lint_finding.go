@@ -0,0 +1,184 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleLintFinding(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_lint_finding",
+		Description: "Findings of the built-in lint rules, a subset of the ansible-lint rules, on the tasks of Ansible playbooks",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsiblePlaybookFilePaths,
+			Hydrate:       listAnsibleLintFindings,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "rule_id",
+				Description: "The ID of the rule, matching the ansible-lint rule ID, e.g. no-changed-when.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("RuleID"),
+			},
+			{
+				Name:        "severity",
+				Description: "The severity of the rule. Possible values are: low, medium, high.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "description",
+				Description: "The description of the rule.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "message",
+				Description: "The message describing the finding.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "start_line",
+				Description: "The line of the finding in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			// Can't use 'column' as a column since it is a reserved word
+			{
+				Name:        "start_column",
+				Description: "The column of the finding in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the playbook where the task is defined.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "section",
+				Description: "The play section where the task is defined, e.g. tasks or handlers. Null for tasks files.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "module",
+				Description: "The name of the module called by the task, as written in the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleLintFinding struct {
+	Description  string
+	Message      string
+	Module       string
+	Path         string
+	PlaybookName string
+	RuleID       string
+	Section      string
+	Severity     string
+	StartColumn  int
+	StartLine    int
+	TaskName     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleLintFindings(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	classification, err := getAnsibleFileClassification(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_lint_finding.listAnsibleLintFindings", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	// Skip the files that are not playbooks or tasks files
+	if !classification.isPlaybookCandidate() && classification.Kind != fileKindTasks {
+		plugin.Logger(ctx).Debug("ansible_lint_finding.listAnsibleLintFindings", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
+
+	findings, parseErrors, err := getAnsibleLintFindings(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_lint_finding.listAnsibleLintFindings", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_lint_finding.listAnsibleLintFindings", path, parseErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, finding := range findings {
+		d.StreamListItem(ctx, finding)
+	}
+
+	return nil, nil
+}
+
+// lintAnsibleFile runs the lint rules against every task of a playbook or
+// tasks file. A task that cannot be decoded is returned as a parse error.
+func lintAnsibleFile(content []byte, path string) ([]AnsibleLintFinding, []ansibleParseError) {
+	// Decoding the file content as nodes to keep the line numbers and comments
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+
+	var findings []AnsibleLintFinding
+	var parseErrors []ansibleParseError
+	walkAnsibleTaskNodes(&doc, func(t ansibleTaskNode) {
+		var raw map[string]interface{}
+		if err := t.Node.Decode(&raw); err != nil {
+			parseErrors = append(parseErrors, newAnsibleParseError(err, t.Node))
+			return
+		}
+		task := &ansibleLintTask{Node: t.Node, Raw: raw}
+		task.Module, task.Args = parseTaskModule(raw)
+		task.ModuleFQCN = resolveModuleFQCN(task.Module)
+		taskName, _ := raw["name"].(string)
+
+		for _, result := range lintAnsibleTask(task) {
+			node := result.Match.Node
+			if node == nil {
+				node = t.Node
+			}
+			findings = append(findings, AnsibleLintFinding{
+				Description:  result.Rule.Description,
+				Message:      result.Match.Message,
+				Module:       task.Module,
+				Path:         path,
+				PlaybookName: t.PlaybookName,
+				RuleID:       result.Rule.ID,
+				Section:      t.Section,
+				Severity:     result.Rule.Severity,
+				StartColumn:  node.Column,
+				StartLine:    node.Line,
+				TaskName:     taskName,
+			})
+		}
+	})
+
+	return findings, parseErrors
+}
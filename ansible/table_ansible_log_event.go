@@ -0,0 +1,162 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleLogEvent(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_log_event",
+		Description: "Events of the log files written by Ansible, e.g. the start of plays and tasks and the result of tasks on hosts",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleLogFilePaths,
+			Hydrate:       listAnsibleLogEvents,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "event_type", Require: plugin.Optional},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the log file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line where the event starts in the log file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "timestamp",
+				Description: "Time of the event, in the time zone of the plugin.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "pid",
+				Description: "The process ID of the run, which tells apart the runs writing to the same log.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("PID"),
+			},
+			{
+				Name:        "user",
+				Description: "The user running Ansible.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "event_type",
+				Description: "The type of the event. Possible values are: play, task, handler, result, recap, warning, message.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "status",
+				Description: "The status of a result, e.g. ok, changed, failed, fatal, unreachable, skipping, included, rescued or ignored.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the current play of the run.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the current task of the run, without the role prefix.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "role",
+				Description: "The role of the current task of the run, if any.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "host",
+				Description: "The host of a result or recap.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "item",
+				Description: "The loop item of a result, as logged.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "detail",
+				Description: "The output following the => arrow of a result, e.g. the error of a failed task, or the file of an included result.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "message",
+				Description: "The logged message, including the lines continuing it.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleLogEventInfo struct {
+	Detail       string
+	EventType    string
+	Host         string
+	Item         string
+	Line         int
+	Message      string
+	Path         string
+	PID          int
+	PlaybookName string
+	Role         string
+	Status       string
+	TaskName     string
+	Timestamp    time.Time
+	User         string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleLogEvents(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	log, err := readAnsibleLog(ctx, d, file, "ansible_log_event.listAnsibleLogEvents")
+	if err != nil || log == nil {
+		return nil, err
+	}
+
+	quals := d.EqualsQuals
+	for _, event := range log.Events {
+		if quals["event_type"] != nil && quals["event_type"].GetStringValue() != event.EventType {
+			continue
+		}
+		if quals["host"] != nil && quals["host"].GetStringValue() != event.Host {
+			continue
+		}
+		d.StreamListItem(ctx, event)
+	}
+
+	return nil, nil
+}
+
+// readAnsibleLog returns the content of a log file, or nil if the file is
+// skipped because it cannot be read to the end.
+func readAnsibleLog(ctx context.Context, d *plugin.QueryData, file filePath, function string) (*ansibleLogContent, error) {
+	log, err := getAnsibleLog(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error(function, "file_error", err, "path", file.Path)
+		return nil, fmt.Errorf("failed to read file %s: %v", file.Path, err)
+	}
+	if log.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, function, file.Path, []ansibleParseError{newAnsibleParseError(log.Err, nil)})
+	}
+	return &log, nil
+}
@@ -0,0 +1,147 @@
+package ansible
+
+import (
+	"context"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleLogRecap(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_log_recap",
+		Description: "The play recap of each host of the runs in the log files written by Ansible",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleLogFilePaths,
+			Hydrate:       listAnsibleLogRecaps,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the log file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line of the recap of the host in the log file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "timestamp",
+				Description: "Time when the recap was logged, i.e. the end of the run, in the time zone of the plugin.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "pid",
+				Description: "The process ID of the run.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("PID"),
+			},
+			{
+				Name:        "user",
+				Description: "The user running Ansible.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the last play of the run.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "ok",
+				Description: "The number of tasks that were ok on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ok"),
+			},
+			{
+				Name:        "changed",
+				Description: "The number of tasks that changed something on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Changed"),
+			},
+			{
+				Name:        "unreachable",
+				Description: "The number of times the host was unreachable.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Unreachable"),
+			},
+			{
+				Name:        "failed",
+				Description: "The number of tasks that failed on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Failed"),
+			},
+			{
+				Name:        "skipped",
+				Description: "The number of tasks skipped on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Skipped"),
+			},
+			{
+				Name:        "rescued",
+				Description: "The number of task failures rescued by a block on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Rescued"),
+			},
+			{
+				Name:        "ignored",
+				Description: "The number of task failures ignored through ignore_errors on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ignored"),
+			},
+		},
+	}
+}
+
+type AnsibleLogRecapInfo struct {
+	Changed      int
+	Failed       int
+	Host         string
+	Ignored      int
+	Line         int
+	Ok           int
+	Path         string
+	PID          int
+	PlaybookName string
+	Rescued      int
+	Skipped      int
+	Timestamp    time.Time
+	Unreachable  int
+	User         string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleLogRecaps(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	log, err := readAnsibleLog(ctx, d, file, "ansible_log_recap.listAnsibleLogRecaps")
+	if err != nil || log == nil {
+		return nil, err
+	}
+
+	quals := d.EqualsQuals
+	for _, recap := range log.Recaps {
+		if quals["host"] != nil && quals["host"].GetStringValue() != recap.Host {
+			continue
+		}
+		d.StreamListItem(ctx, recap)
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,418 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleModule(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_module",
+		Description: "Modules provided by the installed Ansible collections, with their documentation, deprecations and redirects",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleCollectionPaths,
+			Hydrate:       listAnsibleModules,
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "fqcn",
+				Description: "The fully qualified collection name of the module.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("FQCN"),
+			},
+			{
+				Name:        "name",
+				Description: "The name of the module within its collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "collection",
+				Description: "The fully qualified name of the collection providing the module.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "short_description",
+				Description: "A short description of the module.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "version_added",
+				Description: "The collection version the module was added in.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "is_deprecated",
+				Description: "True if the module is deprecated, either in meta/runtime.yml or in its documentation.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IsDeprecated"),
+			},
+			{
+				Name:        "deprecation_removal_version",
+				Description: "The collection version the deprecated module will be removed in.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "deprecation_removal_date",
+				Description: "The date after which the deprecated module will be removed.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "deprecation_warning",
+				Description: "The warning shown when the deprecated module is used.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "deprecation_alternative",
+				Description: "What to use instead of the deprecated module.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "is_tombstoned",
+				Description: "True if the module has been removed from the collection, and using it is an error.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IsTombstoned"),
+			},
+			{
+				Name:        "tombstone_removal_version",
+				Description: "The collection version the module was removed in.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "tombstone_removal_date",
+				Description: "The date the module was removed.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "tombstone_warning",
+				Description: "The error shown when the removed module is used.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "redirect",
+				Description: "The fully qualified name of the module this name redirects to.",
+				Type:        proto.ColumnType_STRING,
+			},
+
+			// JSON columns
+			{
+				Name:        "description",
+				Description: "The long description of the module.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "author",
+				Description: "The authors of the module.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "options",
+				Description: "The documented options of the module, keyed by option name, with their type, required flag, default, choices and aliases.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "requirements",
+				Description: "The requirements on the host that executes the module.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "collection_path",
+				Description: "Path to the collection directory.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "path",
+				Description: "Path to the module file. Null for names only declared in meta/runtime.yml.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleModuleInfo struct {
+	Author                    interface{}
+	Collection                string
+	CollectionPath            string
+	DeprecationAlternative    string
+	DeprecationRemovalDate    string
+	DeprecationRemovalVersion string
+	DeprecationWarning        string
+	Description               interface{}
+	DocFragments              []string
+	FQCN                      string
+	IsDeprecated              bool
+	IsTombstoned              bool
+	Name                      string
+	Options                   map[string]interface{}
+	Path                      string
+	Redirect                  string
+	Requirements              interface{}
+	ShortDescription          string
+	TombstoneRemovalDate      string
+	TombstoneRemovalVersion   string
+	TombstoneWarning          string
+	VersionAdded              string
+}
+
+// ansibleModuleDocumentation is the DOCUMENTATION block embedded in a module.
+// The extends_documentation_fragment key is a fragment name or a list of them.
+// https://docs.ansible.com/ansible/latest/dev_guide/developing_modules_documenting.html
+type ansibleModuleDocumentation struct {
+	Author                       interface{}            `yaml:"author"`
+	Deprecated                   *ansibleDocDeprecation `yaml:"deprecated"`
+	Description                  interface{}            `yaml:"description"`
+	ExtendsDocumentationFragment interface{}            `yaml:"extends_documentation_fragment"`
+	Module                       string                 `yaml:"module"`
+	Options                      map[string]interface{} `yaml:"options"`
+	Requirements                 interface{}            `yaml:"requirements"`
+	ShortDescription             string                 `yaml:"short_description"`
+	VersionAdded                 string                 `yaml:"version_added"`
+}
+
+type ansibleDocDeprecation struct {
+	Alternative   string `yaml:"alternative"`
+	RemovedAtDate string `yaml:"removed_at_date"`
+	RemovedIn     string `yaml:"removed_in"`
+	Why           string `yaml:"why"`
+}
+
+// ansibleCollectionRuntime is the meta/runtime.yml file of a collection.
+// https://docs.ansible.com/ansible/latest/dev_guide/developing_collections_structure.html#meta-directory-and-runtime-yml
+type ansibleCollectionRuntime struct {
+	PluginRouting struct {
+		Modules map[string]ansiblePluginRouting `yaml:"modules"`
+	} `yaml:"plugin_routing"`
+}
+
+type ansiblePluginRouting struct {
+	Deprecation *ansibleRoutingRemoval `yaml:"deprecation"`
+	Redirect    string                 `yaml:"redirect"`
+	Tombstone   *ansibleRoutingRemoval `yaml:"tombstone"`
+}
+
+type ansibleRoutingRemoval struct {
+	RemovalDate    string `yaml:"removal_date"`
+	RemovalVersion string `yaml:"removal_version"`
+	WarningText    string `yaml:"warning_text"`
+}
+
+//// LIST FUNCTION
+
+func listAnsibleModules(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the collections paths
+	path := h.Item.(filePath).Path
+
+	modules, err := readAnsibleCollectionModules(ctx, path)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_module.listAnsibleModules", "read_collection_error", err, "path", path)
+		return nil, err
+	}
+
+	for _, module := range modules {
+		d.StreamListItem(ctx, module)
+	}
+
+	return nil, nil
+}
+
+// readAnsibleCollectionModules returns the modules of the collection, merging
+// the documentation of each module file with the routing of meta/runtime.yml.
+// Names only declared in the routing, e.g. redirects and removed modules, are
+// returned without a path.
+func readAnsibleCollectionModules(ctx context.Context, dir string) ([]*AnsibleModuleInfo, error) {
+	collection, err := readAnsibleCollection(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := listAnsibleCollectionPluginFiles(dir, collection.FQCN, "modules")
+	if err != nil {
+		return nil, err
+	}
+
+	modules := map[string]*AnsibleModuleInfo{}
+	for fqcn, path := range files {
+		module := &AnsibleModuleInfo{
+			Collection:     collection.FQCN,
+			CollectionPath: dir,
+			FQCN:           fqcn,
+			Name:           strings.TrimPrefix(fqcn, collection.FQCN+"."),
+			Path:           path,
+		}
+		modules[fqcn] = module
+
+		if filepath.Ext(path) != ".py" {
+			continue
+		}
+		doc, err := readAnsibleModuleDocumentation(path)
+		if err != nil {
+			// A single module with broken documentation should not hide the
+			// rest of the collection
+			plugin.Logger(ctx).Warn("ansible_module.readAnsibleCollectionModules", "documentation_error", err, "path", path)
+			continue
+		}
+		if doc == nil {
+			continue
+		}
+
+		module.Author = doc.Author
+		module.Description = doc.Description
+		module.DocFragments = stringOrStringList(doc.ExtendsDocumentationFragment)
+		module.Options = doc.Options
+		module.Requirements = doc.Requirements
+		module.ShortDescription = doc.ShortDescription
+		module.VersionAdded = doc.VersionAdded
+		if doc.Deprecated != nil {
+			module.IsDeprecated = true
+			module.DeprecationAlternative = doc.Deprecated.Alternative
+			module.DeprecationRemovalDate = doc.Deprecated.RemovedAtDate
+			module.DeprecationRemovalVersion = doc.Deprecated.RemovedIn
+			module.DeprecationWarning = doc.Deprecated.Why
+		}
+	}
+
+	runtime, err := readAnsibleCollectionRuntime(dir)
+	if err != nil {
+		return nil, err
+	}
+	for name, routing := range runtime.PluginRouting.Modules {
+		fqcn := collection.FQCN + "." + name
+		module, ok := modules[fqcn]
+		if !ok {
+			module = &AnsibleModuleInfo{
+				Collection:     collection.FQCN,
+				CollectionPath: dir,
+				FQCN:           fqcn,
+				Name:           name,
+			}
+			modules[fqcn] = module
+		}
+
+		module.Redirect = routing.Redirect
+		if routing.Deprecation != nil {
+			module.IsDeprecated = true
+			module.DeprecationRemovalDate = routing.Deprecation.RemovalDate
+			module.DeprecationRemovalVersion = routing.Deprecation.RemovalVersion
+			module.DeprecationWarning = routing.Deprecation.WarningText
+		}
+		if routing.Tombstone != nil {
+			module.IsTombstoned = true
+			module.TombstoneRemovalDate = routing.Tombstone.RemovalDate
+			module.TombstoneRemovalVersion = routing.Tombstone.RemovalVersion
+			module.TombstoneWarning = routing.Tombstone.WarningText
+		}
+	}
+
+	names := make([]string, 0, len(modules))
+	for fqcn := range modules {
+		names = append(names, fqcn)
+	}
+	sort.Strings(names)
+
+	result := make([]*AnsibleModuleInfo, 0, len(names))
+	for _, fqcn := range names {
+		result = append(result, modules[fqcn])
+	}
+
+	return result, nil
+}
+
+// readAnsibleCollectionRuntime reads meta/runtime.yml, which is optional.
+func readAnsibleCollectionRuntime(dir string) (*ansibleCollectionRuntime, error) {
+	var runtime ansibleCollectionRuntime
+
+	path := filepath.Join(dir, "meta", "runtime.yml")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &runtime, nil
+		}
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	if err := yaml.Unmarshal(content, &runtime); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal file content %s: %v", path, err)
+	}
+
+	return &runtime, nil
+}
+
+// readAnsibleModuleDocumentation decodes the YAML assigned to the DOCUMENTATION
+// variable of a Python module. It returns nil if the module is undocumented.
+func readAnsibleModuleDocumentation(path string) (*ansibleModuleDocumentation, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	block := extractPythonStringVariable(string(content), "DOCUMENTATION")
+	if block == "" {
+		return nil, nil
+	}
+
+	var doc ansibleModuleDocumentation
+	if err := yaml.Unmarshal([]byte(block), &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal DOCUMENTATION in %s: %v", path, err)
+	}
+
+	return &doc, nil
+}
+
+// extractPythonStringVariable returns the value of a top-level triple quoted
+// string assignment such as `DOCUMENTATION = r”'...”'`.
+func extractPythonStringVariable(source string, name string) string {
+	return extractPythonStringAssignment(source, name, false)
+}
+
+// extractPythonStringAssignment returns the value of a triple quoted string
+// assignment, which may be indented, e.g. the attributes of the
+// ModuleDocFragment class of doc fragments, if indented is set.
+func extractPythonStringAssignment(source string, name string, indented bool) string {
+	for offset := 0; offset < len(source); {
+		i := strings.Index(source[offset:], name)
+		if i < 0 {
+			return ""
+		}
+		start := offset + i
+		offset = start + len(name)
+
+		// Only consider assignments at the start of a line
+		lineStart := start
+		if indented {
+			lineStart = len(strings.TrimRight(source[:start], " \t"))
+		}
+		if lineStart > 0 && source[lineStart-1] != '\n' {
+			continue
+		}
+		rest := strings.TrimLeft(source[offset:], " \t")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t")
+		rest = strings.TrimLeft(rest, "rRuU")
+
+		for _, quote := range []string{"'''", `"""`} {
+			if !strings.HasPrefix(rest, quote) {
+				continue
+			}
+			rest = rest[len(quote):]
+			end := strings.Index(rest, quote)
+			if end < 0 {
+				return ""
+			}
+			return rest[:end]
+		}
+	}
+
+	return ""
+}
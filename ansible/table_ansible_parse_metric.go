@@ -0,0 +1,129 @@
+package ansible
+
+import (
+	"context"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleParseMetric(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_parse_metric",
+		Description: "Statistics of the files read and parsed by the recent queries of the connection",
+		List: &plugin.ListConfig{
+			Hydrate: listAnsibleParseMetrics,
+		},
+		// The metrics change while queries run, so they must not be served
+		// from the query cache
+		Cache: &plugin.TableCacheOptions{
+			Enabled: false,
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "query_id",
+				Description: "Sequence number of the query, unique for the lifetime of the plugin.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("QueryID"),
+			},
+			{
+				Name:        "table_name",
+				Description: "The table that was queried.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "started_at",
+				Description: "Time when the query started listing files.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "max_parse_concurrency",
+				Description: "The maximum number of files read and parsed at the same time.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MaxParseConcurrency"),
+			},
+			{
+				Name:        "files_listed",
+				Description: "Number of files matched by the configured file paths or the path qualifier.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("FilesListed"),
+			},
+			{
+				Name:        "files_parsed",
+				Description: "Number of times a file was read and parsed, i.e. was not found in the cache. A file is parsed once for each kind of content listed from it, e.g. plays and tasks.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("FilesParsed"),
+			},
+			{
+				Name:        "files_skipped",
+				Description: "Number of files not parsed because they do not contain the values of the query's quals, e.g. the task name.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("FilesSkipped"),
+			},
+			{
+				Name:        "cache_hits",
+				Description: "Number of times the parsed content of a file was found in the cache.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("CacheHits"),
+			},
+			{
+				Name:        "bytes_parsed",
+				Description: "Total size of the files read and parsed.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("BytesParsed"),
+			},
+			{
+				Name:        "parse_duration_ms",
+				Description: "Total time spent reading and parsing files, in milliseconds. Files parsed at the same time each count.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("ParseDurationMs"),
+			},
+			{
+				Name:        "wait_duration_ms",
+				Description: "Total time spent waiting for a file to be parsed when max_parse_concurrency files were already being parsed, in milliseconds.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("WaitDurationMs"),
+			},
+		},
+	}
+}
+
+type AnsibleParseMetricInfo struct {
+	BytesParsed         int64
+	CacheHits           int64
+	FilesListed         int64
+	FilesParsed         int64
+	FilesSkipped        int64
+	MaxParseConcurrency int
+	ParseDurationMs     int64
+	QueryID             int64
+	StartedAt           time.Time
+	TableName           string
+	WaitDurationMs      int64
+}
+
+//// LIST FUNCTION
+
+func listAnsibleParseMetrics(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	for _, metrics := range recentParseMetrics(d.Connection.Name) {
+		d.StreamListItem(ctx, AnsibleParseMetricInfo{
+			BytesParsed:         metrics.bytesParsed.Load(),
+			CacheHits:           metrics.cacheHits.Load(),
+			FilesListed:         metrics.filesListed.Load(),
+			FilesParsed:         metrics.filesParsed.Load(),
+			FilesSkipped:        metrics.filesSkipped.Load(),
+			MaxParseConcurrency: metrics.MaxParseConcurrency,
+			ParseDurationMs:     time.Duration(metrics.parseDuration.Load()).Milliseconds(),
+			QueryID:             metrics.QueryID,
+			StartedAt:           metrics.StartedAt,
+			TableName:           metrics.Table,
+			WaitDurationMs:      time.Duration(metrics.waitDuration.Load()).Milliseconds(),
+		})
+	}
+
+	return nil, nil
+}
@@ -3,10 +3,11 @@ package ansible
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,7 +20,13 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 		List: &plugin.ListConfig{
 			ParentHydrate: resolveAnsiblePlaybookFilePaths,
 			Hydrate:       listAnsiblePlaybooks,
-			KeyColumns:    plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "name", Require: plugin.Optional},
+				{Name: "hosts", Require: plugin.Optional},
+				{Name: "become", Require: plugin.Optional},
+				{Name: "tags", Require: plugin.Optional, Operators: []string{"?"}},
+			},
 		},
 		Columns: []*plugin.Column{
 			{
@@ -29,15 +36,29 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "hosts",
-				Description: "A list of groups, hosts or host pattern that translates into a list of hosts that are the play's target.",
+				Description: "A list of groups, hosts or host pattern that translates into a list of hosts that are the play's target. Null if given as a YAML list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Hosts.Value"),
+			},
+			{
+				Name:        "hosts_raw",
+				Description: "The hosts keyword as written in the file: a host pattern or a list of host patterns.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Hosts.Raw"),
 			},
 
 			// Become directives
 			{
 				Name:        "become",
-				Description: "Controls if privilege escalation is used or not on task execution. If true, privilege escalation is activated.",
+				Description: "Controls if privilege escalation is used or not on task execution. If true, privilege escalation is activated. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Become.Value"),
+			},
+			{
+				Name:        "become_raw",
+				Description: "The become keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Become.Raw"),
 			},
 			{
 				Name:        "become_user",
@@ -56,8 +77,15 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "check_mode",
-				Description: "A boolean that controls if a task is executed in 'check' mode.",
+				Description: "A boolean that controls if a task is executed in 'check' mode. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("CheckMode.Value"),
+			},
+			{
+				Name:        "check_mode_raw",
+				Description: "The check_mode keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("CheckMode.Raw"),
 			},
 			{
 				Name:        "debugger",
@@ -66,38 +94,87 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "diff",
-				Description: "Toggle to make tasks return 'diff' information or not.",
+				Description: "Toggle to make tasks return 'diff' information or not. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Diff.Value"),
+			},
+			{
+				Name:        "diff_raw",
+				Description: "The diff keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Diff.Raw"),
 			},
 			{
 				Name:        "force_handlers",
-				Description: "Will force notified handler execution for hosts even if they failed during the play.",
+				Description: "Will force notified handler execution for hosts even if they failed during the play. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("ForceHandlers.Value"),
+			},
+			{
+				Name:        "force_handlers_raw",
+				Description: "The force_handlers keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("ForceHandlers.Raw"),
 			},
 			{
 				Name:        "gather_facts",
-				Description: "A boolean that controls if the play will automatically run the 'setup' task to gather facts for the hosts.",
+				Description: "A boolean that controls if the play will automatically run the 'setup' task to gather facts for the hosts. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("GatherFacts.Value"),
+			},
+			{
+				Name:        "gather_facts_raw",
+				Description: "The gather_facts keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("GatherFacts.Raw"),
 			},
 			{
 				Name:        "ignore_errors",
-				Description: "Boolean that allows you to ignore task failures and continue with play.",
+				Description: "Boolean that allows you to ignore task failures and continue with play. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IgnoreErrors.Value"),
+			},
+			{
+				Name:        "ignore_errors_raw",
+				Description: "The ignore_errors keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IgnoreErrors.Raw"),
 			},
 			{
 				Name:        "ignore_unreachable",
-				Description: "Boolean that allows you to ignore task failures due to an unreachable host and continue with the play.",
+				Description: "Boolean that allows you to ignore task failures due to an unreachable host and continue with the play. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IgnoreUnreachable.Value"),
+			},
+			{
+				Name:        "ignore_unreachable_raw",
+				Description: "The ignore_unreachable keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IgnoreUnreachable.Raw"),
 			},
 			{
 				Name:        "max_fail_percentage",
-				Description: "It can be used to abort the run after a given percentage of hosts in the current batch has failed.",
+				Description: "It can be used to abort the run after a given percentage of hosts in the current batch has failed. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MaxFailPercentage.Value"),
+			},
+			{
+				Name:        "max_fail_percentage_raw",
+				Description: "The max_fail_percentage keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("MaxFailPercentage.Raw"),
 			},
 			{
 				Name:        "no_log",
-				Description: "Boolean that controls information disclosure.",
+				Description: "Boolean that controls information disclosure. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("NoLog.Value"),
+			},
+			{
+				Name:        "no_log_raw",
+				Description: "The no_log keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("NoLog.Raw"),
 			},
 			{
 				Name:        "order",
@@ -111,13 +188,27 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "run_once",
-				Description: "Boolean that will bypass the host loop, forcing the task to attempt to execute on the first host available and afterwards apply any results and facts to all active hosts in the same batch.",
+				Description: "Boolean that will bypass the host loop, forcing the task to attempt to execute on the first host available and afterwards apply any results and facts to all active hosts in the same batch. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("RunOnce.Value"),
+			},
+			{
+				Name:        "run_once_raw",
+				Description: "The run_once keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("RunOnce.Raw"),
 			},
 			{
 				Name:        "serial",
-				Description: "Explicitly define how Ansible batches the execution of the current play on the play's target.",
+				Description: "Explicitly define how Ansible batches the execution of the current play on the play's target. Only set for a number of hosts, and null for a percentage, a list of batches or a templated value.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Serial.Value"),
+			},
+			{
+				Name:        "serial_raw",
+				Description: "The serial keyword as written in the file: a number of hosts, a percentage of hosts, or a list of either for successive batches.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Serial.Raw"),
 			},
 			{
 				Name:        "strategy",
@@ -126,18 +217,39 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "tags",
-				Description: "Tags applied at the level of play.",
-				Type:        proto.ColumnType_STRING,
+				Description: "Tags applied at the level of play. A comma separated string of tags is split into a list. Null if any tag is templated.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Tags.Value"),
+			},
+			{
+				Name:        "tags_raw",
+				Description: "The tags keyword as written in the file: a list of tags or a comma separated string of tags.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Tags.Raw"),
 			},
 			{
 				Name:        "throttle",
-				Description: "Limit number of concurrent task runs on task, block and playbook level.",
+				Description: "Limit number of concurrent task runs on task, block and playbook level. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Throttle.Value"),
+			},
+			{
+				Name:        "throttle_raw",
+				Description: "The throttle keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Throttle.Raw"),
 			},
 			{
 				Name:        "timeout",
-				Description: "Time limit for task to execute in, if exceeded Ansible will interrupt and fail the task.",
+				Description: "Time limit for task to execute in, if exceeded Ansible will interrupt and fail the task. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Timeout.Value"),
+			},
+			{
+				Name:        "timeout_raw",
+				Description: "The timeout keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Timeout.Raw"),
 			},
 
 			// JSON columns
@@ -208,47 +320,77 @@ func tableAnsiblePlaybook(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "start_line",
+				Description: "The line where the play starts in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "end_line",
+				Description: "The line where the play ends in the file.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "start_column",
+				Description: "The column where the play starts in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "source",
+				Description: "The original YAML text of the play.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "content_hash",
+				Description: "A SHA-256 hash of the play content. Comments, whitespace, quoting and key order do not change the hash.",
+				Type:        proto.ColumnType_STRING,
+			},
 		},
 	}
 }
 
 type AnsiblePlaybookInfo struct {
-	Become            bool        `cty:"become"`
-	BecomeFlags       string      `cty:"become_flags"`
-	BecomeMethod      string      `cty:"become_method"`
-	BecomeUser        string      `cty:"become_user"`
-	CheckMode         bool        `cty:"check_mode"`
-	Collections       interface{} `cty:"collections"`
-	Debugger          string      `cty:"debugger"`
-	Diff              bool        `cty:"diff"`
-	Environment       interface{} `cty:"environment"`
-	ForceHandlers     bool        `cty:"force_handlers"`
-	GatherFacts       bool        `cty:"gather_facts"`
-	GatherSubset      interface{} `cty:"gether_subset"`
-	Handlers          interface{} `cty:"handlers"`
-	Hosts             string      `cty:"hosts"`
-	IgnoreErrors      bool        `cty:"ignore_errors"`
-	IgnoreUnreachable bool        `cty:"ignore_unreachable"`
-	MaxFailPercentage int         `cty:"max_fail_percentage"`
-	ModuleDefaults    interface{} `cty:"module_defaults"`
-	Name              string      `cty:"name"`
-	NoLog             bool        `cty:"no_log"`
-	Order             string      `cty:"order"`
-	Path              string      `cty:"-"`
-	PostTasks         interface{} `cty:"post_tasks"`
-	PreTasks          interface{} `cty:"pre_tasks"`
-	RemoteUser        string      `cty:"remote_user"`
-	Roles             interface{} `cty:"roles"`
-	RunOnce           bool        `cty:"run_once"`
-	Serial            int         `cty:"serial"`
-	Strategy          string      `cty:"strategy"`
-	Tags              string      `cty:"tags"`
-	Tasks             interface{} `cty:"tasks"`
-	Throttle          int         `cty:"throttle"`
-	Timeout           int         `cty:"timeout"`
-	Vars              interface{} `cty:"vars"`
-	VarsFiles         interface{} `cty:"vars_files"`
-	VarsPrompt        interface{} `cty:"vars_prompt"`
+	Become            ansibleBoolKeyword   `cty:"become" yaml:"become"`
+	BecomeFlags       string               `cty:"become_flags" yaml:"become_flags"`
+	BecomeMethod      string               `cty:"become_method" yaml:"become_method"`
+	BecomeUser        string               `cty:"become_user" yaml:"become_user"`
+	CheckMode         ansibleBoolKeyword   `cty:"check_mode" yaml:"check_mode"`
+	Collections       interface{}          `cty:"collections" yaml:"collections"`
+	ContentHash       string               `cty:"-" yaml:"-"`
+	Debugger          string               `cty:"debugger" yaml:"debugger"`
+	Diff              ansibleBoolKeyword   `cty:"diff" yaml:"diff"`
+	EndLine           int                  `cty:"-" yaml:"-"`
+	Environment       interface{}          `cty:"environment" yaml:"environment"`
+	ForceHandlers     ansibleBoolKeyword   `cty:"force_handlers" yaml:"force_handlers"`
+	GatherFacts       ansibleBoolKeyword   `cty:"gather_facts" yaml:"gather_facts"`
+	GatherSubset      interface{}          `cty:"gether_subset" yaml:"gather_subset"`
+	Handlers          interface{}          `cty:"handlers" yaml:"handlers"`
+	Hosts             ansibleStringKeyword `cty:"hosts" yaml:"hosts"`
+	IgnoreErrors      ansibleBoolKeyword   `cty:"ignore_errors" yaml:"ignore_errors"`
+	IgnoreUnreachable ansibleBoolKeyword   `cty:"ignore_unreachable" yaml:"ignore_unreachable"`
+	MaxFailPercentage ansibleIntKeyword    `cty:"max_fail_percentage" yaml:"max_fail_percentage"`
+	ModuleDefaults    interface{}          `cty:"module_defaults" yaml:"module_defaults"`
+	Name              string               `cty:"name" yaml:"name"`
+	NoLog             ansibleBoolKeyword   `cty:"no_log" yaml:"no_log"`
+	Order             string               `cty:"order" yaml:"order"`
+	Path              string               `cty:"-"`
+	PostTasks         interface{}          `cty:"post_tasks" yaml:"post_tasks"`
+	PreTasks          interface{}          `cty:"pre_tasks" yaml:"pre_tasks"`
+	RemoteUser        string               `cty:"remote_user" yaml:"remote_user"`
+	Roles             interface{}          `cty:"roles" yaml:"roles"`
+	RunOnce           ansibleBoolKeyword   `cty:"run_once" yaml:"run_once"`
+	Serial            ansibleIntKeyword    `cty:"serial" yaml:"serial"`
+	Source            string               `cty:"-" yaml:"-"`
+	StartColumn       int                  `cty:"-" yaml:"-"`
+	StartLine         int                  `cty:"-" yaml:"-"`
+	Strategy          string               `cty:"strategy" yaml:"strategy"`
+	Tags              ansibleTagsKeyword   `cty:"tags" yaml:"tags"`
+	Tasks             interface{}          `cty:"tasks" yaml:"tasks"`
+	Throttle          ansibleIntKeyword    `cty:"throttle" yaml:"throttle"`
+	Timeout           ansibleIntKeyword    `cty:"timeout" yaml:"timeout"`
+	Vars              interface{}          `cty:"vars" yaml:"vars"`
+	VarsFiles         interface{}          `cty:"vars_files" yaml:"vars_files"`
+	VarsPrompt        interface{}          `cty:"vars_prompt" yaml:"vars_prompt"`
 }
 
 //// LIST FUNCTION
@@ -256,23 +398,47 @@ type AnsiblePlaybookInfo struct {
 func listAnsiblePlaybooks(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// The path comes from a parent hydrate, defaulting to the config paths or
 	// available by the optional key column
-	path := h.Item.(filePath).Path
+	file := h.Item.(filePath)
+	path := file.Path
+
+	// Skip the files that do not contain the values of the quals, e.g. the
+	// name of the play, before parsing them
+	filter := newAnsibleQualFilter(d, "hosts", "name")
+	found, err := scanAnsibleFile(ctx, d, "plays", file, filter.scanWords())
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_playbook.listAnsiblePlaybooks", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	if !found {
+		return nil, nil
+	}
 
-	content, err := os.ReadFile(path)
+	classification, err := getAnsibleFileClassification(ctx, d, file)
 	if err != nil {
 		plugin.Logger(ctx).Error("ansible_playbook.listAnsiblePlaybooks", "file_error", err, "path", path)
 		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
 	}
 
-	// Decoding the file content
-	var data []AnsiblePlaybookInfo
-	err = yaml.Unmarshal(content, &data)
+	// Skip the files that are not playbooks, e.g. vars files matched by a wide glob
+	if !classification.isPlaybookCandidate() {
+		plugin.Logger(ctx).Debug("ansible_playbook.listAnsiblePlaybooks", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
+
+	plays, parseErrors, err := getAnsiblePlays(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_playbook.listAnsiblePlaybooks", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_playbook.listAnsiblePlaybooks", path, parseErrors)
 	if err != nil {
-		plugin.Logger(ctx).Error("ansible_playbook.listAnsiblePlaybooks", "parse_error", err, "path", path)
-		return nil, fmt.Errorf("failed to unmarshal file content %s: %v", path, err)
+		return nil, err
 	}
 
-	for _, play := range data {
+	for _, play := range plays {
+		if !filter.matchPlay(play) {
+			continue
+		}
 		play.Path = path
 
 		d.StreamListItem(ctx, play)
@@ -280,3 +446,44 @@ func listAnsiblePlaybooks(ctx context.Context, d *plugin.QueryData, h *plugin.Hy
 
 	return nil, nil
 }
+
+// decodeAnsiblePlays decodes the plays of a playbook. A play that cannot be
+// decoded is returned as a parse error, so that the other plays of the file
+// can still be listed.
+func decodeAnsiblePlays(content []byte) ([]AnsiblePlaybookInfo, []ansibleParseError) {
+	// Decoding the file content as nodes to keep the location of each play
+	var data []yaml.Node
+	err := yaml.Unmarshal(content, &data)
+	if err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+
+	var plays []AnsiblePlaybookInfo
+	var parseErrors []ansibleParseError
+	lines := strings.Split(string(content), "\n")
+	for i := range data {
+		var play AnsiblePlaybookInfo
+		err = data[i].Decode(&play)
+		if err != nil {
+			parseErrors = append(parseErrors, newAnsibleParseError(err, &data[i]))
+			continue
+		}
+
+		contentHash, err := yamlNodeContentHash(&data[i])
+		if err != nil {
+			parseErrors = append(parseErrors, newAnsibleParseError(err, &data[i]))
+			continue
+		}
+
+		location := yamlNodeLocation(&data[i], lines)
+		play.ContentHash = contentHash
+		play.EndLine = location.EndLine
+		play.Source = yamlNodeSource(location, lines)
+		play.StartColumn = location.StartColumn
+		play.StartLine = location.StartLine
+
+		plays = append(plays, play)
+	}
+
+	return plays, parseErrors
+}
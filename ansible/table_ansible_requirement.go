@@ -0,0 +1,329 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleRequirement(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_requirement",
+		Description: "Roles and collections declared in Ansible requirements files",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleRequirementsFilePaths,
+			Hydrate:       listAnsibleRequirements,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "kind",
+				Description: "The kind of the requirement. Possible values are: role, collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the role or collection.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "version",
+				Description: "The version constraint of the requirement. For SCM sources this is the branch, tag or commit.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source",
+				Description: "Where the requirement is installed from, e.g. a Galaxy name, Galaxy server, Git repository or archive URL.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "scm_type",
+				Description: "The type of the source. Possible values are: galaxy, git, hg, url, file, dir, subdirs.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "is_version_pinned",
+				Description: "True if the requirement is pinned to an exact version, tag or commit.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IsVersionPinned"),
+			},
+			{
+				Name:        "is_git_ref_missing",
+				Description: "True if the requirement is installed from a Git source without a ref.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IsGitRefMissing"),
+			},
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleRequirementInfo struct {
+	IsGitRefMissing bool
+	IsVersionPinned bool
+	Kind            string
+	Name            string
+	Path            string
+	ScmType         string
+	Source          string
+	Version         string
+}
+
+// ansibleRequirementEntry holds every key allowed in a role or collection
+// entry of a requirements file.
+type ansibleRequirementEntry struct {
+	Include string `yaml:"include"`
+	Name    string `yaml:"name"`
+	Scm     string `yaml:"scm"`
+	Source  string `yaml:"source"`
+	Src     string `yaml:"src"`
+	Type    string `yaml:"type"`
+	Version string `yaml:"version"`
+}
+
+//// LIST FUNCTION
+
+func listAnsibleRequirements(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	requirements, parseErrors, err := getAnsibleRequirements(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_requirement.listAnsibleRequirements", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	if len(parseErrors) > 0 {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, "ansible_requirement.listAnsibleRequirements", path, parseErrors)
+	}
+
+	for _, requirement := range requirements {
+		requirement.Path = path
+
+		d.StreamListItem(ctx, requirement)
+	}
+
+	return nil, nil
+}
+
+// parseAnsibleRequirements decodes both requirements file formats: the legacy
+// top-level list of roles, and the map with `roles` and `collections` keys.
+func parseAnsibleRequirements(content []byte) ([]AnsibleRequirementInfo, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var requirements []AnsibleRequirementInfo
+	root := doc.Content[0]
+	switch root.Kind {
+	case yaml.SequenceNode:
+		items, err := parseAnsibleRequirementEntries("role", root)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, items...)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			var kind string
+			switch root.Content[i].Value {
+			case "roles":
+				kind = "role"
+			case "collections":
+				kind = "collection"
+			default:
+				continue
+			}
+			items, err := parseAnsibleRequirementEntries(kind, root.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			requirements = append(requirements, items...)
+		}
+	default:
+		return nil, fmt.Errorf("line %d: expected a list or a map of requirements", root.Line)
+	}
+
+	return requirements, nil
+}
+
+func parseAnsibleRequirementEntries(kind string, node *yaml.Node) ([]AnsibleRequirementInfo, error) {
+	if node.Kind != yaml.SequenceNode {
+		// An empty `roles:` or `collections:` key is allowed
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("line %d: expected a list of %ss", node.Line, kind)
+	}
+
+	var requirements []AnsibleRequirementInfo
+	for _, item := range node.Content {
+		var entry ansibleRequirementEntry
+		switch item.Kind {
+		case yaml.ScalarNode:
+			if kind == "role" {
+				entry.Src = item.Value
+			} else {
+				entry.Name = item.Value
+			}
+		case yaml.MappingNode:
+			if err := item.Decode(&entry); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("line %d: expected a %s name or definition", item.Line, kind)
+		}
+
+		// Nested requirements files are resolved relative to the Ansible
+		// working directory at install time, so skip them here
+		if entry.Include != "" {
+			continue
+		}
+
+		var requirement AnsibleRequirementInfo
+		if kind == "role" {
+			requirement = newAnsibleRoleRequirement(entry)
+		} else {
+			requirement = newAnsibleCollectionRequirement(entry)
+		}
+		requirement.IsVersionPinned = isRequirementVersionPinned(requirement.ScmType, requirement.Version)
+		requirement.IsGitRefMissing = requirement.ScmType == "git" && requirement.Version == ""
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+// newAnsibleRoleRequirement handles roles given as a Galaxy name, an archive
+// URL or an SCM URL, including the `git+<url>,<version>,<name>` shorthand.
+func newAnsibleRoleRequirement(entry ansibleRequirementEntry) AnsibleRequirementInfo {
+	requirement := AnsibleRequirementInfo{
+		Kind:    "role",
+		Name:    entry.Name,
+		ScmType: entry.Scm,
+		Source:  entry.Src,
+		Version: entry.Version,
+	}
+	if requirement.Source == "" {
+		requirement.Source = entry.Name
+	}
+
+	if scm, src, ok := strings.Cut(requirement.Source, "+"); ok && (scm == "git" || scm == "hg") {
+		requirement.ScmType = scm
+		parts := strings.Split(src, ",")
+		requirement.Source = parts[0]
+		if len(parts) > 1 && requirement.Version == "" {
+			requirement.Version = parts[1]
+		}
+		if len(parts) > 2 && requirement.Name == "" {
+			requirement.Name = parts[2]
+		}
+	}
+
+	if requirement.ScmType == "" {
+		switch {
+		case isGitSource(requirement.Source):
+			requirement.ScmType = "git"
+		case strings.Contains(requirement.Source, "://"):
+			requirement.ScmType = "url"
+		default:
+			requirement.ScmType = "galaxy"
+		}
+	}
+
+	if requirement.Name == "" {
+		requirement.Name = requirement.Source
+		if requirement.ScmType != "galaxy" {
+			requirement.Name = strings.TrimSuffix(filepath.Base(requirement.Source), ".git")
+			requirement.Name = strings.TrimSuffix(requirement.Name, ".tar.gz")
+		}
+	}
+
+	return requirement
+}
+
+// newAnsibleCollectionRequirement handles collections given as a Galaxy name
+// or through the `name`, `source` and `type` keys, where `source` is the Galaxy
+// server for Galaxy collections.
+func newAnsibleCollectionRequirement(entry ansibleRequirementEntry) AnsibleRequirementInfo {
+	requirement := AnsibleRequirementInfo{
+		Kind:    "collection",
+		Name:    entry.Name,
+		ScmType: entry.Type,
+		Source:  entry.Source,
+		Version: entry.Version,
+	}
+
+	if strings.HasPrefix(requirement.Name, "git+") {
+		requirement.ScmType = "git"
+		src := strings.TrimPrefix(requirement.Name, "git+")
+		if s, ref, ok := strings.Cut(src, ","); ok {
+			src = s
+			if requirement.Version == "" {
+				requirement.Version = ref
+			}
+		}
+		requirement.Source = src
+		requirement.Name = strings.TrimSuffix(filepath.Base(src), ".git")
+	}
+
+	if requirement.ScmType == "" {
+		switch {
+		case isGitSource(requirement.Name):
+			requirement.ScmType = "git"
+		case strings.Contains(requirement.Name, "://"):
+			requirement.ScmType = "url"
+		default:
+			requirement.ScmType = "galaxy"
+		}
+	}
+
+	// For non Galaxy types the name is the location of the collection
+	if requirement.ScmType != "galaxy" && requirement.Source == "" {
+		requirement.Source = requirement.Name
+	}
+
+	return requirement
+}
+
+func isGitSource(source string) bool {
+	return strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
+
+// isRequirementVersionPinned reports whether the version resolves to exactly
+// one release. Ranges, wildcards and well-known branch names float.
+func isRequirementVersionPinned(scmType string, version string) bool {
+	version = strings.TrimSpace(version)
+	if version == "" || strings.ContainsAny(version, "*<>!,") {
+		return false
+	}
+	if scmType == "git" || scmType == "hg" {
+		switch strings.ToLower(version) {
+		case "head", "master", "main", "develop", "devel", "trunk", "default", "tip":
+			return false
+		}
+	}
+	return true
+}
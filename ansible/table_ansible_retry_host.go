@@ -0,0 +1,119 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleRetryHost(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_retry_host",
+		Description: "Hosts listed in the retry files written by Ansible for the playbooks whose run failed on them",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsiblePlaybookFilePaths,
+			Hydrate:       listAnsibleRetryHosts,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the playbook file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "host",
+				Description: "The name of the host the last run of the playbook failed on.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "retry_path",
+				Description: "Path to the retry file, next to the playbook or in the retry_files_save_path directory of ansible.cfg.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "timestamp",
+				Description: "Time when the retry file was written, i.e. its modification time.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "in_inventory",
+				Description: "True if the host is in the inventory files, or null if inventory_file_paths is not configured.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("InInventory"),
+			},
+		},
+	}
+}
+
+type AnsibleRetryHostInfo struct {
+	Host        string
+	InInventory *bool
+	Path        string
+	RetryPath   string
+	Timestamp   time.Time
+}
+
+//// LIST FUNCTION
+
+func listAnsibleRetryHosts(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	// The inventory is only read if a retry file is found
+	var inventoryHosts map[string]*ansibleInventoryHost
+	inventoryRead := false
+
+	quals := d.EqualsQuals
+	for _, retryPath := range ansibleRetryFilePaths(path) {
+		info, err := os.Stat(retryPath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+
+		hosts, err := getAnsibleRetryFile(ctx, d, filePath{Metrics: file.Metrics, Path: retryPath})
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_retry_host.listAnsibleRetryHosts", "file_error", err, "path", retryPath)
+			return nil, fmt.Errorf("failed to read file %s: %v", retryPath, err)
+		}
+
+		if !inventoryRead && GetConfig(d.Connection).InventoryFilePaths != nil {
+			inventoryHosts, _, err = listAnsibleInventoryHosts(ctx, d, file.Metrics)
+			if err != nil {
+				return nil, err
+			}
+			inventoryRead = true
+		}
+
+		for _, host := range hosts {
+			if quals["host"] != nil && quals["host"].GetStringValue() != host {
+				continue
+			}
+			item := AnsibleRetryHostInfo{
+				Host:      host,
+				Path:      path,
+				RetryPath: retryPath,
+				Timestamp: info.ModTime(),
+			}
+			if inventoryRead {
+				_, ok := inventoryHosts[host]
+				item.InInventory = &ok
+			}
+			d.StreamListItem(ctx, item)
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,178 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleRun(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_run",
+		Description: "Runs of ansible-playbook, parsed from the output of the json stdout callback",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleRunLogFilePaths,
+			Hydrate:       listAnsibleRuns,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file holding the output of the run.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_path",
+				Description: "Path to the playbook run, as recorded by the callback.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "plays",
+				Description: "The names of the plays run, in order.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "started_at",
+				Description: "Time when the first play started.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "ended_at",
+				Description: "Time when the last play ended.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "duration",
+				Description: "The duration of the run, in seconds.",
+				Type:        proto.ColumnType_DOUBLE,
+				Transform:   transform.FromField("Duration"),
+			},
+			{
+				Name:        "success",
+				Description: "True if no host failed or was unreachable.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Success"),
+			},
+			{
+				Name:        "host_count",
+				Description: "The number of hosts of the run.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("HostCount"),
+			},
+			{
+				Name:        "task_count",
+				Description: "The number of tasks run, counted once for all their hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("TaskCount"),
+			},
+			{
+				Name:        "ok",
+				Description: "The number of task results that were ok, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ok"),
+			},
+			{
+				Name:        "changed",
+				Description: "The number of task results that changed something, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Changed"),
+			},
+			{
+				Name:        "failures",
+				Description: "The number of task results that failed, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Failures"),
+			},
+			{
+				Name:        "unreachable",
+				Description: "The number of hosts found unreachable, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Unreachable"),
+			},
+			{
+				Name:        "skipped",
+				Description: "The number of task results that were skipped, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Skipped"),
+			},
+			{
+				Name:        "rescued",
+				Description: "The number of task failures rescued by a block, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Rescued"),
+			},
+			{
+				Name:        "ignored",
+				Description: "The number of task failures ignored through ignore_errors, summed over the hosts.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ignored"),
+			},
+			{
+				Name:        "custom_stats",
+				Description: "The custom stats set by the set_stats module, if any.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+type AnsibleRunInfo struct {
+	Changed      int
+	CustomStats  map[string]interface{}
+	Duration     *float64
+	EndedAt      *time.Time
+	Failures     int
+	HostCount    int
+	Ignored      int
+	Ok           int
+	Path         string
+	PlaybookPath string
+	Plays        []string
+	Rescued      int
+	Skipped      int
+	StartedAt    *time.Time
+	Success      bool
+	TaskCount    int
+	Unreachable  int
+}
+
+//// LIST FUNCTION
+
+func listAnsibleRuns(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	log, err := readAnsibleRunLog(ctx, d, file, "ansible_run.listAnsibleRuns")
+	if err != nil || log == nil {
+		return nil, err
+	}
+	d.StreamListItem(ctx, log.Run)
+
+	return nil, nil
+}
+
+// readAnsibleRunLog returns the run of a run log file, or nil if the file is
+// skipped because it cannot be parsed.
+func readAnsibleRunLog(ctx context.Context, d *plugin.QueryData, file filePath, function string) (*ansibleRunLog, error) {
+	log, err := getAnsibleRunLog(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error(function, "file_error", err, "path", file.Path)
+		return nil, fmt.Errorf("failed to read file %s: %v", file.Path, err)
+	}
+	if log.Err != nil {
+		// With on_parse_error set to fail, the default, the error is returned.
+		// With skip the file is dropped, and with record it is dropped and the
+		// error is listed by the ansible_file_error table.
+		return nil, handleParseErrors(ctx, d, function, file.Path, []ansibleParseError{newAnsibleParseError(log.Err, nil)})
+	}
+	return &log, nil
+}
@@ -0,0 +1,115 @@
+package ansible
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleRunHostStats(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_run_host_stats",
+		Description: "The recap of each host of the runs of ansible-playbook, parsed from the output of the json stdout callback",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleRunLogFilePaths,
+			Hydrate:       listAnsibleRunHostStats,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file holding the output of the run.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "ok",
+				Description: "The number of tasks that were ok on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ok"),
+			},
+			{
+				Name:        "changed",
+				Description: "The number of tasks that changed something on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Changed"),
+			},
+			{
+				Name:        "failures",
+				Description: "The number of tasks that failed on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Failures"),
+			},
+			{
+				Name:        "unreachable",
+				Description: "The number of times the host was unreachable.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Unreachable"),
+			},
+			{
+				Name:        "skipped",
+				Description: "The number of tasks skipped on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Skipped"),
+			},
+			{
+				Name:        "rescued",
+				Description: "The number of task failures rescued by a block on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Rescued"),
+			},
+			{
+				Name:        "ignored",
+				Description: "The number of task failures ignored through ignore_errors on the host.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Ignored"),
+			},
+		},
+	}
+}
+
+type AnsibleRunHostStatsInfo struct {
+	Changed     int
+	Failures    int
+	Host        string
+	Ignored     int
+	Ok          int
+	Path        string
+	Rescued     int
+	Skipped     int
+	Unreachable int
+}
+
+//// LIST FUNCTION
+
+func listAnsibleRunHostStats(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	log, err := readAnsibleRunLog(ctx, d, file, "ansible_run_host_stats.listAnsibleRunHostStats")
+	if err != nil || log == nil {
+		return nil, err
+	}
+
+	quals := d.EqualsQuals
+	for _, stats := range log.HostStats {
+		if quals["host"] != nil && quals["host"].GetStringValue() != stats.Host {
+			continue
+		}
+		d.StreamListItem(ctx, stats)
+	}
+
+	return nil, nil
+}
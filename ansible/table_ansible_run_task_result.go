@@ -0,0 +1,170 @@
+package ansible
+
+import (
+	"context"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleRunTaskResult(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_run_task_result",
+		Description: "The result of each task on each host of the runs of ansible-playbook, parsed from the output of the json stdout callback",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsibleRunLogFilePaths,
+			Hydrate:       listAnsibleRunTaskResults,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+				{Name: "playbook_name", Require: plugin.Optional},
+				{Name: "task_name", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file holding the output of the run.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play of the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task, without the role prefix. Unnamed tasks are named after their action.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "role",
+				Description: "The name of the role of the task, if any.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_path",
+				Description: "Path to the file declaring the task, as recorded by the callback.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_line",
+				Description: "The line where the task is declared in its file.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "host",
+				Description: "The name of the host.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "action",
+				Description: "The module or action plugin run, as written in the task, e.g. ansible.builtin.copy.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "changed",
+				Description: "True if the task changed something on the host.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Changed"),
+			},
+			{
+				Name:        "failed",
+				Description: "True if the task failed on the host, including failures ignored through ignore_errors.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Failed"),
+			},
+			{
+				Name:        "skipped",
+				Description: "True if the task was skipped on the host.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Skipped"),
+			},
+			{
+				Name:        "unreachable",
+				Description: "True if the host was unreachable.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Unreachable"),
+			},
+			{
+				Name:        "started_at",
+				Description: "Time when the task started, on all its hosts.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "ended_at",
+				Description: "Time when the task ended, on all its hosts.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "duration",
+				Description: "The duration of the task on all its hosts, in seconds. The callback does not record the duration on each host.",
+				Type:        proto.ColumnType_DOUBLE,
+				Transform:   transform.FromField("Duration"),
+			},
+			{
+				Name:        "msg",
+				Description: "The message of the result, e.g. the error of a failed task. Messages that are not strings are JSON encoded.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "result",
+				Description: "The result returned by the module on the host.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+type AnsibleRunTaskResultInfo struct {
+	Action       string
+	Changed      bool
+	Duration     *float64
+	EndedAt      *time.Time
+	Failed       bool
+	Host         string
+	Msg          string
+	Path         string
+	PlaybookName string
+	Result       map[string]interface{}
+	Role         string
+	Skipped      bool
+	StartedAt    *time.Time
+	TaskLine     int
+	TaskName     string
+	TaskPath     string
+	Unreachable  bool
+}
+
+//// LIST FUNCTION
+
+func listAnsibleRunTaskResults(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+
+	log, err := readAnsibleRunLog(ctx, d, file, "ansible_run_task_result.listAnsibleRunTaskResults")
+	if err != nil || log == nil {
+		return nil, err
+	}
+
+	quals := d.EqualsQuals
+	for _, result := range log.TaskResults {
+		if quals["host"] != nil && quals["host"].GetStringValue() != result.Host {
+			continue
+		}
+		if quals["playbook_name"] != nil && quals["playbook_name"].GetStringValue() != result.PlaybookName {
+			continue
+		}
+		if quals["task_name"] != nil && quals["task_name"].GetStringValue() != result.TaskName {
+			continue
+		}
+		d.StreamListItem(ctx, result)
+	}
+
+	return nil, nil
+}
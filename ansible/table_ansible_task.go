@@ -3,7 +3,7 @@ package ansible
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
@@ -16,11 +16,18 @@ import (
 func tableAnsibleTask(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "ansible_task",
-		Description: "Tasks defined in an Ansible playbook",
+		Description: "Tasks defined in an Ansible playbook or tasks file",
 		List: &plugin.ListConfig{
 			ParentHydrate: resolveAnsiblePlaybookFilePaths,
 			Hydrate:       listAnsibleTasks,
-			KeyColumns:    plugin.OptionalColumns([]string{"path"}),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "name", Require: plugin.Optional},
+				{Name: "playbook_name", Require: plugin.Optional},
+				{Name: "module_fqcn", Require: plugin.Optional},
+				{Name: "become", Require: plugin.Optional},
+				{Name: "tags", Require: plugin.Optional, Operators: []string{"?"}},
+			},
 		},
 		Columns: []*plugin.Column{
 			{
@@ -28,9 +35,39 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 				Description: "Path to the file.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "start_line",
+				Description: "The line where the task starts in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "end_line",
+				Description: "The line where the task ends in the file.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "start_column",
+				Description: "The column where the task starts in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "source",
+				Description: "The original YAML text of the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "content_hash",
+				Description: "A SHA-256 hash of the task content. Comments, whitespace, quoting and key order do not change the hash.",
+				Type:        proto.ColumnType_STRING,
+			},
 			{
 				Name:        "playbook_name",
-				Description: "The name of the playbook where the task is defined.",
+				Description: "The name of the playbook where the task is defined. Null for tasks files.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "section",
+				Description: "The play section where the task is defined, e.g. pre_tasks, tasks, post_tasks or handlers. Null for tasks files.",
 				Type:        proto.ColumnType_STRING,
 			},
 			{
@@ -38,6 +75,22 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 				Description: "The name of the playbook.",
 				Type:        proto.ColumnType_STRING,
 			},
+			{
+				Name:        "module",
+				Description: "The name of the module called by the task, as written in the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "module_fqcn",
+				Description: "The fully qualified collection name of the module called by the task. Short names are only resolved for ansible.builtin modules.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ModuleFQCN"),
+			},
+			{
+				Name:        "args",
+				Description: "The arguments passed to the module, including inline key=value arguments and the task level args keyword.",
+				Type:        proto.ColumnType_JSON,
+			},
 			{
 				Name:        "any_errors_fatal",
 				Description: "Force any un-handled task errors on any host to propagate to all hosts and end the play.",
@@ -45,15 +98,29 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "async",
-				Description: "Run a task asynchronously if the C(action) supports this; value is maximum runtime in seconds.",
+				Description: "Run a task asynchronously if the C(action) supports this; value is maximum runtime in seconds. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Async.Value"),
+			},
+			{
+				Name:        "async_raw",
+				Description: "The async keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Async.Raw"),
 			},
 
 			// Become directives
 			{
 				Name:        "become",
-				Description: "Controls if privilege escalation is used or not on task execution. If true, privilege escalation is activated.",
+				Description: "Controls if privilege escalation is used or not on task execution. If true, privilege escalation is activated. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Become.Value"),
+			},
+			{
+				Name:        "become_raw",
+				Description: "The become keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Become.Raw"),
 			},
 			{
 				Name:        "become_user",
@@ -72,13 +139,27 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "changed_when",
-				Description: "Conditional expression that overrides the task's normal 'changed' status.",
+				Description: "Conditional expression that overrides the task's normal 'changed' status. Null if given as a list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ChangedWhen.Value"),
+			},
+			{
+				Name:        "changed_when_raw",
+				Description: "The changed_when keyword as written in the file: a condition or a list of conditions that must all be true.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("ChangedWhen.Raw"),
 			},
 			{
 				Name:        "check_mode",
-				Description: "A boolean that controls if a task is executed in 'check' mode.",
+				Description: "A boolean that controls if a task is executed in 'check' mode. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("CheckMode.Value"),
+			},
+			{
+				Name:        "check_mode_raw",
+				Description: "The check_mode keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("CheckMode.Raw"),
 			},
 			{
 				Name:        "connection",
@@ -92,13 +173,27 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "delay",
-				Description: "Number of seconds to delay between retries.",
+				Description: "Number of seconds to delay between retries. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Delay.Value"),
+			},
+			{
+				Name:        "delay_raw",
+				Description: "The delay keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Delay.Raw"),
 			},
 			{
 				Name:        "delegate_facts",
-				Description: "Boolean that allows you to apply facts to a delegated host instead of inventory_hostname.",
+				Description: "Boolean that allows you to apply facts to a delegated host instead of inventory_hostname. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("DelegateFacts.Value"),
+			},
+			{
+				Name:        "delegate_facts_raw",
+				Description: "The delegate_facts keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("DelegateFacts.Raw"),
 			},
 			{
 				Name:        "delegate_to",
@@ -107,28 +202,63 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "diff",
-				Description: "Toggle to make tasks return 'diff' information or not.",
+				Description: "Toggle to make tasks return 'diff' information or not. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Diff.Value"),
+			},
+			{
+				Name:        "diff_raw",
+				Description: "The diff keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Diff.Raw"),
 			},
 			{
 				Name:        "failed_when",
-				Description: "Conditional expression that overrides the task's normal 'failed' status.",
+				Description: "Conditional expression that overrides the task's normal 'failed' status. Null if given as a list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("FailedWhen.Value"),
+			},
+			{
+				Name:        "failed_when_raw",
+				Description: "The failed_when keyword as written in the file: a condition or a list of conditions that must all be true.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("FailedWhen.Raw"),
 			},
 			{
 				Name:        "ignore_errors",
-				Description: "Boolean that allows you to ignore task failures and continue with play.",
+				Description: "Boolean that allows you to ignore task failures and continue with play. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IgnoreErrors.Value"),
+			},
+			{
+				Name:        "ignore_errors_raw",
+				Description: "The ignore_errors keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IgnoreErrors.Raw"),
 			},
 			{
 				Name:        "ignore_unreachable",
-				Description: "Boolean that allows you to ignore task failures due to an unreachable host and continue with the play.",
+				Description: "Boolean that allows you to ignore task failures due to an unreachable host and continue with the play. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("IgnoreUnreachable.Value"),
+			},
+			{
+				Name:        "ignore_unreachable_raw",
+				Description: "The ignore_unreachable keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("IgnoreUnreachable.Raw"),
 			},
 			{
 				Name:        "loop",
-				Description: "Takes a list for the task to iterate over, saving each list element into the item variable (configurable via loop_control)",
+				Description: "Takes a list for the task to iterate over, saving each list element into the item variable (configurable via loop_control). Null if given as a list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Loop.Value"),
+			},
+			{
+				Name:        "loop_raw",
+				Description: "The loop keyword as written in the file: a list of items or a Jinja2 expression returning one.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Loop.Raw"),
 			},
 			{
 				Name:        "loop_action",
@@ -137,18 +267,39 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "no_log",
-				Description: "Boolean that controls information disclosure.",
+				Description: "Boolean that controls information disclosure. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("NoLog.Value"),
+			},
+			{
+				Name:        "no_log_raw",
+				Description: "The no_log keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("NoLog.Raw"),
 			},
 			{
 				Name:        "poll",
-				Description: "Sets the polling interval in seconds for async tasks (default 10s).",
+				Description: "Sets the polling interval in seconds for async tasks (default 10s). Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Poll.Value"),
+			},
+			{
+				Name:        "poll_raw",
+				Description: "The poll keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Poll.Raw"),
 			},
 			{
 				Name:        "port",
-				Description: "Used to override the default port used in a connection.",
+				Description: "Used to override the default port used in a connection. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Port.Value"),
+			},
+			{
+				Name:        "port_raw",
+				Description: "The port keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Port.Raw"),
 			},
 			{
 				Name:        "register",
@@ -162,33 +313,75 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "retries",
-				Description: "Number of retries before giving up in a until loop.",
+				Description: "Number of retries before giving up in a until loop. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Retries.Value"),
+			},
+			{
+				Name:        "retries_raw",
+				Description: "The retries keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Retries.Raw"),
 			},
 			{
 				Name:        "run_once",
-				Description: "Boolean that will bypass the host loop, forcing the task to attempt to execute on the first host available and afterwards apply any results and facts to all active hosts in the same batch.",
+				Description: "Boolean that will bypass the host loop, forcing the task to attempt to execute on the first host available and afterwards apply any results and facts to all active hosts in the same batch. Null if the value is templated.",
 				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("RunOnce.Value"),
+			},
+			{
+				Name:        "run_once_raw",
+				Description: "The run_once keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("RunOnce.Raw"),
 			},
 			{
 				Name:        "throttle",
-				Description: "Limit number of concurrent task runs on task, block and playbook level.",
+				Description: "Limit number of concurrent task runs on task, block and playbook level. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Throttle.Value"),
+			},
+			{
+				Name:        "throttle_raw",
+				Description: "The throttle keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Throttle.Raw"),
 			},
 			{
 				Name:        "timeout",
-				Description: "Time limit for task to execute in, if exceeded Ansible will interrupt and fail the task.",
+				Description: "Time limit for task to execute in, if exceeded Ansible will interrupt and fail the task. Null if the value is templated.",
 				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Timeout.Value"),
+			},
+			{
+				Name:        "timeout_raw",
+				Description: "The timeout keyword as written in the file, e.g. a Jinja2 expression.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Timeout.Raw"),
 			},
 			{
 				Name:        "until",
-				Description: "This keyword implies a 'retries loop' that will go on until the condition supplied here is met or we hit the retries limit.",
+				Description: "This keyword implies a 'retries loop' that will go on until the condition supplied here is met or we hit the retries limit. Null if given as a list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Until.Value"),
+			},
+			{
+				Name:        "until_raw",
+				Description: "The until keyword as written in the file: a condition or a list of conditions that must all be true.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Until.Raw"),
 			},
 			{
 				Name:        "when",
-				Description: "Conditional expression, determines if an iteration of a task is run or not.",
+				Description: "Conditional expression, determines if an iteration of a task is run or not. Null if given as a list.",
 				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("When.Value"),
+			},
+			{
+				Name:        "when_raw",
+				Description: "The when keyword as written in the file: a condition or a list of conditions that must all be true.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("When.Raw"),
 			},
 			// JSON columns
 			{
@@ -213,8 +406,15 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 			},
 			{
 				Name:        "tags",
-				Description: "A list of tags applied to the task or included tasks.",
+				Description: "A list of tags applied to the task or included tasks. A comma separated string of tags is split into a list. Null if any tag is templated.",
 				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Tags.Value"),
+			},
+			{
+				Name:        "tags_raw",
+				Description: "The tags keyword as written in the file: a list of tags or a comma separated string of tags.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Tags.Raw"),
 			},
 			// Can't use 'group' as a column since it is a reserved word
 			{
@@ -235,57 +435,86 @@ func tableAnsibleTask(ctx context.Context) *plugin.Table {
 				Description: "The dictionary/map of variables.",
 				Type:        proto.ColumnType_JSON,
 			},
+			{
+				Name:        "validation_errors",
+				Description: "A list of problems found by checking the module arguments against the module's documented options: unknown options, missing required options, wrong types and values outside the allowed choices. Options of the doc fragments extended by the module are known too, and unknown options are not reported if a fragment cannot be found. Empty if the arguments are valid, and null if the module's options are unknown.",
+				Type:        proto.ColumnType_JSON,
+				Hydrate:     getAnsibleTaskValidationErrors,
+				Transform:   transform.FromValue(),
+			},
 		},
 	}
 }
 
-type AnsiblePlaybookTask struct {
-	Name  string        `cty:"name"`
-	Tasks []AnsibleTask `cty:"tasks"`
+type AnsibleTask struct {
+	AnyErrorsFatal    string                 `cty:"any_errors_fatal" yaml:"any_errors_fatal"`
+	Args              map[string]interface{} `cty:"-" yaml:"-"`
+	Async             ansibleIntKeyword      `cty:"async" yaml:"async"`
+	Become            ansibleBoolKeyword     `cty:"become" yaml:"become"`
+	BecomeFlags       string                 `cty:"become_flags" yaml:"become_flags"`
+	BecomeMethod      string                 `cty:"become_method" yaml:"become_method"`
+	BecomeUser        string                 `cty:"become_user" yaml:"become_user"`
+	ChangedWhen       ansibleStringKeyword   `cty:"changed_when" yaml:"changed_when"`
+	CheckMode         ansibleBoolKeyword     `cty:"check_mode" yaml:"check_mode"`
+	Collections       interface{}            `cty:"collections" yaml:"collections"`
+	ContentHash       string                 `cty:"-" yaml:"-"`
+	Connection        interface{}            `cty:"connection" yaml:"connection"`
+	Debugger          string                 `cty:"debugger" yaml:"debugger"`
+	Delay             ansibleIntKeyword      `cty:"delay" yaml:"delay"`
+	DelegateFacts     ansibleBoolKeyword     `cty:"delegate_facts" yaml:"delegate_facts"`
+	DelegateTo        string                 `cty:"delegate_to" yaml:"delegate_to"`
+	Diff              ansibleBoolKeyword     `cty:"diff" yaml:"diff"`
+	EndLine           int                    `cty:"-" yaml:"-"`
+	FailedWhen        ansibleStringKeyword   `cty:"failed_when" yaml:"failed_when"`
+	Group             interface{}            `cty:"group" yaml:"group"`
+	IgnoreErrors      ansibleBoolKeyword     `cty:"ignore_errors" yaml:"ignore_errors"`
+	IgnoreUnreachable ansibleBoolKeyword     `cty:"ignore_unreachable" yaml:"ignore_unreachable"`
+	Loop              ansibleStringKeyword   `cty:"loop" yaml:"loop"`
+	LoopAction        string                 `cty:"loop_action" yaml:"loop_action"`
+	LoopControl       interface{}            `cty:"loop_control" yaml:"loop_control"`
+	Module            string                 `cty:"-" yaml:"-"`
+	ModuleDefaults    interface{}            `cty:"module_defaults" yaml:"module_defaults"`
+	ModuleFQCN        string                 `cty:"-" yaml:"-"`
+	Name              string                 `cty:"name" yaml:"name"`
+	NoLog             ansibleBoolKeyword     `cty:"no_log" yaml:"no_log"`
+	Notify            interface{}            `cty:"notify" yaml:"notify"`
+	Path              string                 `cty:"-"`
+	PlaybookName      string                 `cty:"-"`
+	Poll              ansibleIntKeyword      `cty:"poll" yaml:"poll"`
+	Port              ansibleIntKeyword      `cty:"port" yaml:"port"`
+	Register          string                 `cty:"register" yaml:"register"`
+	RemoteUser        string                 `cty:"remote_user" yaml:"remote_user"`
+	Retries           ansibleIntKeyword      `cty:"retries" yaml:"retries"`
+	RunOnce           ansibleBoolKeyword     `cty:"run_once" yaml:"run_once"`
+	Section           string                 `cty:"-" yaml:"-"`
+	Source            string                 `cty:"-" yaml:"-"`
+	StartColumn       int                    `cty:"-" yaml:"-"`
+	StartLine         int                    `cty:"-" yaml:"-"`
+	Tags              ansibleTagsKeyword     `cty:"tags" yaml:"tags"`
+	Throttle          ansibleIntKeyword      `cty:"throttle" yaml:"throttle"`
+	Timeout           ansibleIntKeyword      `cty:"timeout" yaml:"timeout"`
+	Until             ansibleStringKeyword   `cty:"until" yaml:"until"`
+	User              interface{}            `cty:"user" yaml:"user"`
+	Vars              interface{}            `cty:"vars" yaml:"vars"`
+	When              ansibleStringKeyword   `cty:"when" yaml:"when"`
 }
 
-type AnsibleTask struct {
-	AnyErrorsFatal    string      `cty:"any_errors_fatal"`
-	Async             int         `cty:"async"`
-	Become            bool        `cty:"become"`
-	BecomeFlags       string      `cty:"become_flags"`
-	BecomeMethod      string      `cty:"become_method"`
-	BecomeUser        string      `cty:"become_user"`
-	ChangedWhen       string      `cty:"changed_when"`
-	CheckMode         bool        `cty:"check_mode"`
-	Collections       interface{} `cty:"collections"`
-	Connection        interface{} `cty:"connection"`
-	Debugger          string      `cty:"debugger"`
-	Delay             int         `cty:"delay"`
-	DelegateFacts     bool        `cty:"delegate_facts"`
-	DelegateTo        string      `cty:"delegate_to"`
-	Diff              bool        `cty:"diff"`
-	FailedWhen        string      `cty:"failed_when"`
-	Group             interface{} `cty:"group"`
-	IgnoreErrors      bool        `cty:"ignore_errors"`
-	IgnoreUnreachable bool        `cty:"ignore_unreachable"`
-	Loop              string      `cty:"loop"`
-	LoopAction        string      `cty:"loop_action"`
-	LoopControl       interface{} `cty:"loop_control"`
-	ModuleDefaults    interface{} `cty:"module_defaults"`
-	Name              string      `cty:"name"`
-	NoLog             bool        `cty:"no_log"`
-	Notify            interface{} `cty:"notify"`
-	Path              string      `cty:"-"`
-	PlaybookName      string      `cty:"-"`
-	Poll              int         `cty:"poll"`
-	Port              int         `cty:"port"`
-	Register          string      `cty:"register"`
-	RemoteUser        string      `cty:"remote_user"`
-	Retries           int         `cty:"retries"`
-	RunOnce           bool        `cty:"run_once"`
-	Tags              []string    `cty:"tags"`
-	Throttle          int         `cty:"throttle"`
-	Timeout           int         `cty:"timeout"`
-	Until             string      `cty:"until"`
-	User              interface{} `cty:"user"`
-	Vars              interface{} `cty:"vars"`
-	When              string      `cty:"when"`
+// UnmarshalYAML decodes the task keywords, then detects the module called by
+// the task from the remaining key.
+func (t *AnsibleTask) UnmarshalYAML(value *yaml.Node) error {
+	type plain AnsibleTask
+	if err := value.Decode((*plain)(t)); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	t.Module, t.Args = parseTaskModule(raw)
+	t.ModuleFQCN = resolveModuleFQCN(t.Module)
+
+	return nil
 }
 
 //// LIST FUNCTION
@@ -293,30 +522,118 @@ type AnsibleTask struct {
 func listAnsibleTasks(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	// The path comes from a parent hydrate, defaulting to the config paths or
 	// available by the optional key column
-	path := h.Item.(filePath).Path
+	file := h.Item.(filePath)
+	path := file.Path
 
-	content, err := os.ReadFile(path)
+	// Skip the files that do not contain the values of the quals, e.g. the
+	// name of the task, before parsing them
+	filter := newAnsibleQualFilter(d, "module_fqcn", "name", "playbook_name")
+	found, err := scanAnsibleFile(ctx, d, "tasks", file, filter.scanWords())
 	if err != nil {
 		plugin.Logger(ctx).Error("ansible_task.listAnsibleTasks", "file_error", err, "path", path)
 		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
 	}
+	if !found {
+		return nil, nil
+	}
 
-	// Decoding the file content
-	var data []AnsiblePlaybookTask
-	err = yaml.Unmarshal(content, &data)
+	classification, err := getAnsibleFileClassification(ctx, d, file)
 	if err != nil {
-		plugin.Logger(ctx).Error("ansible_task.listAnsibleTasks", "parse_error", err, "path", path)
-		return nil, fmt.Errorf("failed to unmarshal file content %s: %v", path, err)
+		plugin.Logger(ctx).Error("ansible_task.listAnsibleTasks", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
 	}
 
-	for _, play := range data {
-		for _, task := range play.Tasks {
-			task.Path = path
-			task.PlaybookName = play.Name
+	// Skip the files that are not playbooks or tasks files, e.g. vars files
+	// matched by a wide glob
+	if !classification.isPlaybookCandidate() && classification.Kind != fileKindTasks {
+		plugin.Logger(ctx).Debug("ansible_task.listAnsibleTasks", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
 
-			d.StreamListItem(ctx, task)
+	tasks, parseErrors, err := getAnsibleTasks(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_task.listAnsibleTasks", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_task.listAnsibleTasks", path, parseErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		if !filter.matchTask(task) {
+			continue
 		}
+		task.Path = path
+
+		d.StreamListItem(ctx, task)
 	}
 
 	return nil, nil
 }
+
+//// HYDRATE FUNCTIONS
+
+func getAnsibleTaskValidationErrors(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	task := h.Item.(AnsibleTask)
+	if task.ModuleFQCN == "" {
+		return nil, nil
+	}
+
+	index, err := getAnsibleModuleSchemaIndex(ctx, d, h)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_task.getAnsibleTaskValidationErrors", "schema_error", err)
+		return nil, err
+	}
+
+	schema := index.(*ansibleModuleSchemaIndex).lookup(task.ModuleFQCN)
+	if schema == nil {
+		return nil, nil
+	}
+
+	return validateModuleArgs(schema, task.Args), nil
+}
+
+// decodeAnsibleTasks decodes the tasks of the plays of a playbook, including
+// their pre_tasks, post_tasks and handlers and the tasks nested in blocks, or
+// the tasks of a tasks file. A task that cannot be decoded is returned as a
+// parse error, so that the other tasks of the file can still be listed.
+func decodeAnsibleTasks(content []byte) ([]AnsibleTask, []ansibleParseError) {
+	// Decoding the file content, keeping the task nodes for their location
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+
+	var tasks []AnsibleTask
+	var parseErrors []ansibleParseError
+	lines := strings.Split(string(content), "\n")
+	walkAnsibleTaskNodes(&doc, func(t ansibleTaskNode) {
+		var task AnsibleTask
+		err := t.Node.Decode(&task)
+		if err != nil {
+			parseErrors = append(parseErrors, newAnsibleParseError(err, t.Node))
+			return
+		}
+
+		contentHash, err := yamlNodeContentHash(t.Node)
+		if err != nil {
+			parseErrors = append(parseErrors, newAnsibleParseError(err, t.Node))
+			return
+		}
+
+		location := yamlNodeLocation(t.Node, lines)
+		task.ContentHash = contentHash
+		task.EndLine = location.EndLine
+		task.PlaybookName = t.PlaybookName
+		task.Section = t.Section
+		task.Source = yamlNodeSource(location, lines)
+		task.StartColumn = location.StartColumn
+		task.StartLine = location.StartLine
+
+		tasks = append(tasks, task)
+	})
+
+	return tasks, parseErrors
+}
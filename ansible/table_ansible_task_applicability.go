@@ -0,0 +1,672 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+
+	filehelpers "github.com/turbot/go-kit/files"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleTaskApplicability(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_task_applicability",
+		Description: "Whether the tasks of the plays run on each targeted host, according to their when conditions and the inventory variables",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsiblePlaybookFilePaths,
+			Hydrate:       listAnsibleTaskApplicabilities,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+				{Name: "host", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the playbook file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play holding the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line where the task starts in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "host",
+				Description: "The name of a host targeted by the play, from the inventories.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "result",
+				Description: "Whether the task runs on the host. Possible values are: true, false, unknown (the conditions need values only known when the playbook runs, e.g. facts or registered variables).",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "reason",
+				Description: "Why the result is false or unknown, e.g. the condition that is false or the variable that cannot be known.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "conditions",
+				Description: "The when conditions of the task and of the blocks holding it, outermost first.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+type AnsibleTaskApplicabilityInfo struct {
+	Conditions   []string
+	Host         string
+	Line         int
+	Path         string
+	PlaybookName string
+	Reason       string
+	Result       string
+	TaskName     string
+}
+
+// ansibleConditionalTask is a task of a play, along with what its conditions
+// are evaluated against.
+type ansibleConditionalTask struct {
+	// Conditions are the when conditions of the blocks holding the task,
+	// then of the task
+	Conditions []string
+	// Hosts are the host patterns of the play
+	Hosts        []string
+	Line         int
+	LoopVar      string
+	PlaybookName string
+	TaskName     string
+	// Vars are the play, block and task vars, the innermost taking
+	// precedence. Templated values are unknown.
+	Vars map[string]interface{}
+}
+
+// ansibleInventoryHost is a host merged from every inventory file.
+type ansibleInventoryHost struct {
+	Groups map[string]bool
+	Name   string
+	Vars   map[string]string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleTaskApplicabilities(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	classification, err := getAnsibleFileClassification(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_task_applicability.listAnsibleTaskApplicabilities", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	// Skip the files that are not playbooks, e.g. tasks files, which have no
+	// hosts
+	if !classification.isPlaybookCandidate() {
+		plugin.Logger(ctx).Debug("ansible_task_applicability.listAnsibleTaskApplicabilities", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
+
+	tasks, parseErrors, err := getAnsibleConditionalTasks(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_task_applicability.listAnsibleTaskApplicabilities", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_task_applicability.listAnsibleTaskApplicabilities", path, parseErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, inventories, err := listAnsibleInventoryHosts(ctx, d, file.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	varsSources, err := loadAnsibleHostVarsSources(ctx, d, file.Metrics, path, inventories)
+	if err != nil {
+		return nil, err
+	}
+	runtimeVars, err := getAnsibleRuntimeVariables(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_task_applicability.listAnsibleTaskApplicabilities", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	quals := d.EqualsQuals
+	for _, task := range tasks {
+		targets, ok := matchAnsibleHostPatterns(task.Hosts, hosts)
+		if !ok {
+			plugin.Logger(ctx).Debug("ansible_task_applicability.listAnsibleTaskApplicabilities", "unresolved_hosts", task.Hosts, "path", path)
+			continue
+		}
+		for _, name := range targets {
+			if quals["host"] != nil && quals["host"].GetStringValue() != name {
+				continue
+			}
+
+			result, reason := evaluateAnsibleConditions(task.Conditions, newAnsibleHostLookup(hosts[name], hosts, task, runtimeVars, varsSources))
+			d.StreamListItem(ctx, AnsibleTaskApplicabilityInfo{
+				Conditions:   task.Conditions,
+				Host:         name,
+				Line:         task.Line,
+				Path:         path,
+				PlaybookName: task.PlaybookName,
+				Reason:       reason,
+				Result:       result,
+				TaskName:     task.TaskName,
+			})
+		}
+	}
+
+	return nil, nil
+}
+
+// evaluateAnsibleConditions evaluates conditions that must all be true. A
+// false condition makes the result false even if another one is unknown.
+func evaluateAnsibleConditions(conditions []string, lookup func(name string) interface{}) (string, string) {
+	result, reason := conditionTrue, ""
+	for _, condition := range conditions {
+		value, why := evaluateAnsibleCondition(condition, lookup)
+		switch value {
+		case conditionFalse:
+			return conditionFalse, fmt.Sprintf("condition %q is false", condition)
+		case conditionUnknown:
+			if result == conditionTrue {
+				result, reason = conditionUnknown, fmt.Sprintf("condition %q: %s", condition, why)
+			}
+		}
+	}
+	return result, reason
+}
+
+// extractAnsibleConditionalTasks lists the tasks of the plays of a playbook,
+// along with the conditions and vars of the blocks holding them.
+func extractAnsibleConditionalTasks(content []byte) ([]ansibleConditionalTask, []ansibleParseError) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+	root := documentRoot(&doc)
+	if root == nil || root.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+
+	var tasks []ansibleConditionalTask
+	for _, play := range root.Content {
+		hostsNode := mappingValue(play, "hosts")
+		if hostsNode == nil {
+			continue
+		}
+		base := ansibleConditionalTask{Vars: staticAnsibleVars(mappingValue(play, "vars"), nil)}
+		if name := mappingValue(play, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			base.PlaybookName = name.Value
+		}
+		switch hostsNode.Kind {
+		case yaml.ScalarNode:
+			base.Hosts = []string{hostsNode.Value}
+		case yaml.SequenceNode:
+			for _, item := range hostsNode.Content {
+				base.Hosts = append(base.Hosts, item.Value)
+			}
+		}
+
+		for _, section := range ansibleTaskSections {
+			if list := mappingValue(play, section); list != nil {
+				walkAnsibleConditionalTasks(list, base, &tasks)
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// walkAnsibleConditionalTasks walks a list of tasks, adding the conditions
+// and vars of each block to those of its tasks.
+func walkAnsibleConditionalTasks(list *yaml.Node, parent ansibleConditionalTask, tasks *[]ansibleConditionalTask) {
+	if list.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, node := range list.Content {
+		if node.Kind != yaml.MappingNode {
+			continue
+		}
+		task := parent
+		task.Conditions = append(append([]string{}, parent.Conditions...), ansibleWhenConditions(mappingValue(node, "when"))...)
+		task.Vars = staticAnsibleVars(mappingValue(node, "vars"), parent.Vars)
+
+		isBlock := false
+		for _, key := range ansibleBlockSections {
+			if nested := mappingValue(node, key); nested != nil {
+				isBlock = true
+				walkAnsibleConditionalTasks(nested, task, tasks)
+			}
+		}
+		if isBlock {
+			continue
+		}
+
+		task.Line = node.Line
+		if name := mappingValue(node, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			task.TaskName = name.Value
+		}
+		// The conditions of a loop are evaluated for each item
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; key == "loop" || strings.HasPrefix(key, "with_") {
+				task.LoopVar = "item"
+				if loopVar := mappingValue(mappingValue(node, "loop_control"), "loop_var"); loopVar != nil && loopVar.Kind == yaml.ScalarNode {
+					task.LoopVar = loopVar.Value
+				}
+			}
+		}
+		*tasks = append(*tasks, task)
+	}
+}
+
+// ansibleWhenConditions returns the conditions of a when keyword, which is a
+// single condition or a list of conditions.
+func ansibleWhenConditions(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}
+	case yaml.SequenceNode:
+		var conditions []string
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				conditions = append(conditions, item.Value)
+			}
+		}
+		return conditions
+	}
+	return nil
+}
+
+// staticAnsibleVars returns the vars of a play, block or task added to those
+// of its parent. The values using templates are unknown, as they may depend
+// on facts.
+func staticAnsibleVars(node *yaml.Node, parent map[string]interface{}) map[string]interface{} {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return parent
+	}
+	vars := make(map[string]interface{}, len(parent)+len(node.Content)/2)
+	for name, value := range parent {
+		vars[name] = value
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name := node.Content[i].Value
+		var value interface{}
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			vars[name] = jinjaUnknown{Reason: fmt.Sprintf("variable %s cannot be decoded", name)}
+			continue
+		}
+		value, templated := jinjaValueFromYAML(normalizeYAMLValue(value))
+		if templated {
+			vars[name] = jinjaUnknown{Reason: fmt.Sprintf("variable %s is templated", name)}
+			continue
+		}
+		vars[name] = value
+	}
+	return vars
+}
+
+// jinjaValueFromYAML converts a decoded YAML value to the types of the
+// evaluator, and reports whether it holds a template.
+func jinjaValueFromYAML(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), false
+	case uint64:
+		return int64(v), false
+	case string:
+		return v, isTemplated(v)
+	case []interface{}:
+		templated := false
+		for i, item := range v {
+			var t bool
+			v[i], t = jinjaValueFromYAML(item)
+			templated = templated || t
+		}
+		return v, templated
+	case map[string]interface{}:
+		templated := false
+		for key, item := range v {
+			var t bool
+			v[key], t = jinjaValueFromYAML(item)
+			templated = templated || t
+		}
+		return v, templated
+	}
+	return value, false
+}
+
+// getAnsibleRuntimeVariables returns the variables that a playbook defines
+// while it runs, e.g. registered variables, or through files that are not
+// read, along with the reason they are unknown.
+func getAnsibleRuntimeVariables(ctx context.Context, d *plugin.QueryData, file filePath) (map[string]string, error) {
+	definitions, _, err := getAnsibleVariableDefinitions(ctx, d, file, fileKindPlaybook)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := map[string]string{
+		variableSourceIncludeVars: "is loaded by include_vars",
+		variableSourceRegister:    "is registered by a task",
+		variableSourceRoleParams:  "is a role parameter",
+		variableSourceSetFact:     "is set by set_fact",
+		variableSourceVarsFile:    "is loaded from a vars file",
+		variableSourceVarsPrompt:  "is prompted for",
+	}
+	runtimeVars := map[string]string{}
+	for _, definition := range definitions.Definitions {
+		if reason, ok := reasons[definition.Source]; ok {
+			runtimeVars[definition.Name] = fmt.Sprintf("variable %s %s", definition.Name, reason)
+		}
+	}
+	for _, varsFile := range definitions.VarsFiles {
+		loaded, _, err := getAnsibleVariableDefinitions(ctx, d, filePath{Metrics: file.Metrics, Path: varsFile}, fileKindVars)
+		if err != nil {
+			// A missing vars file only fails the play when it runs
+			continue
+		}
+		for _, definition := range loaded.Definitions {
+			runtimeVars[definition.Name] = fmt.Sprintf("variable %s is loaded from a vars file", definition.Name)
+		}
+	}
+
+	return runtimeVars, nil
+}
+
+// listAnsibleInventoryHosts returns the hosts of every file matched by the
+// inventory_file_paths config argument, by name, along with the paths of the
+// files. The variables of the later files take precedence.
+func listAnsibleInventoryHosts(ctx context.Context, d *plugin.QueryData, metrics *parseMetrics) (map[string]*ansibleInventoryHost, []string, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	if ansibleConfig.InventoryFilePaths == nil {
+		return nil, nil, errors.New("inventory_file_paths must be configured")
+	}
+
+	hosts := map[string]*ansibleInventoryHost{}
+	var paths []string
+	for _, pattern := range ansibleConfig.InventoryFilePaths {
+		matches, err := d.GetSourceFiles(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range matches {
+			if filehelpers.DirectoryExists(path) {
+				continue
+			}
+			inventory, err := getAnsibleInventory(ctx, d, filePath{Metrics: metrics, Path: path})
+			if err != nil {
+				plugin.Logger(ctx).Error("listAnsibleInventoryHosts", "read_file_error", err, "path", path)
+				return nil, nil, err
+			}
+			if inventory.Err != nil {
+				err := handleParseErrors(ctx, d, "listAnsibleInventoryHosts", path, []ansibleParseError{newAnsibleParseError(inventory.Err, nil)})
+				if err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			paths = append(paths, path)
+
+			for name, host := range inventory.Data.Hosts {
+				merged, ok := hosts[name]
+				if !ok {
+					merged = &ansibleInventoryHost{Groups: map[string]bool{}, Name: name, Vars: map[string]string{}}
+					hosts[name] = merged
+				}
+				for group := range host.Groups {
+					merged.Groups[group] = true
+				}
+				for key, value := range host.Vars {
+					merged.Vars[key] = value
+				}
+			}
+		}
+	}
+
+	return hosts, paths, nil
+}
+
+// matchAnsibleHostPatterns returns the sorted names of the hosts targeted by
+// the host patterns of a play, e.g. webservers:&staging:!web3. It reports
+// false if a pattern is templated.
+func matchAnsibleHostPatterns(patterns []string, hosts map[string]*ansibleInventoryHost) ([]string, bool) {
+	var terms []string
+	for _, pattern := range patterns {
+		if isTemplated(pattern) {
+			return nil, false
+		}
+		terms = append(terms, splitAnsibleHostPattern(pattern)...)
+	}
+
+	selected := map[string]bool{}
+	var intersections, exclusions [][]string
+	for _, term := range terms {
+		// The order of the hosts of a group is not kept, so that subscripts
+		// such as webservers[0:2] cannot be resolved
+		if ansibleHostPatternSubscript.MatchString(strings.TrimLeft(term, "&!")) {
+			return nil, false
+		}
+		switch {
+		case term == "":
+		case strings.HasPrefix(term, "&"):
+			intersections = append(intersections, matchAnsibleHostPattern(term[1:], hosts))
+		case strings.HasPrefix(term, "!"):
+			exclusions = append(exclusions, matchAnsibleHostPattern(term[1:], hosts))
+		default:
+			for _, name := range matchAnsibleHostPattern(term, hosts) {
+				selected[name] = true
+			}
+		}
+	}
+	for _, names := range intersections {
+		keep := map[string]bool{}
+		for _, name := range names {
+			keep[name] = selected[name]
+		}
+		selected = keep
+	}
+	for _, names := range exclusions {
+		for _, name := range names {
+			delete(selected, name)
+		}
+	}
+
+	var names []string
+	for name, ok := range selected {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// ansibleHostPatternTerm matches the terms of a host pattern separated by
+// colons, which may be part of a bracketed subscript.
+var ansibleHostPatternTerm = regexp.MustCompile(`(?:[^\s:\[\]]|\[[^\]]*\])+`)
+
+// ansibleHostPatternSubscript matches a term selecting hosts of a group by
+// position, e.g. webservers[0], webservers[-1] or webservers[0:2].
+var ansibleHostPatternSubscript = regexp.MustCompile(`^.+\[(?:-?\d+|-?\d*:-?\d*)\]$`)
+
+// splitAnsibleHostPattern splits a host pattern into its terms the way
+// Ansible does: on commas if any, otherwise on colons outside of brackets,
+// unless the pattern is an IP address, e.g. an IPv6 address.
+func splitAnsibleHostPattern(pattern string) []string {
+	var terms []string
+	if strings.Contains(pattern, ",") {
+		terms = strings.Split(pattern, ",")
+	} else if net.ParseIP(strings.Trim(strings.TrimSpace(pattern), "[]")) != nil {
+		terms = []string{pattern}
+	} else {
+		terms = ansibleHostPatternTerm.FindAllString(pattern, -1)
+	}
+
+	var result []string
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// matchAnsibleHostPattern returns the hosts matching a single pattern: all,
+// a group, a host, a glob or a regular expression starting with ~, matched
+// against both group and host names.
+func matchAnsibleHostPattern(pattern string, hosts map[string]*ansibleInventoryHost) []string {
+	var match func(name string) bool
+	switch {
+	case pattern == "all" || pattern == "*":
+		match = func(string) bool { return true }
+	case strings.HasPrefix(pattern, "~"):
+		re, err := regexp.Compile(pattern[1:])
+		if err != nil {
+			return nil
+		}
+		match = func(name string) bool { return re.MatchString(name) }
+	case strings.ContainsAny(pattern, "*?["):
+		match = func(name string) bool {
+			ok, _ := filepath.Match(pattern, name)
+			return ok
+		}
+	default:
+		match = func(name string) bool { return name == pattern }
+	}
+
+	var names []string
+	for name, host := range hosts {
+		matched := match(name)
+		for group := range host.Groups {
+			matched = matched || match(group)
+		}
+		if matched {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// newAnsibleHostLookup returns the lookup of the variables of a task on a
+// host. The variables defined while the playbook runs, the facts and most
+// magic variables are unknown, as are the variables of roles, since the roles
+// a play uses are not resolved.
+func newAnsibleHostLookup(host *ansibleInventoryHost, hosts map[string]*ansibleInventoryHost, task ansibleConditionalTask, runtimeVars map[string]string, varsSources *ansibleHostVarsSources) func(name string) interface{} {
+	return func(name string) interface{} {
+		if reason, ok := runtimeVars[name]; ok {
+			return jinjaUnknown{Reason: reason}
+		}
+		if name == task.LoopVar {
+			return jinjaUnknown{Reason: fmt.Sprintf("variable %s is a loop variable", name)}
+		}
+		// Role vars take precedence over play vars
+		if roles, ok := varsSources.roleVars[name]; ok {
+			return jinjaUnknown{Reason: fmt.Sprintf("variable %s is defined by the vars of role %s", name, strings.Join(roles, ", "))}
+		}
+		if value, ok := task.Vars[name]; ok {
+			return value
+		}
+		if value, ok := varsSources.lookup(host, name); ok {
+			return value
+		}
+
+		switch name {
+		case "inventory_hostname":
+			return host.Name
+		case "inventory_hostname_short":
+			return strings.SplitN(host.Name, ".", 2)[0]
+		case "group_names":
+			var names []string
+			for group := range host.Groups {
+				if group != "all" && group != "ungrouped" {
+					names = append(names, group)
+				}
+			}
+			sort.Strings(names)
+			items := make([]interface{}, len(names))
+			for i, group := range names {
+				items[i] = group
+			}
+			return items
+		case "groups":
+			members := map[string][]string{}
+			for _, h := range hosts {
+				for group := range h.Groups {
+					members[group] = append(members[group], h.Name)
+				}
+			}
+			groups := make(map[string]interface{}, len(members))
+			for group, names := range members {
+				sort.Strings(names)
+				items := make([]interface{}, len(names))
+				for i, member := range names {
+					items[i] = member
+				}
+				groups[group] = items
+			}
+			return groups
+		}
+
+		if strings.HasPrefix(name, "ansible_") {
+			return jinjaUnknown{Reason: fmt.Sprintf("variable %s is a fact", name)}
+		}
+		if isAnsibleBuiltinVariable(name) {
+			return jinjaUnknown{Reason: fmt.Sprintf("variable %s is only known when the playbook runs", name)}
+		}
+		return jinjaUndefined{Name: name}
+	}
+}
+
+// inventoryValue converts the value of an INI inventory variable the way
+// Ansible does, as a Python literal if possible.
+func inventoryValue(value string) interface{} {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	switch value {
+	case "True":
+		return true
+	case "False":
+		return false
+	case "None":
+		return nil
+	}
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,371 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleTemplate(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_template",
+		Description: "Jinja2 templates of the playbooks and roles, along with the tasks rendering them",
+		List: &plugin.ListConfig{
+			Hydrate: listAnsibleTemplates,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "role",
+				Description: "The name of the role holding the template. Null for the templates of a playbook directory.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "size",
+				Description: "The size of the template, in bytes.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("Size"),
+			},
+			{
+				Name:        "variables",
+				Description: "The names of the variables used by the template.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "includes",
+				Description: "The templates included by the template, as written. Null items are names that are not literal strings.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "imports",
+				Description: "The templates imported by the template, as written.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "extends",
+				Description: "The templates extended by the template, as written.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "macros",
+				Description: "The names of the macros defined by the template.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "rendered_by",
+				Description: "The tasks rendering the template with the template or win_template module, with their path, playbook_name, task_name and line.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "unreferenced",
+				Description: "True if the template is neither rendered by a task nor included, imported or extended by another template.",
+				Type:        proto.ColumnType_BOOL,
+				Transform:   transform.FromField("Unreferenced"),
+			},
+			{
+				Name:        "parse_error",
+				Description: "The first Jinja2 syntax error of the template, if any. The content found before the error is still listed.",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+type AnsibleTemplateInfo struct {
+	Extends      []*string
+	Imports      []*string
+	Includes     []*string
+	Macros       []string
+	ParseError   string
+	Path         string
+	RenderedBy   []AnsibleTemplateTaskInfo
+	Role         string
+	Size         int64
+	Unreferenced bool
+	Variables    []string
+}
+
+// AnsibleTemplateTaskInfo is a task rendering a template.
+type AnsibleTemplateTaskInfo struct {
+	Line         int    `json:"line"`
+	Path         string `json:"path"`
+	PlaybookName string `json:"playbook_name,omitempty"`
+	TaskName     string `json:"task_name,omitempty"`
+}
+
+// ansibleTemplateTask is a task calling the template module, with the src
+// argument as written.
+type ansibleTemplateTask struct {
+	Line         int
+	PlaybookName string
+	Src          string
+	TaskName     string
+}
+
+// ansibleTemplateAnalysis is what a template uses and defines.
+type ansibleTemplateAnalysis struct {
+	Analysis   jinjaAnalysis
+	ParseError string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleTemplates(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	// Templates are found next to the playbook files and in their roles, and
+	// every playbook file is read to find the tasks rendering them
+	playbooks, err := listAnsiblePlaybookFiles(d)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := listAnsibleTemplateFiles(playbooks)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// #1 - Tasks rendering the templates
+
+	renderedBy := map[string][]AnsibleTemplateTaskInfo{}
+	for _, path := range playbooks {
+		file := filePath{Metrics: metrics, Path: path}
+		metrics.addFileListed()
+
+		classification, err := getAnsibleFileClassification(ctx, d, file)
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_template.listAnsibleTemplates", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		if !classification.ValidYAML || (classification.Kind != fileKindPlaybook && classification.Kind != fileKindTasks) {
+			continue
+		}
+
+		tasks, parseErrors, err := getAnsibleTemplateTasks(ctx, d, file)
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_template.listAnsibleTemplates", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		err = handleParseErrors(ctx, d, "ansible_template.listAnsibleTemplates", path, parseErrors)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, task := range tasks {
+			template := resolveAnsibleTemplateSrc(path, task.Src)
+			if template == "" {
+				continue
+			}
+			renderedBy[template] = append(renderedBy[template], AnsibleTemplateTaskInfo{
+				Line:         task.Line,
+				Path:         path,
+				PlaybookName: task.PlaybookName,
+				TaskName:     task.TaskName,
+			})
+		}
+	}
+
+	// #2 - Templates used by other templates
+
+	analyses := map[string]ansibleTemplateAnalysis{}
+	usedByTemplates := map[string]bool{}
+	for _, path := range templates {
+		file := filePath{Metrics: metrics, Path: path}
+		metrics.addFileListed()
+
+		analysis, err := getAnsibleTemplateAnalysis(ctx, d, file)
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_template.listAnsibleTemplates", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		analyses[path] = analysis
+
+		for _, usages := range [][]jinjaUsage{analysis.Analysis.Extends, analysis.Analysis.Imports, analysis.Analysis.Includes} {
+			for _, usage := range usages {
+				if used := resolveAnsibleTemplateName(path, usage.Name); used != "" {
+					usedByTemplates[used] = true
+				}
+			}
+		}
+	}
+
+	// #3 - Templates matching the quals
+
+	patterns, err := getPathPatterns(d)
+	if err != nil {
+		return nil, err
+	}
+	quals := d.EqualsQuals
+	for _, path := range templates {
+		if quals["path"] != nil && quals["path"].GetStringValue() != path {
+			continue
+		}
+		if !matchPathPatterns(patterns, path) {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_template.listAnsibleTemplates", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		analysis := analyses[path]
+		_, role := ansibleRoleFromPath(path)
+
+		d.StreamListItem(ctx, AnsibleTemplateInfo{
+			Extends:      jinjaUsageNames(analysis.Analysis.Extends),
+			Imports:      jinjaUsageNames(analysis.Analysis.Imports),
+			Includes:     jinjaUsageNames(analysis.Analysis.Includes),
+			Macros:       jinjaMacroNames(analysis.Analysis.Macros),
+			ParseError:   analysis.ParseError,
+			Path:         path,
+			RenderedBy:   renderedBy[path],
+			Role:         role,
+			Size:         info.Size(),
+			Unreferenced: len(renderedBy[path]) == 0 && !usedByTemplates[path],
+			Variables:    jinjaReferenceNames(analysis.Analysis.References),
+		})
+	}
+
+	return nil, nil
+}
+
+// extractAnsibleTemplateTasks lists the tasks of a playbook or tasks file
+// calling the template or win_template module with a literal src.
+func extractAnsibleTemplateTasks(content []byte) ([]ansibleTemplateTask, []ansibleParseError) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+
+	var tasks []ansibleTemplateTask
+	walkAnsibleTaskNodes(&doc, func(task ansibleTaskNode) {
+		var raw map[string]interface{}
+		if err := task.Node.Decode(&raw); err != nil {
+			return
+		}
+		module, args := parseTaskModule(raw)
+		if name := shortModuleName(module); name != "template" && name != "win_template" {
+			return
+		}
+		src, ok := args["src"].(string)
+		if !ok || src == "" || isTemplated(src) {
+			return
+		}
+
+		templateTask := ansibleTemplateTask{Line: task.Node.Line, PlaybookName: task.PlaybookName, Src: src}
+		if name, ok := raw["name"].(string); ok {
+			templateTask.TaskName = name
+		}
+		tasks = append(tasks, templateTask)
+	})
+
+	return tasks, nil
+}
+
+// resolveAnsibleTemplateSrc returns the template rendered by a task of the
+// given file, looking for the src in the templates directory of the role and
+// of the file, then next to them, like Ansible. It returns an empty string if
+// the template is not found.
+func resolveAnsibleTemplateSrc(taskPath string, src string) string {
+	if filepath.IsAbs(src) {
+		return filepath.Clean(src)
+	}
+
+	var dirs []string
+	if roleDir, _ := ansibleRoleFromPath(taskPath); roleDir != "" {
+		dirs = append(dirs, filepath.Join(roleDir, "templates"), roleDir)
+	}
+	dir := filepath.Dir(taskPath)
+	dirs = append(dirs, filepath.Join(dir, "templates"), dir)
+
+	return findExistingFile(dirs, src)
+}
+
+// resolveAnsibleTemplateName returns the template included, imported or
+// extended by a template, which is relative to the templates directory it
+// is in, or to its own directory. It returns an empty string if the template
+// is not found.
+func resolveAnsibleTemplateName(templatePath string, name string) string {
+	if name == "" {
+		return ""
+	}
+
+	var dirs []string
+	for dir := filepath.Dir(templatePath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == "templates" {
+			dirs = append(dirs, dir)
+			break
+		}
+	}
+	dirs = append(dirs, filepath.Dir(templatePath))
+
+	return findExistingFile(dirs, name)
+}
+
+// findExistingFile returns the first existing file named name in the
+// directories.
+func findExistingFile(dirs []string, name string) string {
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// jinjaUsageNames returns the names of the templates used by a template, with
+// nil for the names that are not literal strings.
+func jinjaUsageNames(usages []jinjaUsage) []*string {
+	var names []*string
+	for _, usage := range usages {
+		if usage.Name == "" {
+			names = append(names, nil)
+			continue
+		}
+		name := usage.Name
+		names = append(names, &name)
+	}
+	return names
+}
+
+func jinjaMacroNames(macros []jinjaUsage) []string {
+	var names []string
+	for _, macro := range macros {
+		names = append(names, macro.Name)
+	}
+	return names
+}
+
+// jinjaReferenceNames returns the sorted names of the variables used, once
+// each.
+func jinjaReferenceNames(references []jinjaReference) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, reference := range references {
+		if !seen[reference.Name] {
+			seen[reference.Name] = true
+			names = append(names, reference.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,210 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleTemplateUsage(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_template_usage",
+		Description: "Jinja2 filters, tests and lookup plugins used by Ansible plays, tasks, vars files and templates",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsiblePlaybookAndTemplateFilePaths,
+			Hydrate:       listAnsibleTemplateUsages,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "plugin_type",
+				Description: "The type of the plugin. Possible values are: filter, test, lookup.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "name",
+				Description: "The name of the plugin as written, e.g. json_query or community.general.json_query. Null for a lookup whose plugin name is not a literal string.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "fqcn",
+				Description: "The fully qualified name of the plugin the name resolves to, e.g. community.general.json_query. Null for Jinja2 plugins and unknown plugins.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "collection",
+				Description: "The collection of the plugin the name resolves to, e.g. community.general.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "resolution",
+				Description: "How the name is resolved. Possible values are: fqcn (fully qualified name), builtin (plugin of ansible-core), jinja2 (plugin of Jinja2), routed (short name that ansible-core routes to the collection the plugin moved to), unknown.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "scope",
+				Description: "Where the plugin is used. Possible values are: play, task, vars (for vars files), template.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play where the plugin is used.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task where the plugin is used.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "field",
+				Description: "The path to the string using the plugin in the play, task or vars file, e.g. when or ansible.builtin.template.src. Null for templates.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line where the plugin is used in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+		},
+	}
+}
+
+type AnsibleTemplateUsageInfo struct {
+	Collection   string
+	Field        string
+	FQCN         string
+	Line         int
+	Name         string
+	Path         string
+	PlaybookName string
+	PluginType   string
+	Resolution   string
+	Scope        string
+	TaskName     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleTemplateUsages(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths and
+	// their templates, or available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	classification, err := getAnsibleFileClassification(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_template_usage.listAnsibleTemplateUsages", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	// Skip the files that hold no expressions, e.g. inventories matched by a
+	// wide glob
+	switch {
+	case classification.isPlaybookCandidate(), classification.Kind == fileKindTasks, classification.Kind == fileKindVars, classification.Kind == fileKindTemplate:
+	default:
+		plugin.Logger(ctx).Debug("ansible_template_usage.listAnsibleTemplateUsages", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
+
+	usages, parseErrors, warnings, err := getAnsibleTemplateUsages(ctx, d, file, classification.Kind)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_template_usage.listAnsibleTemplateUsages", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_template_usage.listAnsibleTemplateUsages", path, parseErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plugins of an expression that cannot be parsed are only partially
+	// listed
+	for _, warning := range warnings {
+		plugin.Logger(ctx).Debug("ansible_template_usage.listAnsibleTemplateUsages", "expression_error", warning, "path", path, "line", warning.Line)
+	}
+
+	for _, usage := range usages {
+		d.StreamListItem(ctx, usage)
+	}
+
+	return nil, nil
+}
+
+// extractAnsibleTemplateUsages lists the filters, tests and lookups used by
+// the expressions of a playbook, tasks file, vars file or template. The
+// expressions that cannot be parsed are returned as warnings, along with the
+// plugins found before the error.
+func extractAnsibleTemplateUsages(content []byte, path string, kind string) ([]AnsibleTemplateUsageInfo, []ansibleParseError, []ansibleParseError) {
+	var usages []AnsibleTemplateUsageInfo
+	var warnings []ansibleParseError
+	add := func(analysis jinjaAnalysis, field ansibleExpressionField) {
+		usage := AnsibleTemplateUsageInfo{
+			Field:        field.Field,
+			Path:         path,
+			PlaybookName: field.PlaybookName,
+			Scope:        field.Scope,
+			TaskName:     field.TaskName,
+		}
+		for _, filter := range analysis.Filters {
+			usages = append(usages, newAnsibleTemplateUsage(usage, jinjaPluginFilter, filter.Name, filter.Line))
+		}
+		for _, test := range analysis.Tests {
+			usages = append(usages, newAnsibleTemplateUsage(usage, jinjaPluginTest, test.Name, test.Line))
+		}
+		for _, lookup := range analysis.Lookups {
+			usages = append(usages, newAnsibleTemplateUsage(usage, jinjaPluginLookup, lookup.Plugin, lookup.Line))
+		}
+	}
+
+	if kind == fileKindTemplate {
+		analysis, err := analyzeJinjaTemplate(string(content))
+		if err != nil {
+			warnings = append(warnings, ansibleParseError{Message: err.Error()})
+		}
+		add(analysis, ansibleExpressionField{Scope: expressionScopeTemplate})
+		sort.SliceStable(usages, func(i, j int) bool { return usages[i].Line < usages[j].Line })
+		return usages, nil, warnings
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}, nil
+	}
+	walkAnsibleExpressionFields(&doc, kind, func(field ansibleExpressionField) {
+		analysis, err := field.analyze()
+		if err != nil {
+			warnings = append(warnings, ansibleParseError{Line: field.Node.Line, Message: err.Error()})
+		}
+		add(analysis, field)
+	})
+
+	return usages, nil, warnings
+}
+
+// newAnsibleTemplateUsage returns the usage of a plugin, resolved to its
+// collection.
+func newAnsibleTemplateUsage(usage AnsibleTemplateUsageInfo, pluginType string, name string, line int) AnsibleTemplateUsageInfo {
+	usage.Line = line
+	usage.Name = name
+	usage.PluginType = pluginType
+	if name != "" {
+		resolution := resolveJinjaPlugin(pluginType, name)
+		usage.Collection = resolution.Collection
+		usage.FQCN = resolution.FQCN
+		usage.Resolution = resolution.Resolution
+	}
+	return usage
+}
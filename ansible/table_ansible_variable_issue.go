@@ -0,0 +1,433 @@
+package ansible
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Types of variable issues
+const (
+	variableIssueUndefined = "undefined"
+	variableIssueUnused    = "unused"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleVariableIssue(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_variable_issue",
+		Description: "Variables used but never defined, or defined but never used, across the configured playbooks and inventories",
+		List: &plugin.ListConfig{
+			Hydrate: listAnsibleVariableIssues,
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file using or defining the variable.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "issue_type",
+				Description: "The type of the issue. Possible values are: undefined (used but never defined), unused (defined but never used).",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "variable",
+				Description: "The name of the variable.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "message",
+				Description: "The message describing the issue.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "suggestion",
+				Description: "For undefined variables, the defined variable with the closest name, e.g. app_version for app_verison.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source",
+				Description: "For unused variables, how the variable is defined. Possible values are: group_vars, host_vars, include_vars, inventory_group, inventory_host, loop_var, play_vars, register, role_defaults, role_params, role_vars, set_fact, task_vars, vars_file, vars_prompt.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play using or defining the variable.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task using or defining the variable.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "field",
+				Description: "For undefined variables, the path to the string using the variable in the play, task or vars file, e.g. when or ansible.builtin.template.src.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "line",
+				Description: "The line of the issue in the file, starting at 1. Null if the definition is not located, e.g. for variables of the implicit all group of an inventory.",
+				Type:        proto.ColumnType_INT,
+			},
+		},
+	}
+}
+
+type AnsibleVariableIssueInfo struct {
+	Field        string
+	IssueType    string
+	Line         int
+	Message      string
+	Path         string
+	PlaybookName string
+	Source       string
+	Suggestion   string
+	TaskName     string
+	Variable     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleVariableIssues(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	if GetConfig(d.Connection).PlayBookFilePaths == nil {
+		return nil, errors.New("playbook_file_paths must be configured")
+	}
+
+	// Variables are shared across plays, roles and inventories at runtime, so
+	// every configured file is read before reporting any issue
+	files, err := listAnsibleConfigFiles(d)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []AnsibleVariableReferenceInfo
+	var definitions []ansibleVariableDefinition
+	// Names used by the templated values of inventory variables and by the
+	// Jinja2 templates
+	uses := map[string]bool{}
+	analyzed := map[string]bool{}
+	var varsFiles []string
+	var playbooks, inventories []string
+
+	for _, file := range files {
+		file.Metrics = metrics
+		metrics.addFileListed()
+		path := file.Path
+
+		switch file.Kind {
+		case fileKindPlaybook:
+			if analyzed[path] {
+				continue
+			}
+			analyzed[path] = true
+
+			classification, err := getAnsibleFileClassification(ctx, d, file)
+			if err != nil {
+				plugin.Logger(ctx).Error("ansible_variable_issue.listAnsibleVariableIssues", "file_error", err, "path", path)
+				return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+			}
+			if !classification.ValidYAML || (classification.Kind != fileKindPlaybook && classification.Kind != fileKindTasks && classification.Kind != fileKindVars) {
+				continue
+			}
+
+			fileReferences, fileDefinitions, err := getAnsibleFileVariables(ctx, d, file, classification.Kind)
+			if err != nil {
+				return nil, err
+			}
+			references = append(references, fileReferences...)
+			definitions = append(definitions, fileDefinitions.Definitions...)
+			varsFiles = append(varsFiles, fileDefinitions.VarsFiles...)
+			playbooks = append(playbooks, path)
+
+		case fileKindInventory:
+			inventory, err := getAnsibleInventory(ctx, d, file)
+			if err != nil {
+				plugin.Logger(ctx).Error("ansible_variable_issue.listAnsibleVariableIssues", "read_file_error", err, "path", path)
+				return nil, err
+			}
+			if inventory.Err != nil {
+				err = handleParseErrors(ctx, d, "ansible_variable_issue.listAnsibleVariableIssues", path, []ansibleParseError{newAnsibleParseError(inventory.Err, nil)})
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			inventories = append(inventories, path)
+			definitions = append(definitions, extractInventoryVariableDefinitions(path, inventory.Data, inventory.Locations)...)
+			for _, group := range inventory.Data.Groups {
+				addTemplateVariableNames(uses, group.Vars)
+			}
+			for _, host := range inventory.Data.Hosts {
+				addTemplateVariableNames(uses, host.Vars)
+			}
+		}
+	}
+
+	// Vars files loaded by plays and tasks, and those of roles and of the
+	// group_vars and host_vars directories, may be outside of the configured
+	// paths
+	implicitVarsFiles, err := listAnsibleImplicitVarsFiles(playbooks, inventories)
+	if err != nil {
+		return nil, err
+	}
+	varsFiles = append(varsFiles, implicitVarsFiles...)
+	for _, path := range varsFiles {
+		if analyzed[path] {
+			continue
+		}
+		analyzed[path] = true
+
+		file := filePath{Metrics: metrics, Path: path}
+		metrics.addFileListed()
+		fileReferences, fileDefinitions, err := getAnsibleFileVariables(ctx, d, file, fileKindVars)
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, fileReferences...)
+		definitions = append(definitions, fileDefinitions.Definitions...)
+	}
+
+	// Role defaults and vars are often only read by the templates rendered
+	// with the template module. Templates are only checked for uses, since
+	// the variables they use are not located in the tasks rendering them.
+	templates, err := listAnsibleTemplateFiles(playbooks)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range templates {
+		file := filePath{Metrics: metrics, Path: path}
+		metrics.addFileListed()
+		analysis, err := getAnsibleTemplateAnalysis(ctx, d, file)
+		if err != nil {
+			plugin.Logger(ctx).Error("ansible_variable_issue.listAnsibleVariableIssues", "file_error", err, "path", path)
+			return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+		}
+		for _, reference := range analysis.Analysis.References {
+			uses[reference.Name] = true
+		}
+	}
+
+	for _, issue := range findAnsibleVariableIssues(references, definitions, uses) {
+		d.StreamListItem(ctx, issue)
+	}
+
+	return nil, nil
+}
+
+// getAnsibleFileVariables returns the variables used and defined by a
+// playbook, tasks file or vars file.
+func getAnsibleFileVariables(ctx context.Context, d *plugin.QueryData, file filePath, kind string) ([]AnsibleVariableReferenceInfo, ansibleVariableDefinitions, error) {
+	path := file.Path
+
+	references, parseErrors, warnings, err := getAnsibleVariableReferences(ctx, d, file, kind)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_variable_issue.getAnsibleFileVariables", "file_error", err, "path", path)
+		return nil, ansibleVariableDefinitions{}, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_variable_issue.getAnsibleFileVariables", path, parseErrors)
+	if err != nil {
+		return nil, ansibleVariableDefinitions{}, err
+	}
+	for _, warning := range warnings {
+		plugin.Logger(ctx).Debug("ansible_variable_issue.getAnsibleFileVariables", "expression_error", warning, "path", path, "line", warning.Line)
+	}
+
+	// Parse errors were already handled with the references
+	definitions, _, err := getAnsibleVariableDefinitions(ctx, d, file, kind)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_variable_issue.getAnsibleFileVariables", "file_error", err, "path", path)
+		return nil, ansibleVariableDefinitions{}, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	return references, definitions, nil
+}
+
+// addTemplateVariableNames adds the names of the variables used by the
+// templated values to names.
+func addTemplateVariableNames(names map[string]bool, vars map[string]string) {
+	for _, value := range vars {
+		if !isTemplated(value) {
+			continue
+		}
+		// Inventory values are only checked for uses, so that errors are ignored
+		analysis, _ := analyzeJinjaTemplate(value)
+		for _, reference := range analysis.References {
+			names[reference.Name] = true
+		}
+	}
+}
+
+// findAnsibleVariableIssues compares the variables used and defined across
+// the files. A variable is considered defined if it is defined anywhere, and
+// used if it is used anywhere, since plays, roles and included files share
+// their variables at runtime.
+func findAnsibleVariableIssues(references []AnsibleVariableReferenceInfo, definitions []ansibleVariableDefinition, uses map[string]bool) []AnsibleVariableIssueInfo {
+	defined := map[string]bool{}
+	for _, definition := range definitions {
+		defined[definition.Name] = true
+	}
+	var definedNames []string
+	for name := range defined {
+		definedNames = append(definedNames, name)
+	}
+	sort.Strings(definedNames)
+
+	used := map[string]bool{}
+	for name := range uses {
+		used[name] = true
+	}
+	// A variable checked with the defined test guards its other uses in the
+	// same play or task
+	type guardKey struct {
+		Path, PlaybookName, Scope, TaskName, Variable string
+	}
+	guarded := map[guardKey]bool{}
+	for _, reference := range references {
+		used[reference.Variable] = true
+		if hasVariableGuard(reference) {
+			guarded[guardKey{reference.Path, reference.PlaybookName, reference.Scope, reference.TaskName, reference.Variable}] = true
+		}
+	}
+
+	var issues []AnsibleVariableIssueInfo
+	for _, reference := range references {
+		name := reference.Variable
+		if defined[name] || isAnsibleBuiltinVariable(name) || hasVariableGuard(reference) {
+			continue
+		}
+		if guarded[guardKey{reference.Path, reference.PlaybookName, reference.Scope, reference.TaskName, name}] {
+			continue
+		}
+
+		issue := AnsibleVariableIssueInfo{
+			Field:        reference.Field,
+			IssueType:    variableIssueUndefined,
+			Line:         reference.Line,
+			Message:      fmt.Sprintf("variable %s is used but never defined", name),
+			Path:         reference.Path,
+			PlaybookName: reference.PlaybookName,
+			Suggestion:   closestVariableName(name, definedNames),
+			TaskName:     reference.TaskName,
+			Variable:     name,
+		}
+		if issue.Suggestion != "" {
+			issue.Message += fmt.Sprintf(", did you mean %s?", issue.Suggestion)
+		}
+		issues = append(issues, issue)
+	}
+
+	// The same definition may be read twice, e.g. a vars file matched by
+	// several globs
+	type definitionKey struct {
+		Line       int
+		Name, Path string
+	}
+	seen := map[definitionKey]bool{}
+	for _, definition := range definitions {
+		name := definition.Name
+		if used[name] || isAnsibleBuiltinVariable(name) {
+			continue
+		}
+		key := definitionKey{definition.Line, name, definition.Path}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		issues = append(issues, AnsibleVariableIssueInfo{
+			IssueType:    variableIssueUnused,
+			Line:         definition.Line,
+			Message:      fmt.Sprintf("variable %s is defined but never used", name),
+			Path:         definition.Path,
+			PlaybookName: definition.PlaybookName,
+			Source:       definition.Source,
+			TaskName:     definition.TaskName,
+			Variable:     name,
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Path != issues[j].Path {
+			return issues[i].Path < issues[j].Path
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	return issues
+}
+
+// hasVariableGuard reports whether a use of a variable handles the variable
+// being undefined, e.g. foo | default('bar') or foo is defined.
+func hasVariableGuard(reference AnsibleVariableReferenceInfo) bool {
+	for _, filter := range reference.Filters {
+		if filter == "default" || filter == "d" {
+			return true
+		}
+	}
+	for _, test := range reference.Tests {
+		if test == "defined" || test == "undefined" {
+			return true
+		}
+	}
+	return false
+}
+
+// closestVariableName returns the name closest to the given one, if it is
+// close enough to be a typo: one edit for short names, two otherwise.
+func closestVariableName(name string, names []string) string {
+	maxDistance := 1
+	if len(name) > 6 {
+		maxDistance = 2
+	}
+
+	closest := ""
+	closestDistance := maxDistance + 1
+	for _, candidate := range names {
+		distance := editDistance(name, candidate)
+		if distance < closestDistance {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between two strings, counting
+// a transposition of adjacent characters as one edit.
+func editDistance(a string, b string) int {
+	previous2 := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(b)]
+}
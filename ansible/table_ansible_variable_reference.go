@@ -0,0 +1,170 @@
+package ansible
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+//// TABLE DEFINITION
+
+func tableAnsibleVariableReference(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "ansible_variable_reference",
+		Description: "Variables used by the Jinja2 expressions of Ansible plays, tasks and vars files",
+		List: &plugin.ListConfig{
+			ParentHydrate: resolveAnsiblePlaybookFilePaths,
+			Hydrate:       listAnsibleVariableReferences,
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "path", Require: plugin.Optional, Operators: []string{"=", "~~"}},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "path",
+				Description: "Path to the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "variable",
+				Description: "The name of the variable, e.g. ansible_facts for ansible_facts.os_family.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "scope",
+				Description: "Where the expression is. Possible values are: play, task, vars (for vars files).",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "playbook_name",
+				Description: "The name of the play where the expression is.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "task_name",
+				Description: "The name of the task where the expression is.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "field",
+				Description: "The path to the string holding the expression in the play, task or vars file, e.g. when, ansible.builtin.template.src or vars.packages[0].",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "expression",
+				Description: "The string holding the expression, as written in the file.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "filters",
+				Description: "The filters applied to the part of the expression using the variable, e.g. [\"default\"] for foo | default('bar').",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "tests",
+				Description: "The tests applied to the part of the expression using the variable, e.g. [\"defined\"] for foo is defined.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "line",
+				Description: "The line of the variable in the file, starting at 1.",
+				Type:        proto.ColumnType_INT,
+			},
+		},
+	}
+}
+
+type AnsibleVariableReferenceInfo struct {
+	Expression   string
+	Field        string
+	Filters      []string
+	Line         int
+	Path         string
+	PlaybookName string
+	Scope        string
+	TaskName     string
+	Tests        []string
+	Variable     string
+}
+
+//// LIST FUNCTION
+
+func listAnsibleVariableReferences(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	// The path comes from a parent hydrate, defaulting to the config paths or
+	// available by the optional key column
+	file := h.Item.(filePath)
+	path := file.Path
+
+	classification, err := getAnsibleFileClassification(ctx, d, file)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_variable_reference.listAnsibleVariableReferences", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+
+	// Skip the files that are not playbooks, tasks files or vars files
+	if !classification.isPlaybookCandidate() && classification.Kind != fileKindTasks && classification.Kind != fileKindVars {
+		plugin.Logger(ctx).Debug("ansible_variable_reference.listAnsibleVariableReferences", "skip_file", path, "kind", classification.Kind)
+		return nil, nil
+	}
+
+	references, parseErrors, warnings, err := getAnsibleVariableReferences(ctx, d, file, classification.Kind)
+	if err != nil {
+		plugin.Logger(ctx).Error("ansible_variable_reference.listAnsibleVariableReferences", "file_error", err, "path", path)
+		return nil, fmt.Errorf("failed to read file %s: %v", path, err)
+	}
+	err = handleParseErrors(ctx, d, "ansible_variable_reference.listAnsibleVariableReferences", path, parseErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	// The variables of an expression that cannot be parsed are only partially
+	// listed
+	for _, warning := range warnings {
+		plugin.Logger(ctx).Debug("ansible_variable_reference.listAnsibleVariableReferences", "expression_error", warning, "path", path, "line", warning.Line)
+	}
+
+	for _, reference := range references {
+		d.StreamListItem(ctx, reference)
+	}
+
+	return nil, nil
+}
+
+// extractAnsibleVariableReferences lists the variables used by the
+// expressions of a playbook, tasks file or vars file. The expressions that
+// cannot be parsed are returned as warnings, along with the variables found
+// before the error.
+func extractAnsibleVariableReferences(content []byte, path string, kind string) ([]AnsibleVariableReferenceInfo, []ansibleParseError, []ansibleParseError) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, []ansibleParseError{newAnsibleParseError(err, nil)}, nil
+	}
+
+	var references []AnsibleVariableReferenceInfo
+	var warnings []ansibleParseError
+	walkAnsibleExpressionFields(&doc, kind, func(field ansibleExpressionField) {
+		analysis, err := field.analyze()
+		if err != nil {
+			warnings = append(warnings, ansibleParseError{Line: field.Node.Line, Message: err.Error()})
+		}
+		for _, reference := range analysis.References {
+			references = append(references, AnsibleVariableReferenceInfo{
+				Expression:   field.Node.Value,
+				Field:        field.Field,
+				Filters:      reference.Filters,
+				Line:         reference.Line,
+				Path:         path,
+				PlaybookName: field.PlaybookName,
+				Scope:        field.Scope,
+				TaskName:     field.TaskName,
+				Tests:        reference.Tests,
+				Variable:     reference.Name,
+			})
+		}
+	})
+
+	return references, nil, warnings
+}
@@ -0,0 +1,98 @@
+package ansible
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractAnsibleVariableReferences(t *testing.T) {
+	type reference struct {
+		Variable string
+		Field    string
+		Line     int
+		Scope    string
+		TaskName string
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		kind    string
+		want    []reference
+	}{
+		{
+			name:    "vars file",
+			content: "app_url: \"https://{{ app_host }}:{{ app_port }}\"\nplain: value\n",
+			path:    "/src/group_vars/all.yml",
+			kind:    fileKindVars,
+			want: []reference{
+				{Variable: "app_host", Field: "app_url", Line: 1, Scope: expressionScopeVars},
+				{Variable: "app_port", Field: "app_url", Line: 1, Scope: expressionScopeVars},
+			},
+		},
+		{
+			name:    "list-rooted vars file",
+			content: "- \"{{ foo }}\"\n- bar\n- nested:\n    - \"{{ baz }}\"\n",
+			path:    "/src/group_vars/all.yml",
+			kind:    fileKindVars,
+			want: []reference{
+				{Variable: "foo", Field: "[0]", Line: 1, Scope: expressionScopeVars},
+				{Variable: "baz", Field: "[2].nested[0]", Line: 4, Scope: expressionScopeVars},
+			},
+		},
+		{
+			name: "playbook",
+			content: `- name: Web servers
+  hosts: "{{ target }}"
+  tasks:
+    - name: Install packages
+      ansible.builtin.apt:
+        name: "{{ item }}"
+      loop: "{{ packages }}"
+      when: install_enabled
+    - block:
+        - name: Check service
+          ansible.builtin.assert:
+            that:
+              - service_port > 1024
+`,
+			path: "/src/site.yml",
+			kind: fileKindPlaybook,
+			want: []reference{
+				{Variable: "target", Field: "hosts", Line: 2, Scope: expressionScopePlay},
+				{Variable: "item", Field: "ansible.builtin.apt.name", Line: 6, Scope: expressionScopeTask, TaskName: "Install packages"},
+				{Variable: "packages", Field: "loop", Line: 7, Scope: expressionScopeTask, TaskName: "Install packages"},
+				{Variable: "install_enabled", Field: "when", Line: 8, Scope: expressionScopeTask, TaskName: "Install packages"},
+				{Variable: "service_port", Field: "ansible.builtin.assert.that[0]", Line: 13, Scope: expressionScopeTask, TaskName: "Check service"},
+			},
+		},
+		{
+			name:    "tasks file",
+			content: "- name: Debug\n  debug:\n    var: message\n    msg: \"{{ greeting | default('hi') }}\"\n",
+			path:    "/src/roles/web/tasks/main.yml",
+			kind:    fileKindTasks,
+			want: []reference{
+				// debug var takes a bare variable name
+				{Variable: "message", Field: "debug.var", Line: 3, Scope: expressionScopeTask, TaskName: "Debug"},
+				{Variable: "greeting", Field: "debug.msg", Line: 4, Scope: expressionScopeTask, TaskName: "Debug"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			references, parseErrors, warnings := extractAnsibleVariableReferences([]byte(test.content), test.path, test.kind)
+			if len(parseErrors) > 0 || len(warnings) > 0 {
+				t.Fatalf("unexpected errors: %v %v", parseErrors, warnings)
+			}
+			var got []reference
+			for _, r := range references {
+				got = append(got, reference{Variable: r.Variable, Field: r.Field, Line: r.Line, Scope: r.Scope, TaskName: r.TaskName})
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package ansible
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ansibleTaskKeywords are the keys of a task that are not the module to run.
+// https://docs.ansible.com/ansible/latest/reference_appendices/playbooks_keywords.html#task
+var ansibleTaskKeywords = map[string]bool{
+	"action":             true,
+	"always":             true,
+	"any_errors_fatal":   true,
+	"args":               true,
+	"async":              true,
+	"become":             true,
+	"become_exe":         true,
+	"become_flags":       true,
+	"become_method":      true,
+	"become_user":        true,
+	"block":              true,
+	"changed_when":       true,
+	"check_mode":         true,
+	"collections":        true,
+	"connection":         true,
+	"debugger":           true,
+	"delay":              true,
+	"delegate_facts":     true,
+	"delegate_to":        true,
+	"diff":               true,
+	"environment":        true,
+	"failed_when":        true,
+	"ignore_errors":      true,
+	"ignore_unreachable": true,
+	"listen":             true,
+	"local_action":       true,
+	"loop":               true,
+	"loop_control":       true,
+	"module_defaults":    true,
+	"name":               true,
+	"no_log":             true,
+	"notify":             true,
+	"poll":               true,
+	"port":               true,
+	"register":           true,
+	"remote_user":        true,
+	"rescue":             true,
+	"retries":            true,
+	"run_once":           true,
+	"tags":               true,
+	"throttle":           true,
+	"timeout":            true,
+	"until":              true,
+	"vars":               true,
+	"when":               true,
+}
+
+// ansibleBuiltinModules are the modules shipped in the ansible.builtin
+// collection, which may be referenced by their short name.
+var ansibleBuiltinModules = map[string]bool{
+	"add_host":               true,
+	"apt":                    true,
+	"apt_key":                true,
+	"apt_repository":         true,
+	"assemble":               true,
+	"assert":                 true,
+	"async_status":           true,
+	"blockinfile":            true,
+	"command":                true,
+	"copy":                   true,
+	"cron":                   true,
+	"deb822_repository":      true,
+	"debconf":                true,
+	"debug":                  true,
+	"dnf":                    true,
+	"dnf5":                   true,
+	"dpkg_selections":        true,
+	"expect":                 true,
+	"fail":                   true,
+	"fetch":                  true,
+	"file":                   true,
+	"find":                   true,
+	"gather_facts":           true,
+	"get_url":                true,
+	"getent":                 true,
+	"git":                    true,
+	"group":                  true,
+	"group_by":               true,
+	"hostname":               true,
+	"import_playbook":        true,
+	"import_role":            true,
+	"import_tasks":           true,
+	"include_role":           true,
+	"include_tasks":          true,
+	"include_vars":           true,
+	"iptables":               true,
+	"known_hosts":            true,
+	"lineinfile":             true,
+	"meta":                   true,
+	"mount_facts":            true,
+	"package":                true,
+	"package_facts":          true,
+	"pause":                  true,
+	"ping":                   true,
+	"pip":                    true,
+	"raw":                    true,
+	"reboot":                 true,
+	"replace":                true,
+	"rpm_key":                true,
+	"script":                 true,
+	"service":                true,
+	"service_facts":          true,
+	"set_fact":               true,
+	"set_stats":              true,
+	"setup":                  true,
+	"shell":                  true,
+	"slurp":                  true,
+	"stat":                   true,
+	"subversion":             true,
+	"systemd":                true,
+	"systemd_service":        true,
+	"sysvinit":               true,
+	"tempfile":               true,
+	"template":               true,
+	"unarchive":              true,
+	"uri":                    true,
+	"user":                   true,
+	"validate_argument_spec": true,
+	"wait_for":               true,
+	"wait_for_connection":    true,
+	"yum":                    true,
+	"yum_repository":         true,
+}
+
+// ansibleFreeFormModules take their main argument as a free-form string
+// rather than as key=value pairs.
+var ansibleFreeFormModules = map[string][]string{
+	"command":     {"argv", "chdir", "creates", "executable", "removes", "stdin", "stdin_add_newline", "strip_empty_ends"},
+	"shell":       {"chdir", "creates", "executable", "removes", "stdin", "stdin_add_newline"},
+	"raw":         {"executable"},
+	"script":      {"chdir", "creates", "decrypt", "executable", "removes"},
+	"win_command": {"chdir", "creates", "removes", "stdin"},
+	"win_shell":   {"chdir", "creates", "executable", "removes"},
+	"meta":        {},
+}
+
+// parseTaskModule returns the module called by the task and its arguments,
+// handling the `module: args`, `action: module args` and `local_action` forms.
+func parseTaskModule(task map[string]interface{}) (string, map[string]interface{}) {
+	var module string
+	var value interface{}
+
+	for _, key := range []string{"action", "local_action"} {
+		action, ok := task[key]
+		if !ok {
+			continue
+		}
+		switch v := action.(type) {
+		case string:
+			module, value, _ = strings.Cut(strings.TrimSpace(v), " ")
+		case map[string]interface{}:
+			if m, ok := v["module"].(string); ok {
+				module = m
+				args := map[string]interface{}{}
+				for k, a := range v {
+					if k != "module" {
+						args[k] = a
+					}
+				}
+				value = args
+			}
+		}
+		break
+	}
+
+	if module == "" {
+		keys := make([]string, 0, len(task))
+		for key := range task {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if ansibleTaskKeywords[key] || strings.HasPrefix(key, "with_") {
+				continue
+			}
+			module = key
+			value = task[key]
+			break
+		}
+	}
+
+	if module == "" {
+		return "", nil
+	}
+
+	args := map[string]interface{}{}
+	switch v := value.(type) {
+	case string:
+		args = parseModuleArgsString(module, v)
+	case map[string]interface{}:
+		for k, a := range v {
+			args[k] = a
+		}
+	}
+
+	// Arguments passed through the task level `args` keyword
+	if extra, ok := task["args"].(map[string]interface{}); ok {
+		for k, a := range extra {
+			if _, exists := args[k]; !exists {
+				args[k] = a
+			}
+		}
+	}
+
+	if len(args) == 0 {
+		args = nil
+	}
+	return module, args
+}
+
+// parseModuleArgsString splits `key=value` pairs out of an inline argument
+// string. For free-form modules only the documented keys are extracted and the
+// rest of the string is kept in `_raw_params`.
+func parseModuleArgsString(module string, s string) map[string]interface{} {
+	args := map[string]interface{}{}
+	freeFormKeys, isFreeForm := ansibleFreeFormModules[shortModuleName(module)]
+
+	var raw []string
+	for _, token := range splitModuleArgs(s) {
+		key, value, ok := strings.Cut(token, "=")
+		if ok && isIdentifier(key) {
+			if !isFreeForm || slices.Contains(freeFormKeys, key) {
+				args[key] = unquote(value)
+				continue
+			}
+		}
+		raw = append(raw, token)
+	}
+
+	if len(raw) > 0 {
+		args["_raw_params"] = strings.Join(raw, " ")
+	}
+	return args
+}
+
+// splitModuleArgs splits on whitespace that is not inside quotes or a Jinja2
+// expression.
+func splitModuleArgs(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	var quote rune
+	depth := 0
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote && (i == 0 || s[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{':
+			depth++
+		case r == '}':
+			if depth > 0 {
+				depth--
+			}
+		case (r == ' ' || r == '\t' || r == '\n') && depth == 0:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
+
+// resolveModuleFQCN returns the fully qualified collection name of a module.
+// Short names are only resolved for modules in ansible.builtin, since other
+// collections depend on the `collections` keyword and what is installed.
+func resolveModuleFQCN(module string) string {
+	if strings.Count(module, ".") >= 2 {
+		return module
+	}
+	if ansibleBuiltinModules[module] {
+		return "ansible.builtin." + module
+	}
+	return ""
+}
+
+// shortModuleName strips the collection from a fully qualified module name.
+func shortModuleName(module string) string {
+	return module[strings.LastIndex(module, ".")+1:]
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -3,93 +3,326 @@ package ansible
 import (
 	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
 
 	filehelpers "github.com/turbot/go-kit/files"
 )
 
 type filePath struct {
-	Path string
+	// Kind is the config argument the path was matched by, e.g. playbook. It
+	// is only set for tables listing files of every kind.
+	Kind string
+	// Metrics are the parse metrics of the query listing the file
+	Metrics *parseMetrics
+	Path    string
 }
 
 func resolveAnsiblePlaybookFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.PlayBookFilePaths, "playbook_file_paths")
+}
+
+func resolveAnsibleInventoryFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.InventoryFilePaths, "inventory_file_paths")
+}
+
+func resolveAnsibleRequirementsFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.RequirementsFilePaths, "requirements_file_paths")
+}
+
+func resolveAnsibleFactCacheFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.FactCachePaths, "fact_cache_paths")
+}
+
+func resolveAnsibleRunLogFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.RunLogPaths, "run_log_paths")
+}
+
+func resolveAnsibleLogFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.LogFilePaths, "log_file_paths")
+}
+
+func resolveAnsibleJUnitReportFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ansibleConfig := GetConfig(d.Connection)
+	return nil, streamSourceFilePaths(ctx, d, ansibleConfig.JUnitReportPaths, "junit_report_paths")
+}
+
+// resolveAnsiblePlaybookAndTemplateFilePaths streams the files matched by the
+// playbook_file_paths config argument, along with the templates found next to
+// them and in their roles.
+func resolveAnsiblePlaybookAndTemplateFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return nil, err
+	}
 
 	// #1 - Path via qual
 
-	// If the path was requested through qualifier then match it exactly. Globs
-	// are not supported in this context since the output value for the column
-	// will never match the requested value.
 	quals := d.EqualsQuals
 	if quals["path"] != nil {
-		d.StreamListItem(ctx, filePath{Path: quals["path"].GetStringValue()})
+		metrics.addFileListed()
+		d.StreamListItem(ctx, filePath{Metrics: metrics, Path: quals["path"].GetStringValue()})
 		return nil, nil
 	}
 
-	// #2 - paths in config
+	// #2 - paths in config, and their templates
 
 	// Fail if no paths are specified
-	ansibleConfig := GetConfig(d.Connection)
-	if ansibleConfig.PlayBookFilePaths == nil {
+	if GetConfig(d.Connection).PlayBookFilePaths == nil {
 		return nil, errors.New("playbook_file_paths must be configured")
 	}
 
-	// Gather file path matches for the glob
-	var matches []string
-	paths := ansibleConfig.PlayBookFilePaths
-	for _, i := range paths {
+	playbooks, err := listAnsiblePlaybookFiles(d)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := listAnsibleTemplateFiles(playbooks)
+	if err != nil {
+		return nil, err
+	}
+	patterns, err := getPathPatterns(d)
+	if err != nil {
+		return nil, err
+	}
 
-		// List the files in the given source directory
-		files, err := d.GetSourceFiles(i)
-		if err != nil {
-			return nil, err
+	seen := map[string]bool{}
+	for _, path := range append(playbooks, templates...) {
+		if seen[path] || !matchPathPatterns(patterns, path) {
+			continue
 		}
-		matches = append(matches, files...)
+		seen[path] = true
+		metrics.addFileListed()
+		d.StreamListItem(ctx, filePath{Metrics: metrics, Path: path})
 	}
 
-	// Sanitize the matches to ignore the directories
-	for _, i := range matches {
+	return nil, nil
+}
 
-		// Ignore directories
-		if filehelpers.DirectoryExists(i) {
+// ansibleFileKinds lists the file paths config arguments, along with the kind
+// of the files they match.
+var ansibleFileKinds = []struct {
+	Kind  string
+	Paths func(config ansibleConfig) []string
+}{
+	{fileKindPlaybook, func(config ansibleConfig) []string { return config.PlayBookFilePaths }},
+	{fileKindInventory, func(config ansibleConfig) []string { return config.InventoryFilePaths }},
+	{fileKindRequirements, func(config ansibleConfig) []string { return config.RequirementsFilePaths }},
+	{fileKindFactCache, func(config ansibleConfig) []string { return config.FactCachePaths }},
+	{fileKindRunLog, func(config ansibleConfig) []string { return config.RunLogPaths }},
+	{fileKindLog, func(config ansibleConfig) []string { return config.LogFilePaths }},
+	{fileKindJUnitReport, func(config ansibleConfig) []string { return config.JUnitReportPaths }},
+}
+
+// resolveAnsibleFilePaths streams the files matched by every file paths
+// config argument, along with their kind. A file matched by several arguments
+// is streamed once for each.
+func resolveAnsibleFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	files, err := listAnsibleConfigFiles(d)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		file.Metrics = metrics
+		metrics.addFileListed()
+		d.StreamListItem(ctx, file)
+	}
+
+	return nil, nil
+}
+
+// resolveAnsibleUniqueFilePaths streams the files matched by any file paths
+// config argument, once each, along with the kind of the first argument
+// matching them.
+func resolveAnsibleUniqueFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	files, err := listAnsibleConfigFiles(d)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, file := range files {
+		if seen[file.Path] {
 			continue
 		}
-		d.StreamListItem(ctx, filePath{Path: i})
+		seen[file.Path] = true
+		metrics.addFileListed()
+		file.Metrics = metrics
+		d.StreamListItem(ctx, file)
 	}
 
 	return nil, nil
 }
 
-func resolveAnsibleInventoryFilePaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+// listAnsibleConfigFiles returns the files matched by every file paths config
+// argument. Unlike the other resolvers, a path requested through the
+// qualifier must be matched by the config.
+func listAnsibleConfigFiles(d *plugin.QueryData) ([]filePath, error) {
+	ansibleConfig := GetConfig(d.Connection)
+
+	patterns, err := getPathPatterns(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []filePath
+	quals := d.EqualsQuals
+	for _, fileKind := range ansibleFileKinds {
+		for _, path := range fileKind.Paths(ansibleConfig) {
+			matches, err := d.GetSourceFiles(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				if filehelpers.DirectoryExists(match) {
+					continue
+				}
+				if quals["path"] != nil && quals["path"].GetStringValue() != match {
+					continue
+				}
+				if !matchPathPatterns(patterns, match) {
+					continue
+				}
+				files = append(files, filePath{Kind: fileKind.Kind, Path: match})
+			}
+		}
+	}
+
+	return files, nil
+}
+
+func resolveAnsibleCollectionPaths(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	dirs, err := listAnsibleCollectionDirs(GetConfig(d.Connection))
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := getPathPatterns(d)
+	if err != nil {
+		return nil, err
+	}
+	quals := d.EqualsQuals
+	for _, dir := range dirs {
+		if quals["path"] != nil && quals["path"].GetStringValue() != dir {
+			continue
+		}
+		if !matchPathPatterns(patterns, dir) {
+			continue
+		}
+		d.StreamListItem(ctx, filePath{Path: dir})
+	}
+
+	return nil, nil
+}
+
+// defaultCollectionsPaths mirrors the default COLLECTIONS_PATHS of ansible-core.
+var defaultCollectionsPaths = []string{"~/.ansible/collections", "/usr/share/ansible/collections"}
+
+// listAnsibleCollectionDirs returns the directory of every collection installed
+// under the configured collections paths, i.e. the
+// `ansible_collections/<namespace>/<name>` directories containing a
+// MANIFEST.json or galaxy.yml file.
+func listAnsibleCollectionDirs(config ansibleConfig) ([]string, error) {
+	roots := config.CollectionsPaths
+	if roots == nil {
+		roots = defaultCollectionsPaths
+	}
+
+	seen := map[string]bool{}
+	var dirs []string
+	for _, root := range roots {
+		root, err := filehelpers.Tildefy(root)
+		if err != nil {
+			return nil, err
+		}
+
+		// Like Ansible, accept both the parent of the ansible_collections
+		// directory and the directory itself
+		if filepath.Base(root) != "ansible_collections" {
+			root = filepath.Join(root, "ansible_collections")
+		}
+
+		for _, name := range []string{"MANIFEST.json", "galaxy.yml"} {
+			matches, err := filepath.Glob(filepath.Join(root, "*", "*", name))
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				dir := filepath.Dir(match)
+				if !seen[dir] {
+					seen[dir] = true
+					dirs = append(dirs, dir)
+				}
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	return dirs, nil
+}
+
+// streamSourceFilePaths streams a filePath item for every file matched by the
+// given config paths, or only the path requested through the qualifier. The
+// config argument must be set unless the path is requested, and is named by
+// argument in the error otherwise.
+func streamSourceFilePaths(ctx context.Context, d *plugin.QueryData, paths []string, argument string) error {
+	metrics, err := newParseMetrics(d)
+	if err != nil {
+		return err
+	}
 
 	// #1 - Path via qual
 
 	// If the path was requested through qualifier then match it exactly. Globs
 	// are not supported in this context since the output value for the column
-	// will never match the requested value.
+	// will never match the requested value, use a LIKE qualifier instead.
 	quals := d.EqualsQuals
 	if quals["path"] != nil {
-		d.StreamListItem(ctx, filePath{Path: quals["path"].GetStringValue()})
-		return nil, nil
+		metrics.addFileListed()
+		d.StreamListItem(ctx, filePath{Metrics: metrics, Path: quals["path"].GetStringValue()})
+		return nil
 	}
 
 	// #2 - paths in config
 
 	// Fail if no paths are specified
-	ansibleConfig := GetConfig(d.Connection)
-	if ansibleConfig.InventoryFilePaths == nil {
-		return nil, errors.New("inventory_file_paths must be configured")
+	if paths == nil {
+		return fmt.Errorf("%s must be configured", argument)
+	}
+
+	patterns, err := getPathPatterns(d)
+	if err != nil {
+		return err
 	}
 
 	// Gather file path matches for the glob
 	var matches []string
-	paths := ansibleConfig.InventoryFilePaths
 	for _, i := range paths {
 
 		// List the files in the given source directory
 		files, err := d.GetSourceFiles(i)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		matches = append(matches, files...)
 	}
@@ -101,8 +334,73 @@ func resolveAnsibleInventoryFilePaths(ctx context.Context, d *plugin.QueryData,
 		if filehelpers.DirectoryExists(i) {
 			continue
 		}
-		d.StreamListItem(ctx, filePath{Path: i})
+		// Ignore the files not matched by the LIKE qualifiers, without
+		// parsing them
+		if !matchPathPatterns(patterns, i) {
+			continue
+		}
+		metrics.addFileListed()
+		d.StreamListItem(ctx, filePath{Metrics: metrics, Path: i})
 	}
 
-	return nil, nil
+	return nil
+}
+
+// getPathPatterns returns the LIKE qualifiers of the path column, e.g.
+// `path like '%/roles/%/tasks/%.yml'`, converted to regular expressions.
+func getPathPatterns(d *plugin.QueryData) ([]*regexp.Regexp, error) {
+	if d.Quals["path"] == nil {
+		return nil, nil
+	}
+
+	var patterns []*regexp.Regexp
+	for _, qual := range d.Quals["path"].Quals {
+		if qual.Operator != quals.QualOperatorLike {
+			continue
+		}
+		pattern, err := likeToRegexp(qual.Value.GetStringValue())
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// likeToRegexp converts a SQL LIKE pattern, where % matches any sequence of
+// characters, including /, and _ matches any character, to a regular
+// expression. A backslash escapes the next character.
+func likeToRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("(?s)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(".*")
+		case r == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// matchPathPatterns reports whether a path matches every pattern.
+func matchPathPatterns(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if !pattern.MatchString(path) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,36 @@
+package ansible
+
+import "testing"
+
+func TestLikeToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "%/roles/%/tasks/%.yml", path: "/src/roles/web/tasks/main.yml", want: true},
+		{pattern: "%/roles/%/tasks/%.yml", path: "/src/roles/web/handlers/main.yml", want: false},
+		{pattern: "/src/site.yml", path: "/src/site.yml", want: true},
+		{pattern: "/src/site.yml", path: "/src/site.yaml", want: false},
+		{pattern: "/src/site_yml", path: "/src/site.yml", want: true},
+		{pattern: "/src/site_.yml", path: "/src/site.yml", want: false},
+		{pattern: `/src/100\%.yml`, path: "/src/100%.yml", want: true},
+		{pattern: `/src/100\%.yml`, path: "/src/1000.yml", want: false},
+		{pattern: `/src/a\_b.yml`, path: "/src/axb.yml", want: false},
+		{pattern: "/src/(web)+.yml", path: "/src/(web)+.yml", want: true},
+		{pattern: "/src/(web)+.yml", path: "/src/webweb.yml", want: false},
+		{pattern: "%", path: "/src/multi\nline.yml", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.path, func(t *testing.T) {
+			re, err := likeToRegexp(test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := re.MatchString(test.path); got != test.want {
+				t.Errorf("got %v, want %v (regexp %s)", got, test.want, re)
+			}
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package ansible
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/relex/aini"
+	filehelpers "github.com/turbot/go-kit/files"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of variable definitions
+const (
+	variableSourceGroupVars      = "group_vars"
+	variableSourceHostVars       = "host_vars"
+	variableSourceIncludeVars    = "include_vars"
+	variableSourceInventoryGroup = "inventory_group"
+	variableSourceInventoryHost  = "inventory_host"
+	variableSourceLoopVar        = "loop_var"
+	variableSourcePlayVars       = "play_vars"
+	variableSourceRegister       = "register"
+	variableSourceRoleDefaults   = "role_defaults"
+	variableSourceRoleParams     = "role_params"
+	variableSourceRoleVars       = "role_vars"
+	variableSourceSetFact        = "set_fact"
+	variableSourceTaskVars       = "task_vars"
+	variableSourceVarsFile       = "vars_file"
+	variableSourceVarsPrompt     = "vars_prompt"
+)
+
+// ansibleMagicVariables are the variables Ansible always defines. Variables
+// starting with ansible_, e.g. facts and connection variables, are handled
+// separately.
+var ansibleMagicVariables = map[string]bool{
+	"environment":              true,
+	"group_names":              true,
+	"groups":                   true,
+	"hostvars":                 true,
+	"inventory_dir":            true,
+	"inventory_file":           true,
+	"inventory_hostname":       true,
+	"inventory_hostname_short": true,
+	"item":                     true,
+	"omit":                     true,
+	"play_hosts":               true,
+	"playbook_dir":             true,
+	"role_name":                true,
+	"role_names":               true,
+	"role_path":                true,
+	"vars":                     true,
+}
+
+// isAnsibleBuiltinVariable reports whether Ansible defines the variable
+// itself, e.g. inventory_hostname or a fact such as ansible_os_family.
+func isAnsibleBuiltinVariable(name string) bool {
+	return ansibleMagicVariables[name] || strings.HasPrefix(name, "ansible_")
+}
+
+// ansibleVariableDefinition is a variable defined by a play, a task, a vars
+// file or an inventory.
+type ansibleVariableDefinition struct {
+	// Line is the line of the definition, or 0 if it is unknown
+	Line         int
+	Name         string
+	Path         string
+	PlaybookName string
+	// Source is how the variable is defined, e.g. play_vars or register
+	Source   string
+	TaskName string
+}
+
+// ansibleVariableDefinitions are the variables defined by a file, along with
+// the vars files it loads through vars_files or include_vars.
+type ansibleVariableDefinitions struct {
+	Definitions []ansibleVariableDefinition
+	VarsFiles   []string
+}
+
+// extractAnsibleVariableDefinitions lists the variables defined by a playbook,
+// a tasks file or a vars file.
+func extractAnsibleVariableDefinitions(content []byte, path string, kind string) (ansibleVariableDefinitions, []ansibleParseError) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return ansibleVariableDefinitions{}, []ansibleParseError{newAnsibleParseError(err, nil)}
+	}
+
+	root := documentRoot(&doc)
+	if root == nil {
+		return ansibleVariableDefinitions{}, nil
+	}
+
+	w := variableDefinitionWalker{path: path}
+	if kind == fileKindVars {
+		w.defineKeys(root, ansibleVarsFileSource(path), "", "")
+		return w.result, nil
+	}
+	if root.Kind != yaml.SequenceNode {
+		return w.result, nil
+	}
+
+	for _, item := range root.Content {
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+		if !isAnsiblePlayNode(item) {
+			w.walkTasks([]*yaml.Node{item}, "")
+			continue
+		}
+		w.walkPlay(item)
+	}
+
+	return w.result, nil
+}
+
+// ansibleVarsFileSource returns how a vars file defines its variables, from
+// the directory holding it, e.g. role_defaults for roles/x/defaults/main.yml.
+func ansibleVarsFileSource(path string) string {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		switch parts[i] {
+		case "group_vars":
+			return variableSourceGroupVars
+		case "host_vars":
+			return variableSourceHostVars
+		case "defaults", "vars":
+			// Only the directories of a role, e.g. roles/x/defaults
+			if i >= 2 && parts[i-2] == "roles" {
+				if parts[i] == "defaults" {
+					return variableSourceRoleDefaults
+				}
+				return variableSourceRoleVars
+			}
+		}
+	}
+	return variableSourceVarsFile
+}
+
+// listAnsibleImplicitVarsFiles returns the vars files Ansible loads without
+// them being named by a play: the defaults and vars of the roles found from
+// the playbooks, and the group_vars and host_vars next to the playbooks and
+// inventories.
+func listAnsibleImplicitVarsFiles(playbooks []string, inventories []string) ([]string, error) {
+	roleDirs, err := listAnsibleRoleDirs(playbooks)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	// Roles load their main file, or every file of a main directory
+	for _, roleDir := range roleDirs {
+		for _, dir := range []string{"defaults", "vars"} {
+			for _, name := range []string{"main", "main.yml", "main.yaml", "main.json"} {
+				path := filepath.Join(roleDir, dir, name)
+				if filehelpers.DirectoryExists(path) {
+					if err := walkAnsibleVarsDir(path, add); err != nil {
+						return nil, err
+					}
+				} else if _, err := os.Stat(path); err == nil {
+					add(path)
+				}
+			}
+		}
+	}
+
+	// Host and group variables are loaded from next to both the inventories
+	// and the playbooks
+	dirs := map[string]bool{}
+	for _, path := range append(append([]string{}, playbooks...), inventories...) {
+		dirs[filepath.Dir(path)] = true
+	}
+	var sortedDirs []string
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		for _, name := range []string{"group_vars", "host_vars"} {
+			path := filepath.Join(dir, name)
+			if !filehelpers.DirectoryExists(path) {
+				continue
+			}
+			if err := walkAnsibleVarsDir(path, add); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// walkAnsibleVarsDir calls add for every vars file of a directory, i.e. the
+// YAML and JSON files, or files without extension, as Ansible loads them.
+func walkAnsibleVarsDir(dir string, add func(path string)) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			// Skip hidden directories, e.g. .git
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		switch filepath.Ext(entry.Name()) {
+		case "", ".yml", ".yaml", ".json":
+			add(path)
+		}
+		return nil
+	})
+}
+
+// variableDefinitionWalker collects the variable definitions of a file.
+type variableDefinitionWalker struct {
+	path   string
+	result ansibleVariableDefinitions
+}
+
+func (w *variableDefinitionWalker) define(name string, line int, source string, playbookName string, taskName string) {
+	if name == "" || isTemplated(name) {
+		return
+	}
+	w.result.Definitions = append(w.result.Definitions, ansibleVariableDefinition{
+		Line:         line,
+		Name:         name,
+		Path:         w.path,
+		PlaybookName: playbookName,
+		Source:       source,
+		TaskName:     taskName,
+	})
+}
+
+// defineKeys defines a variable for every key of a mapping, e.g. a vars
+// section.
+func (w *variableDefinitionWalker) defineKeys(node *yaml.Node, source string, playbookName string, taskName string) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		w.define(node.Content[i].Value, node.Content[i].Line, source, playbookName, taskName)
+	}
+}
+
+// addVarsFile records a vars file loaded by the file, resolved against the
+// given directories. Templated paths cannot be resolved.
+func (w *variableDefinitionWalker) addVarsFile(name string, dirs ...string) {
+	if name == "" || isTemplated(name) {
+		return
+	}
+	if filepath.IsAbs(name) {
+		w.result.VarsFiles = append(w.result.VarsFiles, name)
+		return
+	}
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			w.result.VarsFiles = append(w.result.VarsFiles, candidate)
+			return
+		}
+	}
+}
+
+func (w *variableDefinitionWalker) walkPlay(play *yaml.Node) {
+	var playbookName string
+	if name := mappingValue(play, "name"); name != nil && name.Kind == yaml.ScalarNode {
+		playbookName = name.Value
+	}
+
+	w.defineKeys(mappingValue(play, "vars"), variableSourcePlayVars, playbookName, "")
+
+	if prompts := mappingValue(play, "vars_prompt"); prompts != nil && prompts.Kind == yaml.SequenceNode {
+		for _, prompt := range prompts.Content {
+			if name := mappingValue(prompt, "name"); name != nil && name.Kind == yaml.ScalarNode {
+				w.define(name.Value, name.Line, variableSourceVarsPrompt, playbookName, "")
+			}
+		}
+	}
+
+	// vars_files are relative to the playbook, and an item may be a list of
+	// files of which the first one found is loaded
+	if files := mappingValue(play, "vars_files"); files != nil && files.Kind == yaml.SequenceNode {
+		dir := filepath.Dir(w.path)
+		for _, file := range files.Content {
+			switch file.Kind {
+			case yaml.ScalarNode:
+				w.addVarsFile(file.Value, dir)
+			case yaml.SequenceNode:
+				for _, candidate := range file.Content {
+					w.addVarsFile(candidate.Value, dir)
+				}
+			}
+		}
+	}
+
+	// Roles may be given parameters, either as extra keys or in vars
+	if roles := mappingValue(play, "roles"); roles != nil && roles.Kind == yaml.SequenceNode {
+		for _, role := range roles.Content {
+			if role.Kind != yaml.MappingNode {
+				continue
+			}
+			w.defineKeys(mappingValue(role, "vars"), variableSourceRoleParams, playbookName, "")
+			for i := 0; i+1 < len(role.Content); i += 2 {
+				key := role.Content[i]
+				if key.Value == "role" || key.Value == "name" || ansibleTaskKeywords[key.Value] {
+					continue
+				}
+				w.define(key.Value, key.Line, variableSourceRoleParams, playbookName, "")
+			}
+		}
+	}
+
+	for _, section := range ansibleTaskSections {
+		if tasks := mappingValue(play, section); tasks != nil && tasks.Kind == yaml.SequenceNode {
+			w.walkTasks(tasks.Content, playbookName)
+		}
+	}
+}
+
+// walkTasks collects the variables defined by tasks, recursing into blocks.
+func (w *variableDefinitionWalker) walkTasks(tasks []*yaml.Node, playbookName string) {
+	for _, task := range tasks {
+		if task.Kind != yaml.MappingNode {
+			continue
+		}
+
+		var taskName string
+		if name := mappingValue(task, "name"); name != nil && name.Kind == yaml.ScalarNode {
+			taskName = name.Value
+		}
+		w.defineKeys(mappingValue(task, "vars"), variableSourceTaskVars, playbookName, taskName)
+
+		if register := mappingValue(task, "register"); register != nil && register.Kind == yaml.ScalarNode {
+			w.define(register.Value, register.Line, variableSourceRegister, playbookName, taskName)
+		}
+		if control := mappingValue(task, "loop_control"); control != nil {
+			for _, key := range []string{"loop_var", "index_var"} {
+				if value := mappingValue(control, key); value != nil && value.Kind == yaml.ScalarNode {
+					w.define(value.Value, value.Line, variableSourceLoopVar, playbookName, taskName)
+				}
+			}
+		}
+
+		isBlock := false
+		for _, section := range ansibleBlockSections {
+			if nested := mappingValue(task, section); nested != nil && nested.Kind == yaml.SequenceNode {
+				isBlock = true
+				w.walkTasks(nested.Content, playbookName)
+			}
+		}
+		if !isBlock {
+			w.walkModuleArgs(task, playbookName, taskName)
+		}
+	}
+}
+
+// walkModuleArgs collects the variables defined by the set_fact and
+// include_vars modules.
+func (w *variableDefinitionWalker) walkModuleArgs(task *yaml.Node, playbookName string, taskName string) {
+	var raw map[string]interface{}
+	if err := task.Decode(&raw); err != nil {
+		return
+	}
+	module, args := parseTaskModule(raw)
+
+	// The arguments are located on their key when given as a map, or on the
+	// module otherwise
+	line := task.Line
+	lines := map[string]int{}
+	for _, key := range []string{module, "action", "local_action", "args"} {
+		node := mappingKey(task, key)
+		if node == nil {
+			continue
+		}
+		if key == module {
+			line = node.Line
+		}
+		value := mappingValue(task, key)
+		if value.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(value.Content); i += 2 {
+				lines[value.Content[i].Value] = value.Content[i].Line
+			}
+		}
+	}
+	argLine := func(name string) int {
+		if l, ok := lines[name]; ok {
+			return l
+		}
+		return line
+	}
+
+	switch shortModuleName(module) {
+	case "set_fact":
+		for name := range args {
+			if name != "cacheable" && name != "_raw_params" {
+				w.define(name, argLine(name), variableSourceSetFact, playbookName, taskName)
+			}
+		}
+	case "include_vars":
+		// The variables are nested under name if it is set, otherwise the
+		// keys of the file are defined
+		if name, ok := args["name"].(string); ok {
+			w.define(name, argLine("name"), variableSourceIncludeVars, playbookName, taskName)
+			return
+		}
+		file, ok := args["file"].(string)
+		if !ok {
+			file, _ = args["_raw_params"].(string)
+		}
+		// Like Ansible, look for the file in the vars directory of the role
+		// before the directory of the tasks file
+		dir := filepath.Dir(w.path)
+		w.addVarsFile(file, filepath.Join(dir, "..", "vars"), dir)
+	}
+}
+
+// extractInventoryVariableDefinitions lists the variables defined by the
+// hosts and groups of an inventory. A variable is only defined by the group
+// or host setting it, not by the groups and hosts inheriting it.
+func extractInventoryVariableDefinitions(path string, data *aini.InventoryData, locations inventoryLocations) []ansibleVariableDefinition {
+	var definitions []ansibleVariableDefinition
+
+	for _, group := range data.Groups {
+		for name, value := range group.Vars {
+			inherited := false
+			for _, parent := range group.Parents {
+				if v, ok := parent.Vars[name]; ok && v == value {
+					inherited = true
+					break
+				}
+			}
+			if !inherited {
+				definitions = append(definitions, ansibleVariableDefinition{
+					Line:   locations.Groups[group.Name].StartLine,
+					Name:   name,
+					Path:   path,
+					Source: variableSourceInventoryGroup,
+				})
+			}
+		}
+	}
+
+	for _, host := range data.Hosts {
+		for name, value := range host.Vars {
+			inherited := false
+			for _, group := range host.Groups {
+				if v, ok := group.Vars[name]; ok && v == value {
+					inherited = true
+					break
+				}
+			}
+			if !inherited {
+				definitions = append(definitions, ansibleVariableDefinition{
+					Line:   locations.Hosts[host.Name].StartLine,
+					Name:   name,
+					Path:   path,
+					Source: variableSourceInventoryHost,
+				})
+			}
+		}
+	}
+
+	return definitions
+}